@@ -0,0 +1,50 @@
+// Command fakehume serves an in-memory fake of the Hume API for offline
+// development. Point the server at it with HUME_API_URL=http://localhost:8090
+// and HUME_CONFIG_ID set to -config-id.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/hume-evi/web/internal/hume/fakehume"
+)
+
+func main() {
+	addr := flag.String("addr", ":8090", "listen address")
+	apiKey := flag.String("api-key", "", "require this X-Hume-Api-Key (any non-empty key when unset)")
+	configID := flag.String("config-id", "fake-config", "ID of a config created at startup")
+	scriptPath := flag.String("script", "", "JSON file with scripted chat turns: [{\"user\": \"...\", \"assistant\": \"...\"}]")
+	flag.Parse()
+
+	fake := fakehume.New()
+	fake.APIKey = *apiKey
+	if *configID != "" {
+		fake.SeedConfig(*configID, "Default")
+	}
+
+	if *scriptPath != "" {
+		data, err := os.ReadFile(*scriptPath)
+		if err != nil {
+			log.Fatal("Failed to read script:", err)
+		}
+		var turns []fakehume.Turn
+		if err := json.Unmarshal(data, &turns); err != nil {
+			log.Fatal("Failed to parse script:", err)
+		}
+		fake.Script(turns...)
+		log.Printf("Loaded %d scripted turns", len(turns))
+	}
+
+	log.Printf("Fake Hume API listening on %s", *addr)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("%s %s", r.Method, r.URL.Path)
+		fake.ServeHTTP(w, r)
+	})
+	if err := http.ListenAndServe(*addr, handler); err != nil {
+		log.Fatal("Server error:", err)
+	}
+}
@@ -7,10 +7,13 @@ import (
 	"os/signal"
 	"syscall"
 
+	"github.com/hume-evi/web/internal/analysis"
 	"github.com/hume-evi/web/internal/api"
 	"github.com/hume-evi/web/internal/auth"
 	"github.com/hume-evi/web/internal/config"
 	"github.com/hume-evi/web/internal/db"
+	"github.com/hume-evi/web/internal/graph"
+	"github.com/hume-evi/web/internal/websocket"
 )
 
 func main() {
@@ -20,6 +23,11 @@ func main() {
 		log.Fatal("Failed to load config:", err)
 	}
 
+	// "server migrate ..." manages the schema and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(cfg, os.Args[2:]))
+	}
+
 	// Validate required config
 	if cfg.HumeAPIKey == "" {
 		log.Fatal("HUME_API_KEY is required")
@@ -41,7 +49,7 @@ func main() {
 	}
 	defer database.Close()
 
-	// Run migrations first
+	// Apply pending migrations first
 	ctx := context.Background()
 	if err := database.RunMigrations(ctx); err != nil {
 		log.Fatal("Failed to run migrations:", err)
@@ -79,10 +87,7 @@ func main() {
 		}
 	}
 
-	// Create server
-	server := api.NewServer(cfg, database)
-
-	// Setup graceful shutdown
+	// Setup graceful shutdown; background work started from here on stops with ctx
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -95,6 +100,31 @@ func main() {
 		cancel()
 	}()
 
+	// Build the context analyzer chain shared by the API and the EVI proxy
+	analyzer, err := analysis.NewChainFromConfig(ctx, cfg)
+	if err != nil {
+		log.Fatal("Failed to configure context analyzers:", err)
+	}
+
+	// Connect to Memgraph (optional - graph features degrade without it)
+	var graphClient *graph.Client
+	if cfg.MemgraphURI != "" {
+		graphClient, err = graph.NewClient(cfg.MemgraphURI, cfg.MemgraphUsername, cfg.MemgraphPassword)
+		if err != nil {
+			log.Printf("Warning: Memgraph unavailable, knowledge graph features disabled: %v", err)
+			graphClient = nil
+		} else {
+			defer graphClient.Close(context.Background())
+		}
+	}
+
+	// Start the EVI proxy hub
+	hub := websocket.NewHub(database, cfg, analyzer)
+	go hub.Run()
+
+	// Create server
+	server := api.NewServer(cfg, database, hub, analyzer, graphClient)
+
 	// Start server
 	if err := server.Start(ctx); err != nil {
 		log.Fatal("Server error:", err)
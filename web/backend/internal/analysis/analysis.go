@@ -0,0 +1,119 @@
+package analysis
+
+import (
+	"context"
+	"strings"
+)
+
+// Context types understood by Hume's session_settings context field
+const (
+	ContextTemporary  = "temporary"
+	ContextPersistent = "persistent"
+)
+
+// ConversationMessage represents a message in the conversation
+type ConversationMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+	// Emotions holds Hume expression scores (e.g. "Anxiety": 0.42) when known
+	Emotions map[string]float64 `json:"emotions,omitempty"`
+}
+
+// Session carries per-conversation metadata alongside the history, for
+// analyzers that keep state between calls (cooldowns, message counts)
+type Session struct {
+	ConversationID string
+	// MessageCount is the total number of messages in the conversation,
+	// which may exceed the rolling history passed to Analyze
+	MessageCount int
+}
+
+type sessionKey struct{}
+
+// WithSession attaches conversation metadata to the analysis context
+func WithSession(ctx context.Context, session Session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, session)
+}
+
+// SessionFromContext returns the conversation metadata, if any
+func SessionFromContext(ctx context.Context) (Session, bool) {
+	session, ok := ctx.Value(sessionKey{}).(Session)
+	return session, ok
+}
+
+// AnalyzeResponse represents the AI analysis response
+type AnalyzeResponse struct {
+	ShouldIntervene bool   `json:"shouldIntervene"`
+	ContextText     string `json:"contextText"`
+	ContextType     string `json:"contextType"` // "temporary" or "persistent"
+	Reasoning       string `json:"reasoning"`
+	// Rule names the context rule that asked for the intervention, if any
+	Rule string `json:"rule,omitempty"`
+}
+
+// Analyzer decides whether context should be injected into a conversation
+type Analyzer interface {
+	Name() string
+	Analyze(ctx context.Context, history []ConversationMessage) (AnalyzeResponse, error)
+}
+
+// InterventionRecorder is implemented by analyzers that keep state between
+// calls. Analyze only proposes an intervention; RecordIntervention is called
+// once its context has actually been delivered, so cooldowns don't start for
+// context that was never injected.
+type InterventionRecorder interface {
+	RecordIntervention(ctx context.Context, result AnalyzeResponse)
+}
+
+// RecordIntervention tells analyzer that result was delivered, if it keeps
+// track. ctx should carry the same Session that was passed to Analyze.
+func RecordIntervention(ctx context.Context, analyzer Analyzer, result AnalyzeResponse) {
+	if recorder, ok := analyzer.(InterventionRecorder); ok {
+		recorder.RecordIntervention(ctx, result)
+	}
+}
+
+// NoIntervention is the response returned when nothing needs injecting
+func NoIntervention(reasoning string) AnalyzeResponse {
+	return AnalyzeResponse{
+		ShouldIntervene: false,
+		ContextText:     "",
+		ContextType:     ContextTemporary,
+		Reasoning:       reasoning,
+	}
+}
+
+// KeywordAnalyzer is the built-in fallback: it looks for stress keywords in
+// the latest message
+type KeywordAnalyzer struct{}
+
+func (KeywordAnalyzer) Name() string { return "keywords" }
+
+func (KeywordAnalyzer) Analyze(ctx context.Context, history []ConversationMessage) (AnalyzeResponse, error) {
+	response := NoIntervention("Default: No intervention needed")
+
+	if len(history) > 0 {
+		lastMessage := history[len(history)-1]
+
+		// Check for stress indicators
+		if ContainsKeywords(lastMessage.Content, []string{"stress", "overwhelm", "anxious", "worried"}) {
+			response.ShouldIntervene = true
+			response.ContextText = "The user is expressing stress. Be empathetic, validate their feelings, and help them break down the problem into manageable steps."
+			response.ContextType = ContextTemporary
+			response.Reasoning = "Detected stress keywords in user message"
+		}
+	}
+
+	return response, nil
+}
+
+// ContainsKeywords reports whether text contains any of the keywords, ignoring case
+func ContainsKeywords(text string, keywords []string) bool {
+	lowerText := strings.ToLower(text)
+	for _, keyword := range keywords {
+		if strings.Contains(lowerText, strings.ToLower(keyword)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,188 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hume-evi/web/internal/config"
+	"github.com/hume-evi/web/internal/llm"
+)
+
+// MergeStrategy controls how results from several analyzers are combined
+type MergeStrategy string
+
+const (
+	// MergeFirst uses the highest-priority analyzer that wants to intervene
+	MergeFirst MergeStrategy = "first"
+	// MergeAll combines the context from every analyzer that wants to intervene.
+	// The result is persistent if any contributor asked for persistent context.
+	MergeAll MergeStrategy = "all"
+)
+
+type registration struct {
+	analyzer Analyzer
+	priority int
+	order    int
+}
+
+// Chain runs registered analyzers in priority order (highest first) and
+// merges their results. A Chain is itself an Analyzer, so the HTTP handler
+// and the EVI proxy can share one.
+type Chain struct {
+	mu            sync.RWMutex
+	registrations []registration
+	strategy      MergeStrategy
+}
+
+// NewChain creates an empty chain using the given merge strategy
+func NewChain(strategy MergeStrategy) *Chain {
+	if strategy != MergeAll {
+		strategy = MergeFirst
+	}
+	return &Chain{strategy: strategy}
+}
+
+// Register adds an analyzer. Analyzers with equal priority run in
+// registration order.
+func (c *Chain) Register(analyzer Analyzer, priority int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.registrations = append(c.registrations, registration{
+		analyzer: analyzer,
+		priority: priority,
+		order:    len(c.registrations),
+	})
+	sort.SliceStable(c.registrations, func(i, j int) bool {
+		if c.registrations[i].priority != c.registrations[j].priority {
+			return c.registrations[i].priority > c.registrations[j].priority
+		}
+		return c.registrations[i].order < c.registrations[j].order
+	})
+}
+
+// Analyzers lists the registered analyzer names in execution order
+func (c *Chain) Analyzers() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	names := make([]string, 0, len(c.registrations))
+	for _, reg := range c.registrations {
+		names = append(names, reg.analyzer.Name())
+	}
+	return names
+}
+
+func (c *Chain) Name() string {
+	return fmt.Sprintf("chain(%s)", strings.Join(c.Analyzers(), ","))
+}
+
+// Analyze runs the chain. A failing analyzer is logged and skipped so one
+// broken backend (e.g. an unreachable LLM) doesn't disable the others.
+func (c *Chain) Analyze(ctx context.Context, history []ConversationMessage) (AnalyzeResponse, error) {
+	c.mu.RLock()
+	registrations := make([]registration, len(c.registrations))
+	copy(registrations, c.registrations)
+	c.mu.RUnlock()
+
+	var interventions []AnalyzeResponse
+	var contributors []string
+	for _, reg := range registrations {
+		if err := ctx.Err(); err != nil {
+			return AnalyzeResponse{}, err
+		}
+
+		result, err := reg.analyzer.Analyze(ctx, history)
+		if err != nil {
+			log.Printf("Analyzer %s failed: %v", reg.analyzer.Name(), err)
+			continue
+		}
+		if !result.ShouldIntervene || result.ContextText == "" {
+			continue
+		}
+
+		if c.strategy == MergeFirst {
+			result.Reasoning = fmt.Sprintf("[%s] %s", reg.analyzer.Name(), result.Reasoning)
+			return result, nil
+		}
+		interventions = append(interventions, result)
+		contributors = append(contributors, reg.analyzer.Name())
+	}
+
+	if len(interventions) == 0 {
+		return NoIntervention("No analyzer requested an intervention"), nil
+	}
+
+	merged := AnalyzeResponse{
+		ShouldIntervene: true,
+		ContextType:     ContextTemporary,
+	}
+	var texts, reasons []string
+	for i, result := range interventions {
+		if merged.Rule == "" {
+			merged.Rule = result.Rule
+		}
+		texts = append(texts, result.ContextText)
+		reasons = append(reasons, fmt.Sprintf("[%s] %s", contributors[i], result.Reasoning))
+		if result.ContextType == ContextPersistent {
+			merged.ContextType = ContextPersistent
+		}
+	}
+	merged.ContextText = strings.Join(texts, "\n\n")
+	merged.Reasoning = strings.Join(reasons, "; ")
+	return merged, nil
+}
+
+// RecordIntervention passes a delivered result on to every analyzer in the
+// chain that keeps state
+func (c *Chain) RecordIntervention(ctx context.Context, result AnalyzeResponse) {
+	c.mu.RLock()
+	registrations := make([]registration, len(c.registrations))
+	copy(registrations, c.registrations)
+	c.mu.RUnlock()
+
+	for _, reg := range registrations {
+		RecordIntervention(ctx, reg.analyzer, result)
+	}
+}
+
+// Analyzer priorities used by NewChainFromConfig
+const (
+	PriorityRules    = 100
+	PriorityLLM      = 50
+	PriorityKeywords = 10
+)
+
+// rulesReloadInterval is how often the rule file is checked for changes
+const rulesReloadInterval = 5 * time.Second
+
+// NewChainFromConfig builds the analyzer chain shared by the HTTP handler and
+// the EVI proxy. Rule files take precedence over the LLM; the built-in keyword
+// check is only used when no rule file is configured. The rule file is
+// hot-reloaded until ctx is cancelled.
+func NewChainFromConfig(ctx context.Context, cfg *config.Config) (*Chain, error) {
+	chain := NewChain(MergeStrategy(cfg.AnalyzerMerge))
+
+	if cfg.AnalyzerRulesFile != "" {
+		rules, err := LoadRuleFile(cfg.AnalyzerRulesFile)
+		if err != nil {
+			return nil, err
+		}
+		go rules.Watch(ctx, rulesReloadInterval)
+		chain.Register(rules, PriorityRules)
+	} else {
+		chain.Register(KeywordAnalyzer{}, PriorityKeywords)
+	}
+
+	if cfg.LLMBaseURL != "" {
+		chain.Register(NewLLMAnalyzer(llm.NewClient(cfg.LLMBaseURL, cfg.LLMAPIKey, cfg.LLMModel)), PriorityLLM)
+	}
+
+	log.Printf("Context analyzers: %s (merge=%s)", strings.Join(chain.Analyzers(), ", "), chain.strategy)
+	return chain, nil
+}
@@ -0,0 +1,134 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hume-evi/web/internal/llm"
+)
+
+func intervene(text, contextType, reasoning string) AnalyzeResponse {
+	return AnalyzeResponse{ShouldIntervene: true, ContextText: text, ContextType: contextType, Reasoning: reasoning}
+}
+
+func TestChainRunsAnalyzersByPriority(t *testing.T) {
+	chain := NewChain(MergeFirst)
+	low := &FakeAnalyzer{AnalyzerName: "low", Responses: []AnalyzeResponse{intervene("low", ContextTemporary, "low fired")}}
+	quiet := &FakeAnalyzer{AnalyzerName: "quiet"}
+	high := &FakeAnalyzer{AnalyzerName: "high", Responses: []AnalyzeResponse{intervene("high", ContextTemporary, "high fired")}}
+	tie := &FakeAnalyzer{AnalyzerName: "tie"}
+	chain.Register(low, 10)
+	chain.Register(quiet, 50)
+	chain.Register(high, 20)
+	chain.Register(tie, 50)
+
+	if got := strings.Join(chain.Analyzers(), ","); got != "quiet,tie,high,low" {
+		t.Fatalf("analyzers run as %s, want quiet,tie,high,low", got)
+	}
+
+	result, err := chain.Analyze(context.Background(), []ConversationMessage{{Role: "user", Content: "hi"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ContextText != "high" || result.Reasoning != "[high] high fired" {
+		t.Fatalf("MergeFirst returned %+v, want high's context", result)
+	}
+	if len(quiet.Calls()) != 1 || len(tie.Calls()) != 1 || len(high.Calls()) != 1 {
+		t.Fatal("analyzers ahead of the first intervention weren't all asked")
+	}
+	if len(low.Calls()) != 0 {
+		t.Fatal("MergeFirst kept going after the first intervention")
+	}
+}
+
+func TestChainMergeAll(t *testing.T) {
+	chain := NewChain(MergeAll)
+	chain.Register(&FakeAnalyzer{AnalyzerName: "rules", Responses: []AnalyzeResponse{
+		{ShouldIntervene: true, ContextText: "Slow down.", ContextType: ContextTemporary, Reasoning: "anxious", Rule: "high-anxiety"},
+	}}, 100)
+	chain.Register(&FakeAnalyzer{AnalyzerName: "empty", Responses: []AnalyzeResponse{intervene("", ContextPersistent, "nothing to say")}}, 75)
+	chain.Register(&FakeAnalyzer{AnalyzerName: "llm", Responses: []AnalyzeResponse{intervene("Summarise.", ContextPersistent, "long chat")}}, 50)
+	chain.Register(&FakeAnalyzer{AnalyzerName: "keywords"}, 10)
+
+	result, err := chain.Analyze(context.Background(), []ConversationMessage{{Role: "user", Content: "hi"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := AnalyzeResponse{
+		ShouldIntervene: true,
+		ContextText:     "Slow down.\n\nSummarise.",
+		ContextType:     ContextPersistent,
+		Reasoning:       "[rules] anxious; [llm] long chat",
+		Rule:            "high-anxiety",
+	}
+	if result != want {
+		t.Fatalf("MergeAll returned %+v, want %+v", result, want)
+	}
+
+	// Nothing to merge is no intervention
+	quiet := NewChain(MergeAll)
+	quiet.Register(&FakeAnalyzer{}, 0)
+	if result, _ := quiet.Analyze(context.Background(), nil); result.ShouldIntervene {
+		t.Fatalf("a chain of quiet analyzers returned %+v", result)
+	}
+}
+
+func TestChainSkipsFailingAnalyzers(t *testing.T) {
+	for _, strategy := range []MergeStrategy{MergeFirst, MergeAll} {
+		t.Run(string(strategy), func(t *testing.T) {
+			chain := NewChain(strategy)
+			broken := &FakeAnalyzer{AnalyzerName: "broken", Err: errors.New("connection refused")}
+			working := &FakeAnalyzer{AnalyzerName: "working", Responses: []AnalyzeResponse{intervene("Breathe.", ContextTemporary, "stressed")}}
+			chain.Register(broken, 100)
+			chain.Register(working, 10)
+
+			result, err := chain.Analyze(context.Background(), []ConversationMessage{{Role: "user", Content: "hi"}})
+			if err != nil {
+				t.Fatalf("Analyze = %v, want the failure skipped", err)
+			}
+			if len(broken.Calls()) != 1 || result.ContextText != "Breathe." || result.Reasoning != "[working] stressed" {
+				t.Fatalf("Analyze = %+v, want the working analyzer's result", result)
+			}
+		})
+	}
+
+	// A cancelled context stops the chain instead of being skipped
+	chain := NewChain(MergeFirst)
+	chain.Register(&FakeAnalyzer{}, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := chain.Analyze(ctx, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Analyze with a cancelled context = %v, want context.Canceled", err)
+	}
+}
+
+func TestNewChainDefaultsToMergeFirst(t *testing.T) {
+	chain := NewChain("")
+	chain.Register(&FakeAnalyzer{AnalyzerName: "a", Responses: []AnalyzeResponse{intervene("a", ContextTemporary, "")}}, 0)
+	chain.Register(&FakeAnalyzer{AnalyzerName: "b", Responses: []AnalyzeResponse{intervene("b", ContextTemporary, "")}}, 0)
+	result, _ := chain.Analyze(context.Background(), nil)
+	if result.ContextText != "a" {
+		t.Fatalf("default strategy returned %q, want only the first analyzer's context", result.ContextText)
+	}
+}
+
+func TestLLMAnalyzerWrapsDecodeErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"role": "assistant", "content": "not json"}}},
+		})
+	}))
+	defer server.Close()
+
+	analyzer := NewLLMAnalyzer(llm.NewClient(server.URL, "", "test"))
+	_, err := analyzer.Analyze(context.Background(), []ConversationMessage{{Role: "user", Content: "hi"}})
+	var syntaxErr *json.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("Analyze = %v, want a wrapped json.SyntaxError", err)
+	}
+}
@@ -0,0 +1,60 @@
+package analysis
+
+import (
+	"context"
+	"sync"
+)
+
+// FakeAnalyzer returns scripted responses and records every call. It is meant
+// for tests and local development.
+type FakeAnalyzer struct {
+	// AnalyzerName defaults to "fake"
+	AnalyzerName string
+	// Responses are returned in order; the last one repeats once exhausted.
+	// With no responses the fake never intervenes.
+	Responses []AnalyzeResponse
+	// Err, when set, is returned instead of a response
+	Err error
+
+	mu    sync.Mutex
+	calls [][]ConversationMessage
+}
+
+func (f *FakeAnalyzer) Name() string {
+	if f.AnalyzerName == "" {
+		return "fake"
+	}
+	return f.AnalyzerName
+}
+
+func (f *FakeAnalyzer) Analyze(ctx context.Context, history []ConversationMessage) (AnalyzeResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	snapshot := make([]ConversationMessage, len(history))
+	copy(snapshot, history)
+	f.calls = append(f.calls, snapshot)
+
+	if f.Err != nil {
+		return AnalyzeResponse{}, f.Err
+	}
+	if len(f.Responses) == 0 {
+		return NoIntervention("fake: no scripted response"), nil
+	}
+
+	index := len(f.calls) - 1
+	if index >= len(f.Responses) {
+		index = len(f.Responses) - 1
+	}
+	return f.Responses[index], nil
+}
+
+// Calls returns the histories the fake has been asked to analyze
+func (f *FakeAnalyzer) Calls() [][]ConversationMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	calls := make([][]ConversationMessage, len(f.calls))
+	copy(calls, f.calls)
+	return calls
+}
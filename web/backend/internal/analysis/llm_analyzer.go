@@ -0,0 +1,61 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hume-evi/web/internal/llm"
+)
+
+const llmAnalyzerPrompt = `You monitor a live voice conversation between a user and an empathic AI companion.
+Decide whether the companion needs extra guidance for its next reply.
+Only intervene when it would clearly help (distress, confusion, a topic change the companion is missing).
+
+Respond with a JSON object:
+{"shouldIntervene": bool, "contextText": string, "contextType": "temporary" | "persistent", "reasoning": string}
+
+contextText is an instruction addressed to the companion. Use "persistent" only for guidance that should
+apply for the rest of the conversation.`
+
+// LLMAnalyzer asks an OpenAI-compatible chat model whether to intervene
+type LLMAnalyzer struct {
+	client *llm.Client
+}
+
+// NewLLMAnalyzer creates an analyzer backed by the given chat client
+func NewLLMAnalyzer(client *llm.Client) *LLMAnalyzer {
+	return &LLMAnalyzer{client: client}
+}
+
+func (a *LLMAnalyzer) Name() string { return "llm" }
+
+func (a *LLMAnalyzer) Analyze(ctx context.Context, history []ConversationMessage) (AnalyzeResponse, error) {
+	if len(history) == 0 {
+		return NoIntervention("No messages to analyze"), nil
+	}
+
+	var transcript strings.Builder
+	for _, msg := range history {
+		fmt.Fprintf(&transcript, "%s: %s\n", msg.Role, msg.Content)
+	}
+
+	content, err := a.client.Complete(ctx, []llm.Message{
+		{Role: "system", Content: llmAnalyzerPrompt},
+		{Role: "user", Content: transcript.String()},
+	}, &llm.ResponseFormat{Type: "json_object"})
+	if err != nil {
+		return AnalyzeResponse{}, err
+	}
+
+	var result AnalyzeResponse
+	if err := json.Unmarshal([]byte(content), &result); err != nil {
+		return AnalyzeResponse{}, fmt.Errorf("failed to decode analyzer output: %w, content: %s", err, content)
+	}
+	if result.ContextType != ContextPersistent {
+		result.ContextType = ContextTemporary
+	}
+
+	return result, nil
+}
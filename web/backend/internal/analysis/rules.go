@@ -0,0 +1,608 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Rule files are YAML (or JSON, which parses as YAML):
+//
+//	rules:
+//	  - name: stress
+//	    priority: 10
+//	    when:
+//	      role: user
+//	      any_keywords: [stress, overwhelmed, anxious]
+//	      regex: "can'?t (cope|sleep)"
+//	      min_messages: 2
+//	      min_since_last_intervention: 2m
+//	      emotions:
+//	        - name: Anxiety
+//	          min: 0.4
+//	    context:
+//	      text: The user sounds stressed. Slow down and validate their feelings.
+//	      type: temporary
+//	    cooldown: 5m
+//	    cooldown_messages: 6
+//
+// Conditions within "when" must all hold for the latest message. Rules are
+// evaluated by descending priority, then file order; the first match fires.
+// Cooldowns and min_since_last_intervention only count firings whose context
+// was delivered (see RecordIntervention).
+
+// Rule is a validated, compiled intervention rule
+type Rule struct {
+	Name     string `json:"name"`
+	Priority int    `json:"priority"`
+	Line     int    `json:"line"`
+
+	Role                     string             `json:"role,omitempty"`
+	AnyKeywords              []string           `json:"any_keywords,omitempty"`
+	AllKeywords              []string           `json:"all_keywords,omitempty"`
+	Regex                    *regexp.Regexp     `json:"-"`
+	MinMessages              int                `json:"min_messages,omitempty"`
+	MaxMessages              int                `json:"max_messages,omitempty"`
+	MinSinceLastIntervention time.Duration      `json:"min_since_last_intervention,omitempty"`
+	Emotions                 []EmotionThreshold `json:"emotions,omitempty"`
+
+	ContextText string `json:"context_text"`
+	ContextType string `json:"context_type"`
+
+	Cooldown         time.Duration `json:"cooldown,omitempty"`
+	CooldownMessages int           `json:"cooldown_messages,omitempty"`
+}
+
+// EmotionThreshold matches when a Hume expression score is within [Min, Max]
+type EmotionThreshold struct {
+	Name string   `json:"name"`
+	Min  *float64 `json:"min,omitempty"`
+	Max  *float64 `json:"max,omitempty"`
+}
+
+// ValidationError describes a problem with one rule, located by line number
+type ValidationError struct {
+	Line    int
+	Rule    string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	if e.Rule != "" {
+		return fmt.Sprintf("line %d: rule %q: %s", e.Line, e.Rule, e.Message)
+	}
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// ValidationErrors collects every problem found in a rule file
+type ValidationErrors struct {
+	Path   string
+	Errors []ValidationError
+}
+
+func (e *ValidationErrors) Error() string {
+	lines := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		lines = append(lines, fmt.Sprintf("%s:%s", e.Path, strings.TrimPrefix(err.Error(), "line ")))
+	}
+	return fmt.Sprintf("invalid rule file:\n%s", strings.Join(lines, "\n"))
+}
+
+// duration accepts Go duration strings such as "90s" or "5m"
+type duration time.Duration
+
+func (d *duration) UnmarshalYAML(value *yaml.Node) error {
+	var raw string
+	if err := value.Decode(&raw); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(raw)
+	if err != nil {
+		return fmt.Errorf("line %d: invalid duration %q", value.Line, raw)
+	}
+	*d = duration(parsed)
+	return nil
+}
+
+type ruleSpec struct {
+	Name     string `yaml:"name"`
+	Priority int    `yaml:"priority"`
+	Disabled bool   `yaml:"disabled"`
+	When     struct {
+		Role                     string   `yaml:"role"`
+		AnyKeywords              []string `yaml:"any_keywords"`
+		AllKeywords              []string `yaml:"all_keywords"`
+		Regex                    string   `yaml:"regex"`
+		MinMessages              int      `yaml:"min_messages"`
+		MaxMessages              int      `yaml:"max_messages"`
+		MinSinceLastIntervention duration `yaml:"min_since_last_intervention"`
+		Emotions                 []struct {
+			Name string   `yaml:"name"`
+			Min  *float64 `yaml:"min"`
+			Max  *float64 `yaml:"max"`
+		} `yaml:"emotions"`
+	} `yaml:"when"`
+	Context struct {
+		Text string `yaml:"text"`
+		Type string `yaml:"type"`
+	} `yaml:"context"`
+	Cooldown         duration `yaml:"cooldown"`
+	CooldownMessages int      `yaml:"cooldown_messages"`
+}
+
+var lineNumberPattern = regexp.MustCompile(`^line (\d+): `)
+
+// ParseRules parses and validates rule file contents. Every problem is
+// reported, not just the first, so authors can fix a file in one pass.
+func ParseRules(path string, data []byte) ([]Rule, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(root.Content) == 0 {
+		return nil, nil
+	}
+
+	// Accept either a top-level "rules" key or a bare list
+	list := root.Content[0]
+	if list.Kind == yaml.MappingNode {
+		list = mappingValue(list, "rules")
+		if list == nil {
+			return nil, &ValidationErrors{Path: path, Errors: []ValidationError{{Line: root.Content[0].Line, Message: `missing "rules" list`}}}
+		}
+	}
+	if list.Kind != yaml.SequenceNode {
+		return nil, &ValidationErrors{Path: path, Errors: []ValidationError{{Line: list.Line, Message: "rules must be a list"}}}
+	}
+
+	var rules []Rule
+	var problems []ValidationError
+	seen := make(map[string]int)
+
+	for _, node := range list.Content {
+		var spec ruleSpec
+		if err := node.Decode(&spec); err != nil {
+			problems = append(problems, decodeErrors(node, err)...)
+			continue
+		}
+		if spec.Disabled {
+			continue
+		}
+
+		fail := func(field, format string, args ...interface{}) {
+			problems = append(problems, ValidationError{
+				Line:    fieldLine(node, field),
+				Rule:    spec.Name,
+				Message: fmt.Sprintf(format, args...),
+			})
+		}
+
+		if spec.Name == "" {
+			fail("name", "name is required")
+		} else if firstLine, ok := seen[spec.Name]; ok {
+			fail("name", "duplicate rule name (first defined on line %d)", firstLine)
+		} else {
+			seen[spec.Name] = node.Line
+		}
+
+		rule := Rule{
+			Name:                     spec.Name,
+			Priority:                 spec.Priority,
+			Line:                     node.Line,
+			Role:                     strings.ToLower(spec.When.Role),
+			AnyKeywords:              spec.When.AnyKeywords,
+			AllKeywords:              spec.When.AllKeywords,
+			MinMessages:              spec.When.MinMessages,
+			MaxMessages:              spec.When.MaxMessages,
+			MinSinceLastIntervention: time.Duration(spec.When.MinSinceLastIntervention),
+			ContextText:              strings.TrimSpace(spec.Context.Text),
+			ContextType:              spec.Context.Type,
+			Cooldown:                 time.Duration(spec.Cooldown),
+			CooldownMessages:         spec.CooldownMessages,
+		}
+
+		switch rule.Role {
+		case "", "user", "assistant":
+		default:
+			fail("when.role", "role must be \"user\" or \"assistant\", got %q", spec.When.Role)
+		}
+
+		if spec.When.Regex != "" {
+			re, err := regexp.Compile("(?i)" + spec.When.Regex)
+			if err != nil {
+				fail("when.regex", "invalid regex: %v", err)
+			}
+			rule.Regex = re
+		}
+
+		if rule.MinMessages < 0 || rule.MaxMessages < 0 {
+			fail("when", "message counts can't be negative")
+		}
+		if rule.MaxMessages > 0 && rule.MinMessages > rule.MaxMessages {
+			fail("when.max_messages", "max_messages (%d) is less than min_messages (%d)", rule.MaxMessages, rule.MinMessages)
+		}
+
+		for _, emotion := range spec.When.Emotions {
+			if emotion.Name == "" {
+				fail("when.emotions", "emotion name is required")
+				continue
+			}
+			if emotion.Min == nil && emotion.Max == nil {
+				fail("when.emotions", "emotion %q needs a min or max score", emotion.Name)
+			}
+			for _, bound := range []*float64{emotion.Min, emotion.Max} {
+				if bound != nil && (*bound < 0 || *bound > 1) {
+					fail("when.emotions", "emotion %q scores must be between 0 and 1", emotion.Name)
+				}
+			}
+			rule.Emotions = append(rule.Emotions, EmotionThreshold{Name: emotion.Name, Min: emotion.Min, Max: emotion.Max})
+		}
+
+		if len(rule.AnyKeywords) == 0 && len(rule.AllKeywords) == 0 && rule.Regex == nil &&
+			len(rule.Emotions) == 0 && rule.MinMessages == 0 && spec.When.Regex == "" {
+			fail("when", "at least one trigger (keywords, regex, emotions or min_messages) is required")
+		}
+
+		if rule.ContextText == "" {
+			fail("context.text", "context text is required")
+		}
+		switch rule.ContextType {
+		case "":
+			rule.ContextType = ContextTemporary
+		case ContextTemporary, ContextPersistent:
+		default:
+			fail("context.type", "context type must be %q or %q, got %q", ContextTemporary, ContextPersistent, rule.ContextType)
+		}
+
+		if rule.Cooldown < 0 || rule.CooldownMessages < 0 {
+			fail("cooldown", "cooldowns can't be negative")
+		}
+
+		rules = append(rules, rule)
+	}
+
+	if len(problems) > 0 {
+		return nil, &ValidationErrors{Path: path, Errors: problems}
+	}
+
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].Priority > rules[j].Priority
+	})
+	return rules, nil
+}
+
+// decodeErrors converts yaml type errors into line-numbered validation errors
+func decodeErrors(node *yaml.Node, err error) []ValidationError {
+	name := ""
+	if nameNode := mappingValue(node, "name"); nameNode != nil {
+		name = nameNode.Value
+	}
+
+	var messages []string
+	if typeErr, ok := err.(*yaml.TypeError); ok {
+		messages = typeErr.Errors
+	} else {
+		messages = []string{err.Error()}
+	}
+
+	problems := make([]ValidationError, 0, len(messages))
+	for _, message := range messages {
+		line := node.Line
+		if match := lineNumberPattern.FindStringSubmatch(message); match != nil {
+			fmt.Sscanf(match[1], "%d", &line)
+			message = message[len(match[0]):]
+		}
+		problems = append(problems, ValidationError{Line: line, Rule: name, Message: message})
+	}
+	return problems
+}
+
+// mappingValue returns the value node for key in a mapping node
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// fieldLine finds the line of a dotted field path, falling back to the
+// closest enclosing node
+func fieldLine(node *yaml.Node, path string) int {
+	line := node.Line
+	current := node
+	for _, key := range strings.Split(path, ".") {
+		current = mappingValue(current, key)
+		if current == nil {
+			break
+		}
+		line = current.Line
+	}
+	return line
+}
+
+// firing records when a rule last fired in a conversation
+type firing struct {
+	at           time.Time
+	messageCount int
+}
+
+type conversationState struct {
+	lastIntervention time.Time
+	fired            map[string]firing
+	touched          time.Time
+}
+
+const (
+	// maxTrackedConversations triggers pruning of idle cooldown state
+	maxTrackedConversations = 1000
+	conversationStateTTL    = 24 * time.Hour
+)
+
+// RuleEngine evaluates rules from a file and can hot-reload it. Cooldowns and
+// intervention times are tracked per conversation, using the Session attached
+// to the analysis context; without one, rules are evaluated statelessly.
+// Analyze doesn't change that state: a firing only counts once it has been
+// passed to RecordIntervention.
+type RuleEngine struct {
+	path string
+
+	mu      sync.RWMutex
+	rules   []Rule
+	modTime time.Time
+	size    int64
+
+	stateMu sync.Mutex
+	state   map[string]*conversationState
+	now     func() time.Time
+}
+
+// LoadRuleFile reads and validates a rule file
+func LoadRuleFile(path string) (*RuleEngine, error) {
+	engine := &RuleEngine{
+		path:  path,
+		state: make(map[string]*conversationState),
+		now:   time.Now,
+	}
+	if err := engine.Reload(); err != nil {
+		return nil, err
+	}
+	return engine, nil
+}
+
+// Reload re-reads the rule file. On error the previous rules stay active.
+func (e *RuleEngine) Reload() error {
+	info, err := os.Stat(e.path)
+	if err != nil {
+		return fmt.Errorf("failed to read rule file: %w", err)
+	}
+	data, err := os.ReadFile(e.path)
+	if err != nil {
+		return fmt.Errorf("failed to read rule file: %w", err)
+	}
+
+	rules, err := ParseRules(e.path, data)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	e.rules = rules
+	e.modTime = info.ModTime()
+	e.size = info.Size()
+	e.mu.Unlock()
+
+	log.Printf("Loaded %d context rules from %s", len(rules), e.path)
+	return nil
+}
+
+// Watch polls the rule file and reloads it when it changes, until ctx is done
+func (e *RuleEngine) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(e.path)
+			if err != nil {
+				continue
+			}
+
+			e.mu.RLock()
+			changed := !info.ModTime().Equal(e.modTime) || info.Size() != e.size
+			e.mu.RUnlock()
+			if !changed {
+				continue
+			}
+
+			if err := e.Reload(); err != nil {
+				log.Printf("Keeping previous context rules: %v", err)
+				// Remember the broken version so it isn't re-parsed every tick
+				e.mu.Lock()
+				e.modTime = info.ModTime()
+				e.size = info.Size()
+				e.mu.Unlock()
+			}
+		}
+	}
+}
+
+// Rules returns the active rules in evaluation order
+func (e *RuleEngine) Rules() []Rule {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	rules := make([]Rule, len(e.rules))
+	copy(rules, e.rules)
+	return rules
+}
+
+func (e *RuleEngine) Name() string { return "rules" }
+
+func (e *RuleEngine) Analyze(ctx context.Context, history []ConversationMessage) (AnalyzeResponse, error) {
+	if len(history) == 0 {
+		return NoIntervention("No messages to analyze"), nil
+	}
+
+	session, _ := SessionFromContext(ctx)
+	messageCount := session.MessageCount
+	if messageCount < len(history) {
+		messageCount = len(history)
+	}
+	lastMessage := history[len(history)-1]
+	now := e.now()
+
+	e.mu.RLock()
+	rules := e.rules
+	e.mu.RUnlock()
+
+	e.stateMu.Lock()
+	defer e.stateMu.Unlock()
+
+	var state *conversationState
+	if session.ConversationID != "" {
+		state = e.conversationState(session.ConversationID, now)
+	}
+
+	for _, rule := range rules {
+		if !rule.matches(lastMessage, messageCount) {
+			continue
+		}
+
+		if state != nil {
+			if rule.MinSinceLastIntervention > 0 && !state.lastIntervention.IsZero() &&
+				now.Sub(state.lastIntervention) < rule.MinSinceLastIntervention {
+				continue
+			}
+			if last, ok := state.fired[rule.Name]; ok {
+				if rule.Cooldown > 0 && now.Sub(last.at) < rule.Cooldown {
+					continue
+				}
+				if rule.CooldownMessages > 0 && messageCount-last.messageCount < rule.CooldownMessages {
+					continue
+				}
+			}
+		}
+
+		return AnalyzeResponse{
+			ShouldIntervene: true,
+			ContextText:     rule.ContextText,
+			ContextType:     rule.ContextType,
+			Reasoning:       fmt.Sprintf("Matched rule %q", rule.Name),
+			Rule:            rule.Name,
+		}, nil
+	}
+
+	return NoIntervention("No rule matched"), nil
+}
+
+// RecordIntervention starts the cooldowns for the rule behind a delivered
+// result. Results from other analyzers, or without a conversation, are ignored.
+func (e *RuleEngine) RecordIntervention(ctx context.Context, result AnalyzeResponse) {
+	session, _ := SessionFromContext(ctx)
+	if result.Rule == "" || session.ConversationID == "" {
+		return
+	}
+	now := e.now()
+
+	e.stateMu.Lock()
+	defer e.stateMu.Unlock()
+
+	state := e.conversationState(session.ConversationID, now)
+	state.lastIntervention = now
+	state.fired[result.Rule] = firing{at: now, messageCount: session.MessageCount}
+}
+
+// conversationState returns the cooldown state for a conversation, pruning
+// idle conversations once too many are tracked. Callers must hold stateMu.
+func (e *RuleEngine) conversationState(conversationID string, now time.Time) *conversationState {
+	if len(e.state) > maxTrackedConversations {
+		for id, state := range e.state {
+			if now.Sub(state.touched) > conversationStateTTL {
+				delete(e.state, id)
+			}
+		}
+	}
+
+	state, ok := e.state[conversationID]
+	if !ok {
+		state = &conversationState{fired: make(map[string]firing)}
+		e.state[conversationID] = state
+	}
+	state.touched = now
+	return state
+}
+
+// matches checks the message-level conditions of a rule
+func (r *Rule) matches(msg ConversationMessage, messageCount int) bool {
+	role := r.Role
+	if role == "" {
+		role = "user"
+	}
+	if !strings.EqualFold(msg.Role, role) {
+		return false
+	}
+
+	if r.MinMessages > 0 && messageCount < r.MinMessages {
+		return false
+	}
+	if r.MaxMessages > 0 && messageCount > r.MaxMessages {
+		return false
+	}
+
+	if len(r.AnyKeywords) > 0 && !ContainsKeywords(msg.Content, r.AnyKeywords) {
+		return false
+	}
+	for _, keyword := range r.AllKeywords {
+		if !ContainsKeywords(msg.Content, []string{keyword}) {
+			return false
+		}
+	}
+
+	if r.Regex != nil && !r.Regex.MatchString(msg.Content) {
+		return false
+	}
+
+	for _, threshold := range r.Emotions {
+		score, ok := emotionScore(msg.Emotions, threshold.Name)
+		if !ok {
+			return false
+		}
+		if threshold.Min != nil && score < *threshold.Min {
+			return false
+		}
+		if threshold.Max != nil && score > *threshold.Max {
+			return false
+		}
+	}
+
+	return true
+}
+
+// emotionScore looks up an expression score case-insensitively
+func emotionScore(emotions map[string]float64, name string) (float64, bool) {
+	if score, ok := emotions[name]; ok {
+		return score, true
+	}
+	for emotion, score := range emotions {
+		if strings.EqualFold(emotion, name) {
+			return score, true
+		}
+	}
+	return 0, false
+}
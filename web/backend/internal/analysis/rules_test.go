@@ -0,0 +1,294 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testRules = `
+rules:
+  - name: high-anxiety
+    priority: 20
+    when:
+      emotions:
+        - name: Anxiety
+          min: 0.5
+      min_since_last_intervention: 2m
+    context:
+      text: Slow down.
+  - name: settle-in
+    when:
+      min_messages: 3
+    context:
+      text: Summarise now and then.
+      type: persistent
+    cooldown: 30m
+`
+
+func loadTestRules(t *testing.T) (*RuleEngine, *time.Time) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte(testRules), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	engine, err := LoadRuleFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	engine.now = func() time.Time { return now }
+	return engine, &now
+}
+
+func TestRuleStateOnlyChangesWhenRecorded(t *testing.T) {
+	engine, now := loadTestRules(t)
+	ctx := WithSession(context.Background(), Session{ConversationID: "conv", MessageCount: 5})
+	calm := []ConversationMessage{{Role: "user", Content: "hello"}}
+	anxious := []ConversationMessage{{Role: "user", Content: "hello", Emotions: map[string]float64{"Anxiety": 0.8}}}
+
+	// settle-in matches every turn; until it's delivered it starts no cooldown
+	for i := 0; i < 3; i++ {
+		result, err := engine.Analyze(ctx, calm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Rule != "settle-in" {
+			t.Fatalf("turn %d: got rule %q, want settle-in", i, result.Rule)
+		}
+	}
+
+	result, _ := engine.Analyze(ctx, anxious)
+	if result.Rule != "high-anxiety" {
+		t.Fatalf("got rule %q, want high-anxiety", result.Rule)
+	}
+
+	// Delivering it starts the cooldowns
+	engine.RecordIntervention(ctx, result)
+	*now = now.Add(time.Minute)
+	if result, _ := engine.Analyze(ctx, anxious); result.Rule == "high-anxiety" {
+		t.Fatal("high-anxiety fired within min_since_last_intervention")
+	}
+
+	*now = now.Add(2 * time.Minute)
+	if result, _ := engine.Analyze(ctx, anxious); result.Rule != "high-anxiety" {
+		t.Fatalf("got rule %q after the gap, want high-anxiety", result.Rule)
+	}
+}
+
+func TestChainRecordsIntoRuleEngine(t *testing.T) {
+	engine, now := loadTestRules(t)
+	chain := NewChain(MergeFirst)
+	chain.Register(engine, PriorityRules)
+	chain.Register(KeywordAnalyzer{}, PriorityKeywords)
+	ctx := WithSession(context.Background(), Session{ConversationID: "conv", MessageCount: 5})
+	history := []ConversationMessage{{Role: "user", Content: "hello"}}
+
+	result, err := chain.Analyze(ctx, history)
+	if err != nil || result.Rule != "settle-in" {
+		t.Fatalf("got %+v, %v; want settle-in", result, err)
+	}
+	RecordIntervention(ctx, chain, result)
+
+	*now = now.Add(10 * time.Minute)
+	if result, _ := chain.Analyze(ctx, history); result.ShouldIntervene {
+		t.Fatalf("settle-in fired again during its cooldown: %+v", result)
+	}
+}
+
+func TestParseRulesReportsEveryProblemByLine(t *testing.T) {
+	const file = `rules:
+  - name: bad-role
+    when:
+      role: narrator
+      any_keywords: [hi]
+    context:
+      text: Hi.
+  - name: bad-regex
+    when:
+      regex: "can't (cope"
+    context:
+      text: Hi.
+  - name: bad-duration
+    when:
+      any_keywords: [hi]
+      min_since_last_intervention: soon
+    context:
+      text: Hi.
+  - name: bad-priority
+    priority: high
+    when:
+      any_keywords: [hi]
+    context:
+      text: Hi.
+  - name: bad-role
+    when:
+      any_keywords: [hi]
+    context:
+      text: Hi.
+      type: forever
+`
+	_, err := ParseRules("rules.yaml", []byte(file))
+	var problems *ValidationErrors
+	if !errors.As(err, &problems) {
+		t.Fatalf("ParseRules = %v, want ValidationErrors", err)
+	}
+
+	want := []struct {
+		line    int
+		rule    string
+		message string
+	}{
+		{4, "bad-role", `role must be "user" or "assistant"`},
+		{10, "bad-regex", "invalid regex"},
+		{16, "bad-duration", `invalid duration "soon"`},
+		{20, "bad-priority", "cannot unmarshal"},
+		{25, "bad-role", "duplicate rule name (first defined on line 2)"},
+		{30, "bad-role", "context type must be"},
+	}
+	if len(problems.Errors) != len(want) {
+		t.Fatalf("got %d problems, want %d:\n%v", len(problems.Errors), len(want), err)
+	}
+	for i, w := range want {
+		got := problems.Errors[i]
+		if got.Line != w.line || got.Rule != w.rule || !strings.Contains(got.Message, w.message) {
+			t.Errorf("problem %d = line %d rule %q %q, want line %d rule %q containing %q", i, got.Line, got.Rule, got.Message, w.line, w.rule, w.message)
+		}
+	}
+	if !strings.Contains(err.Error(), "rules.yaml:16: rule \"bad-duration\"") {
+		t.Errorf("error text doesn't locate problems as path:line:\n%v", err)
+	}
+}
+
+func TestParseRulesStructure(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		line    int
+		message string
+	}{
+		{"missing rules key", "other: []\n", 1, `missing "rules" list`},
+		{"rules not a list", "rules:\n  name: x\n", 2, "rules must be a list"},
+		{"no trigger", "rules:\n  - name: idle\n    context:\n      text: Hi.\n", 2, "at least one trigger"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseRules("rules.yaml", []byte(tt.file))
+			var problems *ValidationErrors
+			if !errors.As(err, &problems) || len(problems.Errors) != 1 {
+				t.Fatalf("ParseRules = %v, want one validation error", err)
+			}
+			if got := problems.Errors[0]; got.Line != tt.line || !strings.Contains(got.Message, tt.message) {
+				t.Fatalf("got line %d %q, want line %d containing %q", got.Line, got.Message, tt.line, tt.message)
+			}
+		})
+	}
+
+	rules, err := ParseRules("rules.yaml", []byte("rules:\n  - name: off\n    disabled: true\n"))
+	if err != nil || len(rules) != 0 {
+		t.Fatalf("a disabled rule parsed as %+v, %v; want it skipped", rules, err)
+	}
+}
+
+// writeRules replaces the rule file, moving its modification time on so the
+// change is seen even on filesystems with coarse timestamps
+func writeRules(t *testing.T, path, contents string, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitForRules polls until the engine has seen the file at modTime
+func waitForRules(t *testing.T, engine *RuleEngine, modTime time.Time) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		engine.mu.RLock()
+		seen := engine.modTime.Equal(modTime)
+		engine.mu.RUnlock()
+		if seen {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("Watch didn't pick up the rule file change")
+}
+
+func ruleNames(engine *RuleEngine) string {
+	var names []string
+	for _, rule := range engine.Rules() {
+		names = append(names, rule.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestWatchReloadsRuleFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	writeRules(t, path, testRules, modTime)
+	engine, err := LoadRuleFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		engine.Watch(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// An edit is picked up
+	modTime = modTime.Add(time.Minute)
+	writeRules(t, path, `
+rules:
+  - name: greeting
+    when:
+      any_keywords: [hello]
+    context:
+      text: Greet them back.
+`, modTime)
+	waitForRules(t, engine, modTime)
+	if got := ruleNames(engine); got != "greeting" {
+		t.Fatalf("after an edit, rules = %q, want greeting", got)
+	}
+
+	// A broken edit keeps the rules that were working
+	modTime = modTime.Add(time.Minute)
+	writeRules(t, path, `
+rules:
+  - name: greeting
+    when:
+      regex: "(unclosed"
+    context:
+      text: Greet them back.
+`, modTime)
+	waitForRules(t, engine, modTime)
+	if got := ruleNames(engine); got != "greeting" {
+		t.Fatalf("after a broken edit, rules = %q, want the previous greeting", got)
+	}
+	result, err := engine.Analyze(context.Background(), []ConversationMessage{{Role: "user", Content: "hello there"}})
+	if err != nil || result.Rule != "greeting" {
+		t.Fatalf("after a broken edit, Analyze = %+v, %v; want greeting to still fire", result, err)
+	}
+
+	// Fixing the file loads it again
+	modTime = modTime.Add(time.Minute)
+	writeRules(t, path, testRules, modTime)
+	waitForRules(t, engine, modTime)
+	if got := ruleNames(engine); got != "high-anxiety,settle-in" {
+		t.Fatalf("after fixing the file, rules = %q, want high-anxiety,settle-in", got)
+	}
+}
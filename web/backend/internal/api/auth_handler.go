@@ -33,7 +33,7 @@ func (s *Server) loginHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get user from database
-	user, err := s.db.GetUserByUsername(r.Context(), req.Username)
+	user, err := s.store.GetUserByUsername(r.Context(), req.Username)
 	if err != nil {
 		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 		return
@@ -90,7 +90,7 @@ func (s *Server) meHandler(w http.ResponseWriter, r *http.Request) {
 	// Fetch user from database to get name field
 	userIDUUID, err := uuid.Parse(userID)
 	if err == nil {
-		user, err := s.db.GetUserByID(r.Context(), userIDUUID)
+		user, err := s.store.GetUserByID(r.Context(), userIDUUID)
 		if err == nil {
 			response := map[string]interface{}{
 				"user_id":  userID,
@@ -3,26 +3,24 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/hume-evi/web/internal/analysis"
 )
 
 // ConversationMessage represents a message in the conversation
-type ConversationMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
-}
+type ConversationMessage = analysis.ConversationMessage
 
 // AnalyzeRequest represents the request to analyze a conversation
 type AnalyzeRequest struct {
-	History []ConversationMessage `json:"history"`
+	// ConversationID is optional; when set, rule cooldowns are tracked for it
+	ConversationID string                `json:"conversationId,omitempty"`
+	History        []ConversationMessage `json:"history"`
 }
 
 // AnalyzeResponse represents the AI analysis response
-type AnalyzeResponse struct {
-	ShouldIntervene bool   `json:"shouldIntervene"`
-	ContextText     string `json:"contextText"`
-	ContextType     string `json:"contextType"` // "temporary" or "persistent"
-	Reasoning       string `json:"reasoning"`
-}
+type AnalyzeResponse = analysis.AnalyzeResponse
 
 // analyzeConversationHandler handles requests to analyze conversations with an external AI
 func (s *Server) analyzeConversationHandler(w http.ResponseWriter, r *http.Request) {
@@ -37,91 +35,39 @@ func (s *Server) analyzeConversationHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	// TODO: Integrate your external AI model here
-	// Examples of what you might do:
-	// 1. Call OpenAI/Claude/etc. to analyze sentiment and topics
-	// 2. Detect patterns (e.g., user is stuck, needs redirection)
-	// 3. Generate context to guide EVI's response
-	//
-	// Example integration:
-	/*
-		// Build prompt for your AI
-		prompt := buildAnalysisPrompt(req.History)
-		
-		// Call your AI API (OpenAI, Claude, etc.)
-		analysis, err := callExternalAI(prompt)
+	ctx := r.Context()
+	if req.ConversationID != "" {
+		userID, err := uuid.Parse(getUserID(r))
 		if err != nil {
-			http.Error(w, "Failed to analyze conversation", http.StatusInternalServerError)
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
 			return
 		}
-		
-		// Parse AI response to determine intervention
-		response := AnalyzeResponse{
-			ShouldIntervene: analysis.NeedsIntervention,
-			ContextText:     analysis.SuggestedContext,
-			ContextType:     "temporary", // or "persistent"
-			Reasoning:       analysis.Reasoning,
+		convID, err := uuid.Parse(req.ConversationID)
+		if err != nil {
+			http.Error(w, "Invalid conversation ID", http.StatusBadRequest)
+			return
 		}
-	*/
-
-	// Default response - no intervention
-	response := AnalyzeResponse{
-		ShouldIntervene: false,
-		ContextText:     "",
-		ContextType:     "temporary",
-		Reasoning:       "Default: No intervention needed",
+		if _, err := s.store.GetConversation(ctx, convID, userID); err != nil {
+			http.Error(w, "Conversation not found", http.StatusNotFound)
+			return
+		}
+		ctx = analysis.WithSession(ctx, analysis.Session{
+			ConversationID: convID.String(),
+			MessageCount:   len(req.History),
+		})
 	}
 
-	// Example: Simple rule-based analysis
-	if len(req.History) > 0 {
-		lastMessage := req.History[len(req.History)-1]
-		
-		// Check for stress indicators
-		if containsKeywords(lastMessage.Content, []string{"stress", "overwhelm", "anxious", "worried"}) {
-			response.ShouldIntervene = true
-			response.ContextText = "The user is expressing stress. Be empathetic, validate their feelings, and help them break down the problem into manageable steps."
-			response.ContextType = "temporary"
-			response.Reasoning = "Detected stress keywords in user message"
-		}
+	// Same analyzer chain the EVI proxy runs on every completed user message
+	response, err := s.analyzer.Analyze(ctx, req.History)
+	if err != nil {
+		http.Error(w, "Failed to analyze conversation", http.StatusInternalServerError)
+		return
+	}
+	if response.ShouldIntervene {
+		// The caller injects what we return, so count it as delivered
+		analysis.RecordIntervention(ctx, s.analyzer, response)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
-
-// Helper function to check for keywords
-func containsKeywords(text string, keywords []string) bool {
-	lowerText := text
-	for _, keyword := range keywords {
-		if contains(lowerText, keyword) {
-			return true
-		}
-	}
-	return false
-}
-
-func contains(s, substr string) bool {
-	// Simple case-insensitive contains
-	for i := 0; i <= len(s)-len(substr); i++ {
-		match := true
-		for j := 0; j < len(substr); j++ {
-			c1 := s[i+j]
-			c2 := substr[j]
-			if c1 >= 'A' && c1 <= 'Z' {
-				c1 += 'a' - 'A'
-			}
-			if c2 >= 'A' && c2 <= 'Z' {
-				c2 += 'a' - 'A'
-			}
-			if c1 != c2 {
-				match = false
-				break
-			}
-		}
-		if match {
-			return true
-		}
-	}
-	return false
-}
-
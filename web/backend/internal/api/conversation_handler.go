@@ -2,18 +2,48 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+
+	"github.com/hume-evi/web/internal/db"
 )
 
 type CreateConversationRequest struct {
 	Title string `json:"title"`
 }
 
+// ConversationsResponse is one page of conversations. Pass NextCursor back
+// as ?cursor= for the next page; it is empty on the last one.
+type ConversationsResponse struct {
+	Conversations []db.Conversation `json:"conversations"`
+	NextCursor    string            `json:"next_cursor,omitempty"`
+}
+
+// MessagesResponse is one page of messages, oldest first; without a cursor
+// it is the newest messages. NextCursor continues the way the page was
+// read: pass it as ?before= to load older turns, or as ?after= when the
+// page was itself read with ?after=. It is empty when there is nothing
+// further.
+type MessagesResponse struct {
+	Messages   []db.Message `json:"messages"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
+// queryCursor decodes an optional cursor query parameter
+func queryCursor(r *http.Request, name string) (*db.Cursor, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	return db.DecodeCursor(value)
+}
+
 func (s *Server) createConversationHandler(w http.ResponseWriter, r *http.Request) {
 	userIDStr := getUserID(r)
 	userID, err := uuid.Parse(userIDStr)
@@ -31,7 +61,7 @@ func (s *Server) createConversationHandler(w http.ResponseWriter, r *http.Reques
 		req.Title = "Conversation " + time.Now().Format("2006-01-02 15:04:05")
 	}
 
-	conv, err := s.db.CreateConversation(r.Context(), userID, req.Title)
+	conv, err := s.store.CreateConversation(r.Context(), userID, req.Title)
 	if err != nil {
 		http.Error(w, "Failed to create conversation", http.StatusInternalServerError)
 		return
@@ -41,6 +71,8 @@ func (s *Server) createConversationHandler(w http.ResponseWriter, r *http.Reques
 	json.NewEncoder(w).Encode(conv)
 }
 
+// listConversationsHandler pages through the user's conversations, most
+// recently updated first, with ?limit= and ?cursor=
 func (s *Server) listConversationsHandler(w http.ResponseWriter, r *http.Request) {
 	userIDStr := getUserID(r)
 	userID, err := uuid.Parse(userIDStr)
@@ -56,14 +88,30 @@ func (s *Server) listConversationsHandler(w http.ResponseWriter, r *http.Request
 		}
 	}
 
-	conversations, err := s.db.ListConversations(r.Context(), userID, limit)
+	after, err := queryCursor(r, "cursor")
+	if err != nil {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
+
+	// Ask for one extra to learn whether there is another page
+	conversations, err := s.store.ListConversations(r.Context(), userID, limit+1, after)
 	if err != nil {
 		http.Error(w, "Failed to list conversations", http.StatusInternalServerError)
 		return
 	}
 
+	resp := ConversationsResponse{Conversations: conversations}
+	if len(conversations) > limit {
+		resp.Conversations = conversations[:limit]
+		resp.NextCursor = db.ConversationCursor(resp.Conversations[limit-1]).Encode()
+	}
+	if resp.Conversations == nil {
+		resp.Conversations = []db.Conversation{}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(conversations)
+	json.NewEncoder(w).Encode(resp)
 }
 
 func (s *Server) getConversationHandler(w http.ResponseWriter, r *http.Request) {
@@ -81,7 +129,7 @@ func (s *Server) getConversationHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	conv, err := s.db.GetConversation(r.Context(), convID, userID)
+	conv, err := s.store.GetConversation(r.Context(), convID, userID)
 	if err != nil {
 		http.Error(w, "Conversation not found", http.StatusNotFound)
 		return
@@ -91,6 +139,9 @@ func (s *Server) getConversationHandler(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(conv)
 }
 
+// getMessagesHandler pages through a conversation's messages with ?limit=
+// and an optional ?before= or ?after= cursor. Prosody scores are left out
+// unless ?emotions=true.
 func (s *Server) getMessagesHandler(w http.ResponseWriter, r *http.Request) {
 	userIDStr := getUserID(r)
 	userID, err := uuid.Parse(userIDStr)
@@ -106,14 +157,62 @@ func (s *Server) getMessagesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	messages, err := s.db.GetMessages(r.Context(), convID, userID)
+	limit := 100
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 500 {
+			limit = l
+		}
+	}
+
+	after, err := queryCursor(r, "after")
+	if err != nil {
+		http.Error(w, "Invalid after cursor", http.StatusBadRequest)
+		return
+	}
+	before, err := queryCursor(r, "before")
+	if err != nil {
+		http.Error(w, "Invalid before cursor", http.StatusBadRequest)
+		return
+	}
+	if after != nil && before != nil {
+		http.Error(w, "Use either after or before, not both", http.StatusBadRequest)
+		return
+	}
+
+	// Ask for one extra to learn whether there is another page
+	page := db.MessagePage{Limit: limit + 1, After: after, Before: before}
+	messages, err := s.store.ListMessages(r.Context(), convID, userID, page)
+	if errors.Is(err, db.ErrNotFound) {
+		http.Error(w, "Conversation not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to get messages", http.StatusInternalServerError)
 		return
 	}
 
+	if r.URL.Query().Get("emotions") != "true" {
+		for i := range messages {
+			messages[i].Emotions = nil
+		}
+	}
+
+	resp := MessagesResponse{Messages: messages}
+	if len(messages) > limit {
+		if after != nil {
+			resp.Messages = messages[:limit]
+			resp.NextCursor = db.MessageCursor(resp.Messages[limit-1]).Encode()
+		} else {
+			resp.Messages = messages[1:]
+			resp.NextCursor = db.MessageCursor(resp.Messages[0]).Encode()
+		}
+	}
+	if resp.Messages == nil {
+		resp.Messages = []db.Message{}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(messages)
+	json.NewEncoder(w).Encode(resp)
 }
 
 func (s *Server) updateConversationStatusHandler(w http.ResponseWriter, r *http.Request) {
@@ -131,6 +230,14 @@ func (s *Server) updateConversationStatusHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
+	// Verify conversation belongs to user; the status update would silently
+	// skip someone else's, but the graph job below would not
+	_, err = s.store.GetConversation(r.Context(), convID, userID)
+	if err != nil {
+		http.Error(w, "Conversation not found", http.StatusNotFound)
+		return
+	}
+
 	var req struct {
 		Status string `json:"status"`
 	}
@@ -139,11 +246,17 @@ func (s *Server) updateConversationStatusHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
-	if err := s.db.UpdateConversationStatus(r.Context(), convID, userID, req.Status); err != nil {
+	if err := s.store.UpdateConversationStatus(r.Context(), convID, userID, req.Status); err != nil {
 		http.Error(w, "Failed to update conversation", http.StatusInternalServerError)
 		return
 	}
 
+	if req.Status == "paused" || req.Status == "ended" {
+		if err := s.graphJobs.Enqueue(r.Context(), convID, userID); err != nil {
+			log.Printf("Failed to schedule graph extraction for %s: %v", convID, err)
+		}
+	}
+
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -162,7 +275,7 @@ func (s *Server) deleteConversationHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	if err := s.db.DeleteConversation(r.Context(), convID, userID); err != nil {
+	if err := s.store.DeleteConversation(r.Context(), convID, userID); err != nil {
 		http.Error(w, "Failed to delete conversation", http.StatusInternalServerError)
 		return
 	}
@@ -178,7 +291,7 @@ func (s *Server) getLastActiveConversationHandler(w http.ResponseWriter, r *http
 		return
 	}
 
-	conv, err := s.db.GetLastActiveConversation(r.Context(), userID)
+	conv, err := s.store.GetLastActiveConversation(r.Context(), userID)
 	if err != nil {
 		// No active conversation found
 		w.Header().Set("Content-Type", "application/json")
@@ -190,3 +303,28 @@ func (s *Server) getLastActiveConversationHandler(w http.ResponseWriter, r *http
 	json.NewEncoder(w).Encode(conv)
 }
 
+
+func (s *Server) getContextInjectionsHandler(w http.ResponseWriter, r *http.Request) {
+	userIDStr := getUserID(r)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	vars := mux.Vars(r)
+	convID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid conversation ID", http.StatusBadRequest)
+		return
+	}
+
+	injections, err := s.store.ListContextInjections(r.Context(), convID, userID)
+	if err != nil {
+		http.Error(w, "Failed to get context injections", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(injections)
+}
@@ -2,8 +2,14 @@ package api
 
 import (
 	"encoding/json"
-	"fmt"
+	"log"
 	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	"github.com/hume-evi/web/internal/db"
+	"github.com/hume-evi/web/internal/graph"
 )
 
 // ExtractGraphRequest represents request to extract graph from conversation
@@ -12,13 +18,16 @@ type ExtractGraphRequest struct {
 	Messages       []ConversationMessage   `json:"messages"`
 }
 
-// ExtractGraphResponse represents the extracted graph structure
+// ExtractGraphResponse represents the extracted graph structure and whether
+// it was persisted to Memgraph
 type ExtractGraphResponse struct {
-	Entities      []map[string]interface{} `json:"entities"`
-	Relationships []map[string]interface{} `json:"relationships"`
+	graph.GraphData
+	Ingested bool                `json:"ingested"`
+	Report   *graph.IngestReport `json:"report,omitempty"`
 }
 
-// extractGraphHandler uses LLM to extract knowledge graph from conversation
+// extractGraphHandler uses the configured extractor (LLM with keyword
+// fallback) to extract a knowledge graph from a conversation
 func (s *Server) extractGraphHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -31,131 +40,118 @@ func (s *Server) extractGraphHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// TODO: Integrate your LLM here to extract entities and relationships
-	// Example prompt structure:
-	/*
-		prompt := `Analyze this conversation and extract:
-		1. Entities (people, topics, emotions, locations, etc.)
-		2. Relationships between entities
-		
-		Conversation:
-		` + formatMessages(req.Messages) + `
-		
-		Return JSON:
-		{
-		  "entities": [
-		    {"type": "Person", "name": "daughter", "properties": {"relation": "child"}},
-		    {"type": "Topic", "name": "moving_house", "properties": {"stress_level": "high"}},
-		    {"type": "Emotion", "name": "stress", "properties": {"intensity": "medium"}}
-		  ],
-		  "relationships": [
-		    {"from": "user", "to": "daughter", "type": "HAS_CHILD"},
-		    {"from": "user", "to": "moving_house", "type": "DISCUSSES"},
-		    {"from": "user", "to": "stress", "type": "FEELS"}
-		  ]
-		}`
-		
-		// Call OpenAI/Claude/etc.
-		response := callLLM(prompt)
-		graphData := parseGraphData(response)
-	*/
-
-	// Default response - minimal extraction
-	response := ExtractGraphResponse{
-		Entities: []map[string]interface{}{
-			{
-				"type":       "Conversation",
-				"name":       req.ConversationID,
-				"properties": map[string]string{"message_count": fmt.Sprintf("%d", len(req.Messages))},
-			},
-		},
-		Relationships: []map[string]interface{}{},
+	messages := make([]graph.ConversationMessage, 0, len(req.Messages))
+	for _, msg := range req.Messages {
+		messages = append(messages, graph.ConversationMessage{Role: msg.Role, Content: msg.Content})
 	}
 
-	// Simple keyword-based extraction (replace with LLM)
-	topics := extractTopics(req.Messages)
-	for _, topic := range topics {
-		response.Entities = append(response.Entities, map[string]interface{}{
-			"type":       "Topic",
-			"name":       topic,
-			"properties": map[string]string{},
-		})
-		response.Relationships = append(response.Relationships, map[string]interface{}{
-			"from": "user",
-			"to":   topic,
-			"type": "DISCUSSES",
-		})
-	}
+	ctx := r.Context()
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
-}
-
-// Simple topic extraction (replace with LLM-based extraction)
-func extractTopics(messages []ConversationMessage) []string {
-	keywords := map[string]bool{
-		"work":    false,
-		"stress":  false,
-		"moving":  false,
-		"house":   false,
-		"family":  false,
-		"health":  false,
-		"project": false,
+	// Only ingest into conversations the caller owns
+	var convID uuid.UUID
+	if req.ConversationID != "" {
+		userID, err := uuid.Parse(getUserID(r))
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+		convID, err = uuid.Parse(req.ConversationID)
+		if err != nil {
+			http.Error(w, "Invalid conversation ID", http.StatusBadRequest)
+			return
+		}
+		if _, err := s.store.GetConversation(ctx, convID, userID); err != nil {
+			http.Error(w, "Conversation not found", http.StatusNotFound)
+			return
+		}
 	}
 
-	for _, msg := range messages {
-		content := msg.Content
-		for keyword := range keywords {
-			if contains(content, keyword) {
-				keywords[keyword] = true
-			}
-		}
+	data, err := s.extractor.Extract(ctx, messages)
+	if err != nil {
+		log.Printf("Graph extraction failed: %v", err)
+		http.Error(w, "Failed to extract graph", http.StatusInternalServerError)
+		return
 	}
 
-	var topics []string
-	for keyword, found := range keywords {
-		if found {
-			topics = append(topics, keyword)
+	response := ExtractGraphResponse{GraphData: data}
+	if s.graph != nil && convID != uuid.Nil {
+		userID := getUserID(r)
+		if err := s.graph.SyncConversation(ctx, userID, convID.String(), messages); err != nil {
+			log.Printf("Failed to sync conversation to graph: %v", err)
+		} else if report, err := s.graph.IngestGraphData(ctx, userID, convID.String(), data); err != nil {
+			log.Printf("Failed to ingest graph data: %v", err)
+		} else {
+			response.Ingested = true
+			response.Report = report
 		}
 	}
 
-	return topics
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
 }
 
-// getUserGraphContextHandler retrieves context from the knowledge graph
+// getUserGraphContextHandler retrieves the caller's context from the knowledge graph
 func (s *Server) getUserGraphContextHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// TODO: Get user ID from JWT/session
-	// userID := "shared-user-id"
-
-	// TODO: Query Memgraph for user context
-	/*
-		if s.graphClient != nil {
-			context, err := s.graphClient.GetUserContext(r.Context(), "shared-user-id")
-			if err != nil {
-				log.Printf("Failed to get graph context: %v", err)
-			} else {
-				w.Header().Set("Content-Type", "application/json")
-				json.NewEncoder(w).Encode(context)
-				return
-			}
-		}
-	*/
-
-	// Default response
-	response := map[string]interface{}{
-		"recurring_topics":    []string{},
-		"emotional_patterns":  []string{},
-		"relationship_context": []string{},
-		"note": "Memgraph integration pending - see graph_handler.go",
+	if s.graph == nil {
+		http.Error(w, "Knowledge graph unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	context, err := s.graph.GetUserContext(r.Context(), getUserID(r))
+	if err != nil {
+		log.Printf("Failed to get graph context: %v", err)
+		http.Error(w, "Failed to get graph context", http.StatusBadGateway)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	json.NewEncoder(w).Encode(context)
 }
 
+// GraphJobsResponse summarises the background graph extraction queue
+type GraphJobsResponse struct {
+	Counts map[string]int          `json:"counts"`
+	Jobs   []db.GraphExtractionJob `json:"jobs"`
+}
+
+// listGraphJobsHandler shows the extraction queue (admin only), optionally
+// filtered with ?status=pending|running|retrying|succeeded|failed
+func (s *Server) listGraphJobsHandler(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", db.JobPending, db.JobRunning, db.JobRetrying, db.JobSucceeded, db.JobFailed:
+	default:
+		http.Error(w, "Invalid status", http.StatusBadRequest)
+		return
+	}
+
+	limit := 50
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 500 {
+			limit = l
+		}
+	}
+
+	counts, err := s.store.CountGraphExtractionJobs(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to count graph jobs", http.StatusInternalServerError)
+		return
+	}
 
+	jobs, err := s.store.ListGraphExtractionJobs(r.Context(), status, limit)
+	if err != nil {
+		http.Error(w, "Failed to list graph jobs", http.StatusInternalServerError)
+		return
+	}
+	if jobs == nil {
+		jobs = []db.GraphExtractionJob{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(GraphJobsResponse{Counts: counts, Jobs: jobs})
+}
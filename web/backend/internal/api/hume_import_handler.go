@@ -0,0 +1,294 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	"github.com/hume-evi/web/internal/db"
+	"github.com/hume-evi/web/internal/hume"
+)
+
+// maxConfigImports bounds one import request, since each config costs a few
+// Hume API calls
+const maxConfigImports = 50
+
+// HumeConfigSummary is a Hume config as listed for import. LinkedVoiceID is
+// set when a local voice already uses the config.
+type HumeConfigSummary struct {
+	ID                    string     `json:"id"`
+	Name                  string     `json:"name"`
+	Version               int        `json:"version"`
+	EVIVersion            string     `json:"evi_version"`
+	PromptID              string     `json:"prompt_id,omitempty"`
+	LanguageModelProvider string     `json:"language_model_provider,omitempty"`
+	LanguageModelResource string     `json:"language_model_resource,omitempty"`
+	HumeVoiceProvider     string     `json:"hume_voice_provider,omitempty"`
+	HumeVoiceName         string     `json:"hume_voice_name,omitempty"`
+	LinkedVoiceID         *uuid.UUID `json:"linked_voice_id"`
+}
+
+type HumeConfigsResponse struct {
+	PageNumber int                 `json:"page_number"`
+	PageSize   int                 `json:"page_size"`
+	TotalPages int                 `json:"total_pages"`
+	Configs    []HumeConfigSummary `json:"configs"`
+}
+
+type ImportHumeConfigsRequest struct {
+	ConfigIDs []string `json:"config_ids"`
+}
+
+// HumeConfigImport is the outcome for one config: "imported" with the new
+// voice, "linked" when a voice already uses it, or "error". Warnings list
+// settings the voice editor doesn't support, which were replaced by defaults.
+type HumeConfigImport struct {
+	HumeConfigID string     `json:"hume_config_id"`
+	Status       string     `json:"status"`
+	Voice        *db.Voice  `json:"voice,omitempty"`
+	VoiceID      *uuid.UUID `json:"voice_id,omitempty"`
+	Warnings     []string   `json:"warnings,omitempty"`
+	Error        string     `json:"error,omitempty"`
+}
+
+// linkedHumeConfigs maps each Hume config ID in use to its voice
+func (s *Server) linkedHumeConfigs(ctx context.Context) (map[string]uuid.UUID, error) {
+	voices, err := s.store.ListVoices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	linked := make(map[string]uuid.UUID, len(voices))
+	for _, voice := range voices {
+		if voice.HumeConfigID != "" {
+			linked[voice.HumeConfigID] = voice.ID
+		}
+	}
+	return linked, nil
+}
+
+// listHumeConfigsHandler pages through the account's Hume configs
+// (?page=&page_size=, pages from 0), marking those already linked to a voice
+func (s *Server) listHumeConfigsHandler(w http.ResponseWriter, r *http.Request) {
+	page := 0
+	if p, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && p >= 0 {
+		page = p
+	}
+	pageSize := 50
+	if ps, err := strconv.Atoi(r.URL.Query().Get("page_size")); err == nil && ps > 0 && ps <= 100 {
+		pageSize = ps
+	}
+
+	ctx := r.Context()
+	configs, err := s.hume.ListConfigs(ctx, page, pageSize)
+	if err != nil {
+		log.Printf("Error listing Hume configs: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to list Hume configs: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	linked, err := s.linkedHumeConfigs(ctx)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list voices: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	resp := HumeConfigsResponse{
+		PageNumber: configs.PageNumber,
+		PageSize:   configs.PageSize,
+		TotalPages: configs.TotalPages,
+		Configs:    make([]HumeConfigSummary, 0, len(configs.Configs)),
+	}
+	for _, config := range configs.Configs {
+		summary := HumeConfigSummary{
+			ID:         config.ID,
+			Name:       config.Name,
+			Version:    config.Version,
+			EVIVersion: config.EVIVersion,
+		}
+		if config.Prompt != nil {
+			summary.PromptID = config.Prompt.ID
+		}
+		if config.LanguageModel != nil {
+			summary.LanguageModelProvider = config.LanguageModel.ModelProvider
+			summary.LanguageModelResource = config.LanguageModel.ModelResource
+		}
+		if config.Voice != nil {
+			summary.HumeVoiceProvider = config.Voice.Provider
+			if config.Voice.Name != nil {
+				summary.HumeVoiceName = *config.Voice.Name
+			}
+		}
+		if voiceID, ok := linked[config.ID]; ok {
+			summary.LinkedVoiceID = &voiceID
+		}
+		resp.Configs = append(resp.Configs, summary)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// importHumeConfigsHandler creates a local voice for each selected Hume
+// config that no voice uses yet
+func (s *Server) importHumeConfigsHandler(w http.ResponseWriter, r *http.Request) {
+	var req ImportHumeConfigsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if len(req.ConfigIDs) == 0 {
+		http.Error(w, "config_ids is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.ConfigIDs) > maxConfigImports {
+		http.Error(w, fmt.Sprintf("At most %d configs can be imported at once", maxConfigImports), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	linked, err := s.linkedHumeConfigs(ctx)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list voices: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	results := make([]HumeConfigImport, 0, len(req.ConfigIDs))
+	for _, configID := range req.ConfigIDs {
+		result := HumeConfigImport{HumeConfigID: configID}
+		if voiceID, ok := linked[configID]; ok {
+			result.Status = "linked"
+			result.VoiceID = &voiceID
+			results = append(results, result)
+			continue
+		}
+
+		voice, warnings, err := s.importHumeConfig(ctx, configID, requestUserID(r))
+		if err != nil {
+			log.Printf("Error importing Hume config %s: %v", configID, err)
+			result.Status = "error"
+			result.Error = err.Error()
+		} else {
+			result.Status = "imported"
+			result.Voice = voice
+			result.VoiceID = &voice.ID
+			result.Warnings = warnings
+			linked[configID] = voice.ID
+		}
+		results = append(results, result)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+}
+
+// importHumeConfig creates a voice from the latest version of a Hume
+// config, copying its prompt text, language model, voice, event messages
+// and timeouts. It returns a warning for each setting it had to replace.
+func (s *Server) importHumeConfig(ctx context.Context, configID string, editedBy *uuid.UUID) (*db.Voice, []string, error) {
+	config, err := s.hume.GetConfig(ctx, configID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch config: %w", err)
+	}
+	if config.Prompt == nil || config.Prompt.ID == "" {
+		return nil, nil, fmt.Errorf("config has no prompt")
+	}
+
+	promptText := config.Prompt.Text
+	if promptText == "" {
+		prompt, err := s.hume.GetPromptVersion(ctx, config.Prompt.ID, config.Prompt.Version)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to fetch prompt: %w", err)
+		}
+		promptText = prompt.Text
+	}
+	if promptText == "" {
+		return nil, nil, fmt.Errorf("config prompt is empty")
+	}
+
+	voice := &db.Voice{
+		Name:                  config.Name,
+		Description:           fmt.Sprintf("Imported from Hume config %s", config.Name),
+		Prompt:                promptText,
+		HumeConfigID:          config.ID,
+		HumeConfigVersion:     &config.Version,
+		HumePromptID:          config.Prompt.ID,
+		HumePromptVersion:     &config.Prompt.Version,
+		EVIVersion:            config.EVIVersion,
+		LanguageModelProvider: hume.DefaultModelProvider,
+		LanguageModelResource: hume.DefaultModelResource,
+		Temperature:           1.0,
+	}
+	if voice.EVIVersion == "" {
+		voice.EVIVersion = "3"
+	}
+	var warnings []string
+	// Keep only settings the voice editor would accept, so the imported
+	// voice can be edited and re-synced as is
+	if lm := config.LanguageModel; lm != nil && lm.ModelProvider != "" {
+		if err := hume.ValidateLanguageModel(lm.ModelProvider, lm.ModelResource); err != nil {
+			warnings = append(warnings, fmt.Sprintf("%v; using %s %s instead", err, hume.DefaultModelProvider, hume.DefaultModelResource))
+		} else {
+			voice.LanguageModelProvider = lm.ModelProvider
+			voice.LanguageModelResource = lm.ModelResource
+		}
+		// Hume leaves the temperature out (0) when it was never set
+		if lm.Temperature > 0 {
+			voice.Temperature = lm.Temperature
+		}
+	}
+	if v := config.Voice; v != nil {
+		voice.HumeVoiceProvider = v.Provider
+		if v.ID != nil {
+			voice.HumeVoiceID = *v.ID
+		}
+		if v.Name != nil {
+			voice.HumeVoiceName = *v.Name
+		}
+	}
+	if em := config.EventMessages; em != nil {
+		if em.OnNewChat.Enabled {
+			voice.GreetingMessage = em.OnNewChat.Text
+		}
+		if em.OnInactivityTimeout.Enabled {
+			voice.InactivityMessage = em.OnInactivityTimeout.Text
+		}
+		if em.OnMaxDurationTimeout.Enabled {
+			voice.MaxDurationMessage = em.OnMaxDurationTimeout.Text
+		}
+	}
+	if t := config.Timeouts; t != nil {
+		if t.Inactivity.Enabled {
+			voice.InactivityTimeoutSecs = t.Inactivity.DurationSecs
+		}
+		if t.MaxDuration.Enabled {
+			voice.MaxDurationSecs = t.MaxDuration.DurationSecs
+		}
+		if err := hume.ValidateTimeouts(voice.InactivityTimeoutSecs, voice.MaxDurationSecs); err != nil {
+			warnings = append(warnings, fmt.Sprintf("%v; using Hume's default timeouts instead", err))
+			voice.InactivityTimeoutSecs = 0
+			voice.MaxDurationSecs = 0
+		}
+	}
+
+	created, err := s.store.CreateVoice(ctx, voice)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to save voice: %w", err)
+	}
+	change := db.VoiceChange{
+		Action:   db.VoiceActionCreate,
+		Note:     fmt.Sprintf("imported from Hume config %s version %d", config.ID, config.Version),
+		EditedBy: editedBy,
+	}
+	s.recordVoiceRevision(ctx, created, change)
+
+	for _, warning := range warnings {
+		log.Printf("Hume config %s: %s", config.ID, warning)
+	}
+	log.Printf("Imported Hume config %s (%s) as voice %s", config.ID, config.Name, created.ID)
+	return created, warnings, nil
+}
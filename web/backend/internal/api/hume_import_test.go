@@ -0,0 +1,164 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hume-evi/web/internal/db"
+	"github.com/hume-evi/web/internal/hume"
+	"github.com/hume-evi/web/internal/hume/fakehume"
+)
+
+// seedImportConfig stores a config in the fake with a prompt and the given
+// language model and timeouts
+func seedImportConfig(t *testing.T, s *Server, fake *fakehume.Server, id, name string, lm *hume.LanguageModel, timeouts *hume.Timeouts) {
+	t.Helper()
+	prompt, err := s.hume.CreatePrompt(context.Background(), name+" prompt", "You are "+name+".")
+	if err != nil {
+		t.Fatalf("CreatePrompt: %v", err)
+	}
+	voiceName := "Ito"
+	fake.SeedConfigVersion(id, hume.ConfigRequest{
+		Name:          name,
+		EVIVersion:    "3",
+		Prompt:        &hume.PromptReference{ID: prompt.ID, Version: prompt.Version},
+		Voice:         &hume.VoiceReference{Provider: hume.VoiceProviderHume, Name: &voiceName},
+		LanguageModel: lm,
+		Timeouts:      timeouts,
+		EventMessages: &hume.EventMessages{OnNewChat: hume.EventMessage{Enabled: true, Text: "Hi, I'm " + name}},
+	})
+}
+
+func importConfigs(t *testing.T, s *Server, ids ...string) []HumeConfigImport {
+	t.Helper()
+	body, _ := json.Marshal(ImportHumeConfigsRequest{ConfigIDs: ids})
+	w := httptest.NewRecorder()
+	s.importHumeConfigsHandler(w, httptest.NewRequest(http.MethodPost, "/api/admin/hume/configs/import", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("import = %d %s", w.Code, w.Body)
+	}
+	var resp struct {
+		Results []HumeConfigImport `json:"results"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decoding import response: %v", err)
+	}
+	return resp.Results
+}
+
+func listConfigs(t *testing.T, s *Server) HumeConfigsResponse {
+	t.Helper()
+	w := httptest.NewRecorder()
+	s.listHumeConfigsHandler(w, httptest.NewRequest(http.MethodGet, "/api/admin/hume/configs?page_size=10", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("list = %d %s", w.Code, w.Body)
+	}
+	var resp HumeConfigsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decoding list response: %v", err)
+	}
+	return resp
+}
+
+func TestImportHumeConfig(t *testing.T) {
+	s, fake := newSyncTestServer(t)
+	seedImportConfig(t, s, fake, "config-aria", "Aria",
+		&hume.LanguageModel{ModelProvider: hume.DefaultModelProvider, ModelResource: hume.DefaultModelResource, Temperature: 0.4},
+		&hume.Timeouts{Inactivity: hume.Timeout{Enabled: true, DurationSecs: 120}})
+
+	results := importConfigs(t, s, "config-aria")
+	if len(results) != 1 || results[0].Status != "imported" || len(results[0].Warnings) != 0 {
+		t.Fatalf("import results %+v, want one clean import", results)
+	}
+
+	voice, err := s.store.GetVoice(context.Background(), *results[0].VoiceID)
+	if err != nil {
+		t.Fatalf("GetVoice: %v", err)
+	}
+	if voice.Name != "Aria" || voice.Prompt != "You are Aria." || voice.HumeConfigID != "config-aria" || *voice.HumeConfigVersion != 0 {
+		t.Fatalf("imported voice %+v", voice)
+	}
+	if voice.Temperature != 0.4 || voice.HumeVoiceName != "Ito" || voice.GreetingMessage != "Hi, I'm Aria" || voice.InactivityTimeoutSecs != 120 {
+		t.Fatalf("imported voice settings %+v", voice)
+	}
+	if revisions := recordedRevisions(t, s, voice.ID); len(revisions) != 1 || revisions[0].Action != db.VoiceActionCreate {
+		t.Fatalf("recorded revisions %+v, want one create", revisions)
+	}
+}
+
+func TestImportHumeConfigWarnsAboutReplacedSettings(t *testing.T) {
+	s, fake := newSyncTestServer(t)
+	seedImportConfig(t, s, fake, "config-old", "Old",
+		&hume.LanguageModel{ModelProvider: "ANTHROPIC", ModelResource: "claude-retired-1"},
+		&hume.Timeouts{Inactivity: hume.Timeout{Enabled: true, DurationSecs: 1}})
+
+	results := importConfigs(t, s, "config-old")
+	if len(results) != 1 || results[0].Status != "imported" {
+		t.Fatalf("import results %+v, want an import", results)
+	}
+	warnings := results[0].Warnings
+	if len(warnings) != 2 || !strings.Contains(warnings[0], "claude-retired-1") || !strings.Contains(warnings[1], "timeouts") {
+		t.Fatalf("warnings %q, want the model and the timeouts", warnings)
+	}
+
+	voice := results[0].Voice
+	if voice.LanguageModelProvider != hume.DefaultModelProvider || voice.LanguageModelResource != hume.DefaultModelResource {
+		t.Fatalf("imported with model %s %s, want the default", voice.LanguageModelProvider, voice.LanguageModelResource)
+	}
+	if voice.Temperature != 1 || voice.InactivityTimeoutSecs != 0 {
+		t.Fatalf("imported with temperature %v and inactivity timeout %d, want the defaults", voice.Temperature, voice.InactivityTimeoutSecs)
+	}
+}
+
+func TestImportHumeConfigLinked(t *testing.T) {
+	s, fake := newSyncTestServer(t)
+	seedImportConfig(t, s, fake, "config-aria", "Aria", nil, nil)
+	seedImportConfig(t, s, fake, "config-bea", "Bea", nil, nil)
+
+	first := importConfigs(t, s, "config-aria")
+	voiceID := *first[0].VoiceID
+
+	// The listing marks the imported config; importing it again links to
+	// the existing voice instead of creating another, even when repeated
+	// within one request
+	list := listConfigs(t, s)
+	if len(list.Configs) != 2 {
+		t.Fatalf("listed %+v, want 2 configs", list.Configs)
+	}
+	for _, config := range list.Configs {
+		switch config.ID {
+		case "config-aria":
+			if config.LinkedVoiceID == nil || *config.LinkedVoiceID != voiceID || config.HumeVoiceName != "Ito" || config.PromptID == "" {
+				t.Fatalf("listed Aria as %+v", config)
+			}
+		case "config-bea":
+			if config.LinkedVoiceID != nil {
+				t.Fatalf("listed Bea as linked to %s", config.LinkedVoiceID)
+			}
+		}
+	}
+
+	results := importConfigs(t, s, "config-aria", "config-bea", "config-bea", "config-missing")
+	if len(results) != 4 {
+		t.Fatalf("import results %+v, want 4", results)
+	}
+	if results[0].Status != "linked" || *results[0].VoiceID != voiceID || results[0].Voice != nil {
+		t.Fatalf("re-import of Aria = %+v, want linked to %s", results[0], voiceID)
+	}
+	if results[1].Status != "imported" || results[2].Status != "linked" || *results[2].VoiceID != *results[1].VoiceID {
+		t.Fatalf("repeated Bea imports = %+v, %+v; want imported then linked", results[1], results[2])
+	}
+	if results[3].Status != "error" || results[3].Error == "" {
+		t.Fatalf("import of a missing config = %+v, want an error", results[3])
+	}
+
+	voices, err := s.store.ListVoices(context.Background())
+	if err != nil || len(voices) != 2 {
+		t.Fatalf("store holds %d voices (%v), want 2", len(voices), err)
+	}
+}
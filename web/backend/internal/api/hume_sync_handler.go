@@ -0,0 +1,99 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"github.com/hume-evi/web/internal/db"
+)
+
+// syncAllVoicesHandler starts a background sync of every voice to Hume and
+// returns the run; poll /admin/hume/sync-runs/{id} for progress
+func (s *Server) syncAllVoicesHandler(w http.ResponseWriter, r *http.Request) {
+	run, err := s.humeSync.StartSync(r.Context(), requestUserID(r))
+	if errors.Is(err, db.ErrHumeSyncRunning) {
+		http.Error(w, "A voice sync is already running", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Error starting voice sync: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to start voice sync: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(run)
+}
+
+// latestVoiceSyncHandler returns the most recent bulk sync
+func (s *Server) latestVoiceSyncHandler(w http.ResponseWriter, r *http.Request) {
+	s.writeLatestHumeSyncRun(w, r, db.HumeSyncKindSync)
+}
+
+// startReconcileHandler starts a drift check between local voices and Hume.
+// Pass ?heal=true to re-sync drifted voices and queue orphaned configs for
+// cleanup.
+func (s *Server) startReconcileHandler(w http.ResponseWriter, r *http.Request) {
+	heal := r.URL.Query().Get("heal") == "true"
+
+	run, err := s.humeSync.StartReconcile(r.Context(), heal, requestUserID(r))
+	if errors.Is(err, db.ErrHumeSyncRunning) {
+		http.Error(w, "A reconciliation is already running", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Error starting Hume reconciliation: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to start reconciliation: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(run)
+}
+
+// latestReconcileHandler returns the most recent drift report, whether it
+// was scheduled or started by an admin
+func (s *Server) latestReconcileHandler(w http.ResponseWriter, r *http.Request) {
+	s.writeLatestHumeSyncRun(w, r, db.HumeSyncKindReconcile)
+}
+
+func (s *Server) writeLatestHumeSyncRun(w http.ResponseWriter, r *http.Request, kind string) {
+	run, err := s.store.GetLatestHumeSyncRun(r.Context(), kind)
+	if err != nil {
+		http.Error(w, "Failed to get latest run", http.StatusInternalServerError)
+		return
+	}
+	if run == nil {
+		http.Error(w, "No runs yet", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(run)
+}
+
+// getHumeSyncRunHandler reports a sync or reconciliation's progress and results
+func (s *Server) getHumeSyncRunHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid run ID", http.StatusBadRequest)
+		return
+	}
+
+	run, err := s.store.GetHumeSyncRun(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Run not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(run)
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 
 	"github.com/google/uuid"
@@ -11,6 +12,26 @@ import (
 type AddMessageRequest struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
+	// Emotions are optional Hume prosody scores for the message
+	Emotions map[string]float64 `json:"emotions,omitempty"`
+}
+
+// maxEmotionScores is generous headroom over the 48 expressions Hume scores
+const maxEmotionScores = 100
+
+func validateEmotions(emotions map[string]float64) error {
+	if len(emotions) > maxEmotionScores {
+		return fmt.Errorf("at most %d emotion scores are allowed", maxEmotionScores)
+	}
+	for name, score := range emotions {
+		if name == "" {
+			return fmt.Errorf("emotion names can't be empty")
+		}
+		if !(score >= 0 && score <= 1) {
+			return fmt.Errorf("emotion score for %s must be between 0 and 1", name)
+		}
+	}
+	return nil
 }
 
 func (s *Server) addMessageHandler(w http.ResponseWriter, r *http.Request) {
@@ -29,7 +50,7 @@ func (s *Server) addMessageHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verify conversation belongs to user
-	_, err = s.db.GetConversation(r.Context(), convID, userID)
+	_, err = s.store.GetConversation(r.Context(), convID, userID)
 	if err != nil {
 		http.Error(w, "Conversation not found", http.StatusNotFound)
 		return
@@ -45,8 +66,12 @@ func (s *Server) addMessageHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Role and content required", http.StatusBadRequest)
 		return
 	}
+	if err := validateEmotions(req.Emotions); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	msg, err := s.db.AddMessage(r.Context(), convID, req.Role, req.Content)
+	msg, err := s.store.AddMessage(r.Context(), convID, req.Role, req.Content, req.Emotions)
 	if err != nil {
 		http.Error(w, "Failed to save message", http.StatusInternalServerError)
 		return
@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"errors"
 	"net/http"
 )
 
@@ -11,45 +12,61 @@ const userIDKey contextKey = "user_id"
 const usernameKey contextKey = "username"
 const isAdminKey contextKey = "is_admin"
 
+var errInvalidToken = errors.New("invalid token")
+
+// tokenClaims holds the user identity carried in the auth_token JWT
+type tokenClaims struct {
+	UserID   string
+	Username string
+	IsAdmin  bool
+}
+
+// authenticateRequest validates the auth_token cookie and extracts the user claims
+func (s *Server) authenticateRequest(r *http.Request) (*tokenClaims, error) {
+	// Get token from cookie
+	cookie, err := r.Cookie("auth_token")
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate token
+	claims, err := s.auth.ValidateJWT(cookie.Value, s.config.JWTSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	// Extract user info from claims
+	userID, ok := claims["user_id"].(string)
+	if !ok {
+		return nil, errInvalidToken
+	}
+
+	username, ok := claims["username"].(string)
+	if !ok {
+		return nil, errInvalidToken
+	}
+
+	// Extract is_admin (default to false if not present for backward compatibility)
+	isAdmin := false
+	if adminVal, ok := claims["is_admin"].(bool); ok {
+		isAdmin = adminVal
+	}
+
+	return &tokenClaims{UserID: userID, Username: username, IsAdmin: isAdmin}, nil
+}
+
 func (s *Server) authMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Get token from cookie
-		cookie, err := r.Cookie("auth_token")
+		claims, err := s.authenticateRequest(r)
 		if err != nil {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
 
-		// Validate token
-		claims, err := s.auth.ValidateJWT(cookie.Value, s.config.JWTSecret)
-		if err != nil {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
-		}
-
-		// Extract user info from claims
-		userID, ok := claims["user_id"].(string)
-		if !ok {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
-			return
-		}
-
-		username, ok := claims["username"].(string)
-		if !ok {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
-			return
-		}
-
-		// Extract is_admin (default to false if not present for backward compatibility)
-		isAdmin := false
-		if adminVal, ok := claims["is_admin"].(bool); ok {
-			isAdmin = adminVal
-		}
-
 		// Add to context
-		ctx := context.WithValue(r.Context(), userIDKey, userID)
-		ctx = context.WithValue(ctx, usernameKey, username)
-		ctx = context.WithValue(ctx, isAdminKey, isAdmin)
+		ctx := context.WithValue(r.Context(), userIDKey, claims.UserID)
+		ctx = context.WithValue(ctx, usernameKey, claims.Username)
+		ctx = context.WithValue(ctx, isAdminKey, claims.IsAdmin)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
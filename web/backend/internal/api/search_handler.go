@@ -0,0 +1,80 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/hume-evi/web/internal/db"
+)
+
+// maxSearchQueryLength bounds the query text; transcripts are searched for
+// a few words, not pasted passages
+const maxSearchQueryLength = 200
+
+// SearchResult is a search hit with a link to its conversation's messages
+type SearchResult struct {
+	db.SearchHit
+	MessagesURL string `json:"messages_url"`
+}
+
+type SearchResponse struct {
+	Query   string         `json:"query"`
+	Results []SearchResult `json:"results"`
+}
+
+// searchHandler searches the user's own conversation transcripts
+// (?q=&limit=). Each hit links to the page of its conversation's messages
+// that ends with it.
+func (s *Server) searchHandler(w http.ResponseWriter, r *http.Request) {
+	userIDStr := getUserID(r)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		http.Error(w, "q is required", http.StatusBadRequest)
+		return
+	}
+	if len(query) > maxSearchQueryLength {
+		http.Error(w, fmt.Sprintf("q must be at most %d characters", maxSearchQueryLength), http.StatusBadRequest)
+		return
+	}
+
+	limit := 20
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 50 {
+			limit = l
+		}
+	}
+
+	hits, err := s.store.SearchMessages(r.Context(), userID, query, limit)
+	if err != nil {
+		log.Printf("Error searching messages: %v", err)
+		http.Error(w, "Failed to search messages", http.StatusInternalServerError)
+		return
+	}
+
+	resp := SearchResponse{Query: query, Results: make([]SearchResult, 0, len(hits))}
+	for _, hit := range hits {
+		// Paging back from a microsecond after the hit keeps the hit as the
+		// last message, with the turns that led up to it
+		through := db.Cursor{Time: hit.Timestamp.Add(time.Microsecond)}
+		resp.Results = append(resp.Results, SearchResult{
+			SearchHit:   hit,
+			MessagesURL: fmt.Sprintf("/api/conversations/%s/messages?before=%s", hit.ConversationID, through.Encode()),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -5,26 +5,58 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 
+	"github.com/hume-evi/web/internal/analysis"
 	"github.com/hume-evi/web/internal/auth"
 	"github.com/hume-evi/web/internal/config"
 	"github.com/hume-evi/web/internal/db"
+	"github.com/hume-evi/web/internal/graph"
+	"github.com/hume-evi/web/internal/hume"
+	"github.com/hume-evi/web/internal/jobs"
+	"github.com/hume-evi/web/internal/websocket"
 )
 
 type Server struct {
 	config *config.Config
-	db     *db.DB
-	auth   *auth.Auth
-	router *mux.Router
+	// store is the database every handler reads and writes through
+	store     db.Store
+	auth      *auth.Auth
+	hub       *websocket.Hub
+	hume      hume.API
+	analyzer  analysis.Analyzer
+	extractor graph.Extractor
+	// graph is nil when Memgraph isn't configured or was unreachable at startup
+	graph *graph.Client
+	// graphJobs is nil whenever graph is
+	graphJobs *jobs.GraphExtractionQueue
+	humeSync  *jobs.HumeSync
+	router    *mux.Router
 }
 
-func NewServer(cfg *config.Config, database *db.DB) *Server {
+func NewServer(cfg *config.Config, database db.Store, hub *websocket.Hub, analyzer analysis.Analyzer, graphClient *graph.Client) *Server {
 	s := &Server{
-		config: cfg,
-		db:     database,
-		auth:   &auth.Auth{},
-		router: mux.NewRouter(),
+		config:    cfg,
+		store:     database,
+		auth:      &auth.Auth{},
+		hub:       hub,
+		hume:      hume.NewClient(cfg.HumeAPIURL, cfg.HumeAPIKey),
+		analyzer:  analyzer,
+		extractor: graph.NewExtractor(cfg.LLMBaseURL, cfg.LLMAPIKey, cfg.LLMModel),
+		graph:     graphClient,
+		router:    mux.NewRouter(),
+	}
+
+	s.humeSync = jobs.NewHumeSync(database, s.hume, s.syncVoiceToHume, cfg.HumeReconcileInterval, cfg.HumeReconcileHeal)
+
+	if graphClient != nil {
+		s.graphJobs = jobs.NewGraphExtractionQueue(database, graphClient, s.extractor)
+		hub.OnConversationEnded(func(ctx context.Context, conversationID, userID uuid.UUID) {
+			if err := s.graphJobs.Enqueue(ctx, conversationID, userID); err != nil {
+				log.Printf("Failed to schedule graph extraction for %s: %v", conversationID, err)
+			}
+		})
 	}
 
 	s.setupRoutes()
@@ -35,6 +67,9 @@ func (s *Server) setupRoutes() {
 	// CORS middleware
 	s.router.Use(corsMiddleware)
 
+	// EVI proxy (authenticates via the auth_token cookie itself)
+	s.router.HandleFunc("/ws", s.websocketHandler).Methods("GET")
+
 	// Public routes
 	api := s.router.PathPrefix("/api").Subrouter()
 	api.HandleFunc("/auth/login", s.loginHandler).Methods("POST", "OPTIONS")
@@ -54,7 +89,15 @@ func (s *Server) setupRoutes() {
 	protected.HandleFunc("/conversations/{id}", s.deleteConversationHandler).Methods("DELETE")
 	protected.HandleFunc("/conversations/{id}/messages", s.getMessagesHandler).Methods("GET")
 	protected.HandleFunc("/conversations/{id}/messages", s.addMessageHandler).Methods("POST")
+	protected.HandleFunc("/conversations/{id}/context-injections", s.getContextInjectionsHandler).Methods("GET")
+
+	// Transcript search
+	protected.HandleFunc("/search", s.searchHandler).Methods("GET")
 	
+	// Live EVI proxy sessions
+	protected.HandleFunc("/sessions", s.listSessionsHandler).Methods("GET")
+	protected.HandleFunc("/sessions/{id}", s.kickSessionHandler).Methods("DELETE")
+
 	// AI context analysis
 	protected.HandleFunc("/analyze-conversation", s.analyzeConversationHandler).Methods("POST")
 	
@@ -79,17 +122,36 @@ func (s *Server) setupRoutes() {
 	
 	// Voice management (admin only)
 	admin.HandleFunc("/voices", s.createVoiceHandler).Methods("POST")
+	admin.HandleFunc("/voices/language-models", s.listLanguageModelsHandler).Methods("GET")
+	admin.HandleFunc("/voices/library", s.listVoiceLibraryHandler).Methods("GET")
 	admin.HandleFunc("/voices/sync", s.syncAllVoicesHandler).Methods("POST")
+	admin.HandleFunc("/voices/sync", s.latestVoiceSyncHandler).Methods("GET")
+	admin.HandleFunc("/voices/preview", s.previewVoiceHandler).Methods("POST")
+	admin.HandleFunc("/voices/previews/{id}/audio", s.voicePreviewAudioHandler).Methods("GET")
+	admin.HandleFunc("/voices/previews/{id}/promote", s.promoteVoicePreviewHandler).Methods("POST")
 	admin.HandleFunc("/voices/{id}", s.updateVoiceHandler).Methods("PATCH")
 	admin.HandleFunc("/voices/{id}", s.deleteVoiceHandler).Methods("DELETE")
 	admin.HandleFunc("/voices/{id}/sync", s.syncVoiceHandler).Methods("POST")
+	admin.HandleFunc("/voices/{id}/revisions", s.listVoiceRevisionsHandler).Methods("GET")
+	admin.HandleFunc("/voices/{id}/revisions/diff", s.diffVoiceRevisionsHandler).Methods("GET")
+	admin.HandleFunc("/voices/{id}/revisions/{revision}/rollback", s.rollbackVoiceHandler).Methods("POST")
+	admin.HandleFunc("/hume/replaced-configs", s.listReplacedHumeConfigsHandler).Methods("GET")
+	admin.HandleFunc("/hume/replaced-configs/{id}", s.cleanupReplacedHumeConfigHandler).Methods("DELETE")
+	admin.HandleFunc("/hume/configs", s.listHumeConfigsHandler).Methods("GET")
+	admin.HandleFunc("/hume/configs/import", s.importHumeConfigsHandler).Methods("POST")
+	admin.HandleFunc("/hume/reconcile", s.startReconcileHandler).Methods("POST")
+	admin.HandleFunc("/hume/reconcile", s.latestReconcileHandler).Methods("GET")
+	admin.HandleFunc("/hume/sync-runs/{id}", s.getHumeSyncRunHandler).Methods("GET")
+
+	// Background graph extraction queue (admin only)
+	admin.HandleFunc("/graph/jobs", s.listGraphJobsHandler).Methods("GET")
 }
 
 
 func (s *Server) Start(ctx context.Context) error {
-	// Run migrations
-	if err := s.db.RunMigrations(ctx); err != nil {
-		log.Printf("Warning: Migration error: %v", err)
+	go s.humeSync.Run(ctx)
+	if s.graphJobs != nil {
+		go s.graphJobs.Run(ctx)
 	}
 
 	server := &http.Server{
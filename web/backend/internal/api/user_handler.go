@@ -22,7 +22,7 @@ type UpdateUserRequest struct {
 }
 
 func (s *Server) listUsersHandler(w http.ResponseWriter, r *http.Request) {
-	users, err := s.db.ListUsers(r.Context())
+	users, err := s.store.ListUsers(r.Context())
 	if err != nil {
 		http.Error(w, "Failed to list users", http.StatusInternalServerError)
 		return
@@ -57,7 +57,7 @@ func (s *Server) createUserHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create user
-	user, err := s.db.CreateUser(r.Context(), req.Username, passwordHash, req.Name, req.IsAdmin)
+	user, err := s.store.CreateUser(r.Context(), req.Username, passwordHash, req.Name, req.IsAdmin)
 	if err != nil {
 		http.Error(w, "Failed to create user", http.StatusInternalServerError)
 		return
@@ -86,7 +86,7 @@ func (s *Server) updateUserHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verify user exists
-	_, err = s.db.GetUserByID(r.Context(), id)
+	_, err = s.store.GetUserByID(r.Context(), id)
 	if err != nil {
 		http.Error(w, "User not found", http.StatusNotFound)
 		return
@@ -104,14 +104,14 @@ func (s *Server) updateUserHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Update user
-	err = s.db.UpdateUser(r.Context(), id, passwordHash, req.Name, req.IsAdmin)
+	err = s.store.UpdateUser(r.Context(), id, passwordHash, req.Name, req.IsAdmin)
 	if err != nil {
 		http.Error(w, "Failed to update user", http.StatusInternalServerError)
 		return
 	}
 
 	// Get updated user
-	updatedUser, err := s.db.GetUserByID(r.Context(), id)
+	updatedUser, err := s.store.GetUserByID(r.Context(), id)
 	if err != nil {
 		http.Error(w, "Failed to get updated user", http.StatusInternalServerError)
 		return
@@ -139,7 +139,7 @@ func (s *Server) deleteUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.db.DeleteUser(r.Context(), id); err != nil {
+	if err := s.store.DeleteUser(r.Context(), id); err != nil {
 		http.Error(w, "Failed to delete user", http.StatusInternalServerError)
 		return
 	}
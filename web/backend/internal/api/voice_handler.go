@@ -1,129 +1,72 @@
 package api
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
-	"strings"
+	"reflect"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 
 	"github.com/hume-evi/web/internal/db"
+	"github.com/hume-evi/web/internal/hume"
 )
 
 type CreateVoiceRequest struct {
-	Name             string  `json:"name"`
-	Description      string  `json:"description"`
-	Prompt           string  `json:"prompt"`
-	VoiceDescription string  `json:"voice_description"`
-	EVIVersion       string  `json:"evi_version"`
-	Temperature      float64 `json:"temperature"`
+	Name                  string  `json:"name"`
+	Description           string  `json:"description"`
+	Prompt                string  `json:"prompt"`
+	VoiceDescription      string  `json:"voice_description"`
+	// Optional voice to speak with instead of designing one from
+	// VoiceDescription: a saved CUSTOM_VOICE by ID, or a HUME_AI library
+	// voice by ID or name
+	HumeVoiceProvider     string  `json:"hume_voice_provider"`
+	HumeVoiceID           string  `json:"hume_voice_id"`
+	HumeVoiceName         string  `json:"hume_voice_name"`
+	EVIVersion            string  `json:"evi_version"`
+	LanguageModelProvider string  `json:"language_model_provider"`
+	LanguageModelResource string  `json:"language_model_resource"`
+	Temperature           float64 `json:"temperature"`
+	// Spoken when a chat starts, goes quiet, or hits its time limit
+	GreetingMessage       string  `json:"greeting_message"`
+	InactivityMessage     string  `json:"inactivity_message"`
+	MaxDurationMessage    string  `json:"max_duration_message"`
+	// Timeouts in seconds; 0 keeps Hume's default
+	InactivityTimeoutSecs int     `json:"inactivity_timeout_secs"`
+	MaxDurationSecs       int     `json:"max_duration_secs"`
 }
 
 type UpdateVoiceRequest struct {
-	Name             string  `json:"name"`
-	Description      string  `json:"description"`
-	Prompt           string  `json:"prompt"`
-	VoiceDescription string  `json:"voice_description"`
-	EVIVersion       string  `json:"evi_version"`
-	Temperature      float64 `json:"temperature"`
-}
-
-type HumeTTSRequest struct {
-	Utterances []struct {
-		Text        string `json:"text"`
-		Description string `json:"description"`
-	} `json:"utterances"`
-	NumGenerations int    `json:"num_generations"`
-	Version        string `json:"version"`
-}
-
-type HumeTTSResponse struct {
-	Generations []struct {
-		GenerationID string `json:"generation_id"`
-		Audio        string `json:"audio"`
-	} `json:"generations"`
-}
-
-type HumeVoiceResponse struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
-}
-
-type HumeConfigRequest struct {
-	Name              string `json:"name"`
-	EVIVersion        string `json:"eviVersion"`
-	VersionDescription string `json:"versionDescription,omitempty"`
-	Prompt            *HumePromptReference `json:"prompt,omitempty"`
-	Voice             *HumeVoiceReference `json:"voice"`
-	LanguageModel     *HumeLanguageModel  `json:"languageModel"`
-	EventMessages     *HumeEventMessages  `json:"eventMessages,omitempty"`
-}
-
-type HumePromptReference struct {
-	ID      string `json:"id"`
-	Version int    `json:"version"`
-}
-
-type HumeVoiceReference struct {
-	Provider string  `json:"provider"`
-	Name     *string `json:"name,omitempty"`
-	ID       *string `json:"id,omitempty"`
-}
-
-type HumeLanguageModel struct {
-	ModelProvider string  `json:"modelProvider"`
-	ModelResource string  `json:"modelResource"`
-	Temperature   float64 `json:"temperature"`
-}
-
-type HumeEventMessages struct {
-	OnNewChat struct {
-		Enabled bool   `json:"enabled"`
-		Text    string `json:"text"`
-	} `json:"onNewChat"`
-	OnInactivityTimeout struct {
-		Enabled bool   `json:"enabled"`
-		Text    string `json:"text"`
-	} `json:"onInactivityTimeout"`
-	OnMaxDurationTimeout struct {
-		Enabled bool   `json:"enabled"`
-		Text    string `json:"text"`
-	} `json:"onMaxDurationTimeout"`
-}
-
-type HumeConfigResponse struct {
-	ID      string `json:"id"`
-	Name    string `json:"name"`
-	Version int    `json:"version"`
-}
-
-type HumePromptRequest struct {
-	Name string `json:"name"`
-	Text string `json:"text"`
-}
-
-type HumePromptResponse struct {
-	ID      string `json:"id"`
-	Name    string `json:"name"`
-	Text    string `json:"text"`
-	Version int    `json:"version"`
-}
-
-type HumePromptListResponse struct {
-	ResultsPage struct {
-		Results []HumePromptResponse `json:"results"`
-	} `json:"results_page"`
+	Name                  string  `json:"name"`
+	Description           string  `json:"description"`
+	Prompt                string  `json:"prompt"`
+	VoiceDescription      string  `json:"voice_description"`
+	// Setting any of these replaces the voice's current selection
+	HumeVoiceProvider     string  `json:"hume_voice_provider"`
+	HumeVoiceID           string  `json:"hume_voice_id"`
+	HumeVoiceName         string  `json:"hume_voice_name"`
+	EVIVersion            string  `json:"evi_version"`
+	LanguageModelProvider string  `json:"language_model_provider"`
+	LanguageModelResource string  `json:"language_model_resource"`
+	Temperature           float64 `json:"temperature"`
+	// Event messages and timeouts are left alone when omitted; "" or 0
+	// clears them
+	GreetingMessage       *string `json:"greeting_message"`
+	InactivityMessage     *string `json:"inactivity_message"`
+	MaxDurationMessage    *string `json:"max_duration_message"`
+	InactivityTimeoutSecs *int    `json:"inactivity_timeout_secs"`
+	MaxDurationSecs       *int    `json:"max_duration_secs"`
 }
 
 func (s *Server) listVoicesHandler(w http.ResponseWriter, r *http.Request) {
-	voices, err := s.db.ListVoices(r.Context())
+	voices, err := s.store.ListVoices(r.Context())
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to list voices: %v", err), http.StatusInternalServerError)
 		return
@@ -141,7 +84,7 @@ func (s *Server) getVoiceHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	voice, err := s.db.GetVoice(r.Context(), id)
+	voice, err := s.store.GetVoice(r.Context(), id)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Voice not found: %v", err), http.StatusNotFound)
 		return
@@ -151,6 +94,45 @@ func (s *Server) getVoiceHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(voice)
 }
 
+// listVoiceLibraryHandler lists Hume voices a voice can speak with:
+// ?provider=HUME_AI (the shared library, default) or CUSTOM_VOICE (voices
+// saved by this account), paged with ?page= (from 0) and ?page_size=
+func (s *Server) listVoiceLibraryHandler(w http.ResponseWriter, r *http.Request) {
+	provider := r.URL.Query().Get("provider")
+	if provider == "" {
+		provider = hume.VoiceProviderHume
+	}
+	if provider != hume.VoiceProviderHume && provider != hume.VoiceProviderCustom {
+		http.Error(w, "Invalid provider", http.StatusBadRequest)
+		return
+	}
+
+	page := 0
+	if p, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && p >= 0 {
+		page = p
+	}
+	pageSize := 50
+	if ps, err := strconv.Atoi(r.URL.Query().Get("page_size")); err == nil && ps > 0 && ps <= 100 {
+		pageSize = ps
+	}
+
+	voices, err := s.hume.ListVoices(r.Context(), provider, page, pageSize)
+	if err != nil {
+		log.Printf("Error listing Hume voices: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to list Hume voices: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(voices)
+}
+
+// listLanguageModelsHandler returns the provider/model pairs voices may use
+func (s *Server) listLanguageModelsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hume.LanguageModels())
+}
+
 func (s *Server) createVoiceHandler(w http.ResponseWriter, r *http.Request) {
 	var req CreateVoiceRequest
 	bodyBytes, _ := io.ReadAll(r.Body)
@@ -171,40 +153,57 @@ func (s *Server) createVoiceHandler(w http.ResponseWriter, r *http.Request) {
 	if req.Temperature == 0 {
 		req.Temperature = 1.0
 	}
+	if req.LanguageModelProvider == "" && req.LanguageModelResource == "" {
+		req.LanguageModelProvider = hume.DefaultModelProvider
+		req.LanguageModelResource = hume.DefaultModelResource
+	}
+	if err := hume.ValidateLanguageModel(req.LanguageModelProvider, req.LanguageModelResource); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := hume.ValidateTimeouts(req.InactivityTimeoutSecs, req.MaxDurationSecs); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	provider, err := voiceSelectionProvider(req.HumeVoiceProvider, req.HumeVoiceID, req.HumeVoiceName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.HumeVoiceProvider = provider
 
 	ctx := r.Context()
 
 	// Step 1: Create voice via TTS API if voice_description is provided
 	// Note: TTS voices and EVI voices are separate - we'll store the TTS voice ID
 	// but use a default EVI voice name for the config
-	var humeVoiceID string
-	if req.VoiceDescription != "" {
+	// An explicitly chosen voice takes precedence
+	if req.VoiceDescription != "" && req.HumeVoiceProvider == "" {
 		voiceID, err := s.createHumeVoice(ctx, req.VoiceDescription)
 		if err != nil {
 			log.Printf("Warning: Failed to create Hume TTS voice: %v, continuing with default voice", err)
 			// Continue without custom voice - use default
 		} else {
-			humeVoiceID = voiceID
+			req.HumeVoiceProvider = hume.VoiceProviderCustom
+			req.HumeVoiceID = voiceID
 		}
 	}
 
 	// Step 2: Create prompt via Hume API
-	var promptRef *HumePromptReference
+	var promptRef *hume.PromptReference
 	promptID, promptVersion, err := s.createHumePrompt(ctx, req.Name, req.Prompt)
 	if err != nil {
 		log.Printf("Error creating Hume prompt: %v", err)
 		http.Error(w, fmt.Sprintf("Failed to create Hume prompt: %v", err), http.StatusInternalServerError)
 		return
 	}
-	promptRef = &HumePromptReference{
+	promptRef = &hume.PromptReference{
 		ID:      promptID,
 		Version: promptVersion,
 	}
 
-	// Step 3: Create EVI config
-	// Note: EVI configs use voice names, not TTS voice IDs
-	// We'll use a default voice name for now
-	configID, err := s.createHumeConfig(ctx, req, promptRef)
+	// Step 3: Create EVI config, speaking with the chosen or designed voice
+	config, err := s.createHumeConfig(ctx, req, promptRef)
 	if err != nil {
 		log.Printf("Error creating Hume config: %v", err)
 		http.Error(w, fmt.Sprintf("Failed to create Hume config: %v", err), http.StatusInternalServerError)
@@ -217,20 +216,31 @@ func (s *Server) createVoiceHandler(w http.ResponseWriter, r *http.Request) {
 		Description:           req.Description,
 		Prompt:                req.Prompt,
 		VoiceDescription:      req.VoiceDescription,
-		HumeVoiceID:           humeVoiceID,
-		HumeConfigID:          configID,
+		HumeVoiceID:           req.HumeVoiceID,
+		HumeVoiceProvider:     req.HumeVoiceProvider,
+		HumeVoiceName:         req.HumeVoiceName,
+		HumeConfigID:          config.ID,
+		HumeConfigVersion:     &config.Version,
+		HumePromptID:          promptRef.ID,
+		HumePromptVersion:     &promptRef.Version,
 		EVIVersion:            req.EVIVersion,
-		LanguageModelProvider: "ANTHROPIC",
-		LanguageModelResource: "claude-3-7-sonnet-latest",
+		LanguageModelProvider: req.LanguageModelProvider,
+		LanguageModelResource: req.LanguageModelResource,
 		Temperature:           req.Temperature,
+		GreetingMessage:       req.GreetingMessage,
+		InactivityMessage:     req.InactivityMessage,
+		MaxDurationMessage:    req.MaxDurationMessage,
+		InactivityTimeoutSecs: req.InactivityTimeoutSecs,
+		MaxDurationSecs:       req.MaxDurationSecs,
 	}
 
-	created, err := s.db.CreateVoice(ctx, voice)
+	created, err := s.store.CreateVoice(ctx, voice)
 	if err != nil {
 		log.Printf("Error saving voice to database: %v", err)
 		http.Error(w, fmt.Sprintf("Failed to save voice: %v", err), http.StatusInternalServerError)
 		return
 	}
+	s.recordVoiceRevision(ctx, created, editorChange(r, db.VoiceActionCreate, ""))
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -252,7 +262,7 @@ func (s *Server) updateVoiceHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get existing voice
-	existing, err := s.db.GetVoice(r.Context(), id)
+	existing, err := s.store.GetVoice(r.Context(), id)
 	if err != nil {
 		http.Error(w, "Voice not found", http.StatusNotFound)
 		return
@@ -277,224 +287,139 @@ func (s *Server) updateVoiceHandler(w http.ResponseWriter, r *http.Request) {
 	if req.Temperature != 0 {
 		existing.Temperature = req.Temperature
 	}
-
-	updated, err := s.db.UpdateVoice(r.Context(), id, existing)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to update voice: %v", err), http.StatusInternalServerError)
-		return
+	if req.LanguageModelProvider != "" {
+		existing.LanguageModelProvider = req.LanguageModelProvider
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(updated)
-}
-
-func (s *Server) deleteVoiceHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id, err := uuid.Parse(vars["id"])
-	if err != nil {
-		http.Error(w, "Invalid voice ID", http.StatusBadRequest)
-		return
+	if req.LanguageModelResource != "" {
+		existing.LanguageModelResource = req.LanguageModelResource
 	}
-
-	if err := s.db.DeleteVoice(r.Context(), id); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to delete voice: %v", err), http.StatusInternalServerError)
+	if err := hume.ValidateLanguageModel(existing.LanguageModelProvider, existing.LanguageModelResource); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-
-	w.WriteHeader(http.StatusNoContent)
-}
-
-// createHumeVoice creates a custom voice via Hume TTS API
-func (s *Server) createHumeVoice(ctx context.Context, voiceDescription string) (string, error) {
-	ttsReq := HumeTTSRequest{
-		Utterances: []struct {
-			Text        string `json:"text"`
-			Description string `json:"description"`
-		}{
-			{
-				Text:        "Hello, this is a test of the voice generation system.",
-				Description: voiceDescription,
-			},
-		},
-		NumGenerations: 1,
-		Version:        "1",
+	if req.GreetingMessage != nil {
+		existing.GreetingMessage = *req.GreetingMessage
 	}
-
-	reqBody, err := json.Marshal(ttsReq)
-	if err != nil {
-		return "", err
+	if req.InactivityMessage != nil {
+		existing.InactivityMessage = *req.InactivityMessage
 	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.hume.ai/v0/tts", bytes.NewBuffer(reqBody))
-	if err != nil {
-		return "", err
-	}
-
-	req.Header.Set("X-Hume-Api-Key", s.config.HumeAPIKey)
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
+	if req.MaxDurationMessage != nil {
+		existing.MaxDurationMessage = *req.MaxDurationMessage
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("Hume API error: %s", string(body))
+	if req.InactivityTimeoutSecs != nil {
+		existing.InactivityTimeoutSecs = *req.InactivityTimeoutSecs
 	}
-
-	var ttsResp HumeTTSResponse
-	if err := json.NewDecoder(resp.Body).Decode(&ttsResp); err != nil {
-		return "", err
+	if req.MaxDurationSecs != nil {
+		existing.MaxDurationSecs = *req.MaxDurationSecs
 	}
-
-	if len(ttsResp.Generations) == 0 {
-		return "", fmt.Errorf("no generations returned")
+	if err := hume.ValidateTimeouts(existing.InactivityTimeoutSecs, existing.MaxDurationSecs); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
-
-	generationID := ttsResp.Generations[0].GenerationID
-
-	// Save the voice
-	saveReq := map[string]string{
-		"generation_id": generationID,
-		"name":          fmt.Sprintf("Voice-%d", time.Now().Unix()),
+	// The voice selection is replaced as a whole
+	if req.HumeVoiceProvider != "" || req.HumeVoiceID != "" || req.HumeVoiceName != "" {
+		provider, err := voiceSelectionProvider(req.HumeVoiceProvider, req.HumeVoiceID, req.HumeVoiceName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		existing.HumeVoiceProvider = provider
+		existing.HumeVoiceID = req.HumeVoiceID
+		existing.HumeVoiceName = req.HumeVoiceName
 	}
 
-	saveBody, err := json.Marshal(saveReq)
+	updated, err := s.store.UpdateVoice(r.Context(), id, existing)
 	if err != nil {
-		return "", err
+		http.Error(w, fmt.Sprintf("Failed to update voice: %v", err), http.StatusInternalServerError)
+		return
 	}
+	s.recordVoiceRevision(r.Context(), updated, editorChange(r, db.VoiceActionUpdate, ""))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
 
-	saveHTTPReq, err := http.NewRequestWithContext(ctx, "POST", "https://api.hume.ai/v0/tts/voices", bytes.NewBuffer(saveBody))
+func (s *Server) deleteVoiceHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
 	if err != nil {
-		return "", err
+		http.Error(w, "Invalid voice ID", http.StatusBadRequest)
+		return
 	}
 
-	saveHTTPReq.Header.Set("X-Hume-Api-Key", s.config.HumeAPIKey)
-	saveHTTPReq.Header.Set("Content-Type", "application/json")
-
-	saveResp, err := client.Do(saveHTTPReq)
+	existing, err := s.store.GetVoice(r.Context(), id)
 	if err != nil {
-		return "", err
+		http.Error(w, "Voice not found", http.StatusNotFound)
+		return
 	}
-	defer saveResp.Body.Close()
 
-	if saveResp.StatusCode != http.StatusOK && saveResp.StatusCode != http.StatusCreated {
-		body, _ := io.ReadAll(saveResp.Body)
-		return "", fmt.Errorf("Hume API error saving voice: %s", string(body))
+	if err := s.store.DeleteVoice(r.Context(), id); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete voice: %v", err), http.StatusInternalServerError)
+		return
 	}
 
-	var voiceResp HumeVoiceResponse
-	if err := json.NewDecoder(saveResp.Body).Decode(&voiceResp); err != nil {
-		return "", err
+	// The voice's config is orphaned in Hume now
+	if existing.HumeConfigID != "" {
+		if err := s.store.RecordReplacedHumeConfig(r.Context(), nil, existing.HumeConfigID, "", "voice deleted"); err != nil {
+			log.Printf("Warning: Failed to record orphaned config %s: %v", existing.HumeConfigID, err)
+		}
 	}
 
-	return voiceResp.ID, nil
+	w.WriteHeader(http.StatusNoContent)
 }
 
+// humeDefaultVoiceID is the Hume voice library voice used by EVI configs
+// for voices that have no voice of their own
+const humeDefaultVoiceID = "5add9038-28df-40a6-900c-2f736d008ab3"
+
 // createHumePromptWithName creates a prompt with a specific name (internal helper)
-// If retryOnConflict is true and we get a 409, it will try with a modified name
+// If the name is taken, the existing prompt is reused, with text published as
+// a new version if it differs. If retryOnConflict is true and the existing
+// prompt can't be found, it will try with a modified name
 func (s *Server) createHumePromptWithName(ctx context.Context, name, text string, retryOnConflict bool) (string, int, error) {
-	promptReq := HumePromptRequest{
-		Name: name,
-		Text: text,
-	}
-
-	reqBody, err := json.Marshal(promptReq)
-	if err != nil {
-		return "", 0, fmt.Errorf("failed to marshal prompt request: %w", err)
+	prompt, err := s.hume.CreatePrompt(ctx, name, text)
+	if err == nil {
+		log.Printf("Created Hume prompt: ID=%s, Version=%d", prompt.ID, prompt.Version)
+		return prompt.ID, prompt.Version, nil
 	}
-
-	log.Printf("Creating Hume prompt with request: %s", string(reqBody))
-
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", "https://api.hume.ai/v0/evi/prompts", bytes.NewBuffer(reqBody))
-	if err != nil {
+	if !hume.IsConflict(err) {
 		return "", 0, err
 	}
 
-	httpReq.Header.Set("X-Hume-Api-Key", s.config.HumeAPIKey)
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(httpReq)
-	if err != nil {
-		return "", 0, fmt.Errorf("failed to make request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", 0, fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		// Handle 409 Conflict - config or prompt already exists
-		if resp.StatusCode == http.StatusConflict {
-			// Parse JSON error response to extract message
-			var errorResp map[string]interface{}
-			if err := json.Unmarshal(bodyBytes, &errorResp); err == nil {
-				if message, ok := errorResp["message"].(string); ok {
-					// Error format: "Prevented duplicate key for prompt (ID: <uuid>, NAME: <name>)"
-					if strings.Contains(message, "ID:") {
-						// Extract UUID from error message
-						startIdx := strings.Index(message, "ID: ")
-						if startIdx != -1 {
-							startIdx += 4 // Move past "ID: "
-							endIdx := strings.Index(message[startIdx:], ",")
-							if endIdx == -1 {
-								endIdx = strings.Index(message[startIdx:], ")")
-							}
-							if endIdx != -1 {
-								promptID := strings.TrimSpace(message[startIdx : startIdx+endIdx])
-								log.Printf("Extracted prompt ID from error: %s", promptID)
-								// Get the prompt to find its version
-								promptIDParsed, promptVersion, err := s.getHumePrompt(ctx, promptID)
-								if err == nil {
-									log.Printf("Found existing Hume prompt: ID=%s, Version=%d", promptIDParsed, promptVersion)
-									return promptIDParsed, promptVersion, nil
-								}
-								// If we can't retrieve it (404/unauthorized), we can't use it
-								// Create a new prompt with a modified name to avoid conflict
-								log.Printf("Warning: Could not retrieve prompt %s: %v, creating new prompt with modified name", promptID, err)
-								newName := fmt.Sprintf("%s (%d)", name, time.Now().Unix())
-								return s.createHumePromptWithName(ctx, newName, text, false)
-							}
-						}
-					}
-				}
-			}
-			// Fallback: list prompts and find by name
-			log.Printf("Attempting to find prompt by name: %s", name)
-			promptID, promptVersion, err := s.findHumePromptByName(ctx, name)
-			if err == nil {
-				log.Printf("Found existing Hume prompt by name: ID=%s, Version=%d", promptID, promptVersion)
-				return promptID, promptVersion, nil
+	// The conflict message usually names the existing prompt
+	var statusErr *hume.StatusError
+	if errors.As(err, &statusErr) {
+		if promptID := statusErr.DuplicateID(); promptID != "" {
+			log.Printf("Extracted prompt ID from error: %s", promptID)
+			existing, getErr := s.hume.GetPrompt(ctx, promptID)
+			if getErr == nil {
+				log.Printf("Found existing Hume prompt: ID=%s, Version=%d", existing.ID, existing.Version)
+				return s.versionHumePrompt(ctx, existing, text, fmt.Sprintf("Updated prompt: %s", name))
 			}
-			log.Printf("Error finding prompt by name: %v", err)
-			// If retryOnConflict is true, try creating with a modified name
-			if retryOnConflict {
-				newName := fmt.Sprintf("%s (%d)", name, time.Now().Unix())
-				log.Printf("Retrying with modified name: %s", newName)
-				return s.createHumePromptWithName(ctx, newName, text, false)
-			}
-			return "", 0, fmt.Errorf("prompt already exists but could not retrieve it: %s", string(bodyBytes))
+			// If we can't retrieve it (404/unauthorized), we can't use it
+			// Create a new prompt with a modified name to avoid conflict
+			log.Printf("Warning: Could not retrieve prompt %s: %v, creating new prompt with modified name", promptID, getErr)
+			newName := fmt.Sprintf("%s (%d)", name, time.Now().Unix())
+			return s.createHumePromptWithName(ctx, newName, text, false)
 		}
-		return "", 0, fmt.Errorf("Hume API error (status %d): %s", resp.StatusCode, string(bodyBytes))
 	}
 
-	var promptResp HumePromptResponse
-	if err := json.Unmarshal(bodyBytes, &promptResp); err != nil {
-		return "", 0, fmt.Errorf("failed to decode prompt response: %v, body: %s", err, string(bodyBytes))
+	// Fallback: list prompts and find by name
+	log.Printf("Attempting to find prompt by name: %s", name)
+	existing, findErr := s.findHumePromptByName(ctx, name)
+	if findErr == nil {
+		log.Printf("Found existing Hume prompt by name: ID=%s, Version=%d", existing.ID, existing.Version)
+		return s.versionHumePrompt(ctx, existing, text, fmt.Sprintf("Updated prompt: %s", name))
 	}
+	log.Printf("Error finding prompt by name: %v", findErr)
 
-	if promptResp.ID == "" {
-		return "", 0, fmt.Errorf("prompt ID not found in response: %s", string(bodyBytes))
+	// If retryOnConflict is true, try creating with a modified name
+	if retryOnConflict {
+		newName := fmt.Sprintf("%s (%d)", name, time.Now().Unix())
+		log.Printf("Retrying with modified name: %s", newName)
+		return s.createHumePromptWithName(ctx, newName, text, false)
 	}
-
-	log.Printf("Created Hume prompt: ID=%s, Version=%d", promptResp.ID, promptResp.Version)
-	return promptResp.ID, promptResp.Version, nil
+	return "", 0, fmt.Errorf("prompt already exists but could not retrieve it: %w", err)
 }
 
 // createHumePrompt creates a prompt via Hume API, handling conflicts by creating with modified name
@@ -502,303 +427,269 @@ func (s *Server) createHumePrompt(ctx context.Context, name, text string) (strin
 	return s.createHumePromptWithName(ctx, name, text, true)
 }
 
-// getHumePrompt retrieves a prompt by ID and returns its latest version
-func (s *Server) getHumePrompt(ctx context.Context, promptID string) (string, int, error) {
-	httpReq, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("https://api.hume.ai/v0/evi/prompts/%s", promptID), nil)
-	if err != nil {
-		return "", 0, err
+// versionHumePrompt publishes text as a new version of an existing prompt,
+// or returns the prompt's current version if the text already matches
+func (s *Server) versionHumePrompt(ctx context.Context, current *hume.Prompt, text, versionDescription string) (string, int, error) {
+	if current.Text == text {
+		return current.ID, current.Version, nil
 	}
 
-	httpReq.Header.Set("X-Hume-Api-Key", s.config.HumeAPIKey)
-
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(httpReq)
-	if err != nil {
-		return "", 0, fmt.Errorf("failed to make request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	bodyBytes, err := io.ReadAll(resp.Body)
+	prompt, err := s.hume.CreatePromptVersion(ctx, current.ID, text, versionDescription)
 	if err != nil {
-		return "", 0, fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return "", 0, fmt.Errorf("Hume API error (status %d): %s", resp.StatusCode, string(bodyBytes))
-	}
-
-	var promptResp HumePromptResponse
-	if err := json.Unmarshal(bodyBytes, &promptResp); err != nil {
-		return "", 0, fmt.Errorf("failed to decode prompt response: %v", err)
+		return "", 0, err
 	}
-
-	return promptResp.ID, promptResp.Version, nil
+	log.Printf("Created Hume prompt version: ID=%s, Version=%d", current.ID, prompt.Version)
+	return current.ID, prompt.Version, nil
 }
 
 // findHumePromptByName lists prompts and finds one by name
-func (s *Server) findHumePromptByName(ctx context.Context, name string) (string, int, error) {
-	httpReq, err := http.NewRequestWithContext(ctx, "GET", "https://api.hume.ai/v0/evi/prompts?page_size=100", nil)
+func (s *Server) findHumePromptByName(ctx context.Context, name string) (*hume.Prompt, error) {
+	prompts, err := s.hume.ListPrompts(ctx)
 	if err != nil {
-		return "", 0, err
+		return nil, err
 	}
 
-	httpReq.Header.Set("X-Hume-Api-Key", s.config.HumeAPIKey)
-
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(httpReq)
-	if err != nil {
-		return "", 0, fmt.Errorf("failed to make request: %w", err)
+	for i := range prompts {
+		if prompts[i].Name == name {
+			return &prompts[i], nil
+		}
 	}
-	defer resp.Body.Close()
 
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", 0, fmt.Errorf("failed to read response body: %w", err)
-	}
+	return nil, fmt.Errorf("prompt with name %q not found in list", name)
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return "", 0, fmt.Errorf("Hume API error (status %d): %s", resp.StatusCode, string(bodyBytes))
+// voiceSelectionProvider validates an optional voice choice and returns its
+// provider ("" when nothing was chosen)
+func voiceSelectionProvider(provider, id, name string) (string, error) {
+	if provider == "" {
+		switch {
+		case id != "":
+			provider = hume.VoiceProviderCustom
+		case name != "":
+			provider = hume.VoiceProviderHume
+		default:
+			return "", nil
+		}
 	}
 
-	// Try different response structures
-	var listResp HumePromptListResponse
-	if err := json.Unmarshal(bodyBytes, &listResp); err != nil {
-		// Try alternative structure - maybe it's just an array
-		var prompts []HumePromptResponse
-		if err2 := json.Unmarshal(bodyBytes, &prompts); err2 == nil {
-			log.Printf("Found %d prompts in array format", len(prompts))
-			for _, prompt := range prompts {
-				if prompt.Name == name {
-					return prompt.ID, prompt.Version, nil
-				}
-			}
-		} else {
-			// Try direct results structure
-			var directResp struct {
-				Results []HumePromptResponse `json:"results"`
-			}
-			if err3 := json.Unmarshal(bodyBytes, &directResp); err3 == nil {
-				log.Printf("Found %d prompts in direct results format", len(directResp.Results))
-				for _, prompt := range directResp.Results {
-					if prompt.Name == name {
-						return prompt.ID, prompt.Version, nil
-					}
-				}
-			} else {
-				log.Printf("Failed to parse prompt list response. Body: %s", string(bodyBytes))
-				return "", 0, fmt.Errorf("failed to decode prompt list response: %v, body: %s", err, string(bodyBytes))
-			}
+	switch provider {
+	case hume.VoiceProviderCustom:
+		if id == "" {
+			return "", fmt.Errorf("a %s voice must be chosen by hume_voice_id", provider)
 		}
-	} else {
-		log.Printf("Found %d prompts in ResultsPage format", len(listResp.ResultsPage.Results))
-		// Find prompt by name
-		for _, prompt := range listResp.ResultsPage.Results {
-			if prompt.Name == name {
-				return prompt.ID, prompt.Version, nil
-			}
+	case hume.VoiceProviderHume:
+		if id == "" && name == "" {
+			return "", fmt.Errorf("a %s voice needs hume_voice_id or hume_voice_name", provider)
 		}
+	default:
+		return "", fmt.Errorf("unsupported voice provider %q", provider)
 	}
-
-	return "", 0, fmt.Errorf("prompt with name %q not found in list", name)
+	return provider, nil
 }
 
-// createHumeConfig creates an EVI configuration via Hume API
-func (s *Server) createHumeConfig(ctx context.Context, req CreateVoiceRequest, promptRef *HumePromptReference) (string, error) {
-	configReq := HumeConfigRequest{
-		Name:       req.Name,
-		EVIVersion: req.EVIVersion,
-		Voice: &HumeVoiceReference{
-			Provider: "HUME_AI",
-		},
-		LanguageModel: &HumeLanguageModel{
-			ModelProvider: "ANTHROPIC",
-			ModelResource: "claude-3-7-sonnet-latest",
-			Temperature:  req.Temperature,
-		},
-		EventMessages: &HumeEventMessages{},
-	}
-
-	// Include prompt reference if provided
-	if promptRef != nil {
-		configReq.Prompt = promptRef
-	}
-
-	// EVI configs can use voice IDs or voice names from the Hume voice library
-	// Using a specific voice ID
-	defaultVoiceID := "5add9038-28df-40a6-900c-2f736d008ab3"
-	configReq.Voice.ID = &defaultVoiceID
-	configReq.Voice.Name = nil
-
-	reqBody, err := json.Marshal(configReq)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal config request: %w", err)
-	}
-	
-	log.Printf("Creating Hume config with request: %s", string(reqBody))
-
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", "https://api.hume.ai/v0/evi/configs", bytes.NewBuffer(reqBody))
-	if err != nil {
-		return "", err
+// humeVoiceReference picks the voice an EVI config speaks with: the voice's
+// saved or library voice, else the default library voice
+func humeVoiceReference(provider, id, name string) *hume.VoiceReference {
+	switch {
+	case id != "":
+		if provider == "" {
+			provider = hume.VoiceProviderCustom
+		}
+		return &hume.VoiceReference{Provider: provider, ID: &id}
+	case name != "":
+		return &hume.VoiceReference{Provider: hume.VoiceProviderHume, Name: &name}
 	}
 
-	httpReq.Header.Set("X-Hume-Api-Key", s.config.HumeAPIKey)
-	httpReq.Header.Set("Content-Type", "application/json")
+	defaultVoiceID := humeDefaultVoiceID
+	return &hume.VoiceReference{Provider: hume.VoiceProviderHume, ID: &defaultVoiceID}
+}
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(httpReq)
-	if err != nil {
-		return "", fmt.Errorf("failed to make request: %w", err)
+// humeConfigRequest builds the EVI config body for a voice
+func humeConfigRequest(req CreateVoiceRequest, promptRef *hume.PromptReference) hume.ConfigRequest {
+	return hume.ConfigRequest{
+		Name:          req.Name,
+		EVIVersion:    req.EVIVersion,
+		Prompt:        promptRef,
+		Voice:         humeVoiceReference(req.HumeVoiceProvider, req.HumeVoiceID, req.HumeVoiceName),
+		LanguageModel: &hume.LanguageModel{
+			ModelProvider: req.LanguageModelProvider,
+			ModelResource: req.LanguageModelResource,
+			Temperature:   req.Temperature,
+		},
+		EventMessages: hume.NewEventMessages(req.GreetingMessage, req.InactivityMessage, req.MaxDurationMessage),
+		Timeouts:      hume.NewTimeouts(req.InactivityTimeoutSecs, req.MaxDurationSecs),
 	}
-	defer resp.Body.Close()
+}
 
-	bodyBytes, err := io.ReadAll(resp.Body)
+// createHumeConfig creates an EVI configuration via Hume API
+func (s *Server) createHumeConfig(ctx context.Context, req CreateVoiceRequest, promptRef *hume.PromptReference) (*hume.Config, error) {
+	config, err := s.hume.CreateConfig(ctx, humeConfigRequest(req, promptRef))
 	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
 		// Handle 409 Conflict - config name already exists
-		if resp.StatusCode == http.StatusConflict {
+		if hume.IsConflict(err) {
 			// Try creating with a modified name
 			log.Printf("Config name conflict, retrying with modified name")
 			req.Name = fmt.Sprintf("%s (%d)", req.Name, time.Now().Unix())
 			return s.createHumeConfig(ctx, req, promptRef)
 		}
-		return "", fmt.Errorf("Hume API error (status %d): %s", resp.StatusCode, string(bodyBytes))
-	}
-
-	var configResp HumeConfigResponse
-	if err := json.Unmarshal(bodyBytes, &configResp); err != nil {
-		return "", fmt.Errorf("failed to decode config response: %v, body: %s", err, string(bodyBytes))
+		return nil, err
 	}
 
-	if configResp.ID == "" {
-		return "", fmt.Errorf("config ID is empty in response: %s", string(bodyBytes))
-	}
-
-	return configResp.ID, nil
+	return config, nil
 }
 
-// updateHumeConfig creates a new version of an existing EVI config with updated prompt
-func (s *Server) updateHumeConfig(ctx context.Context, configID string, req CreateVoiceRequest, promptRef *HumePromptReference) error {
-	configReq := HumeConfigRequest{
-		Name:              req.Name,
-		EVIVersion:        req.EVIVersion,
-		VersionDescription: fmt.Sprintf("Updated with prompt: %s", req.Name),
-		Voice: &HumeVoiceReference{
-			Provider: "HUME_AI",
-		},
-		LanguageModel: &HumeLanguageModel{
-			ModelProvider: "ANTHROPIC",
-			ModelResource: "claude-3-7-sonnet-latest",
-			Temperature:  req.Temperature,
-		},
-		EventMessages: &HumeEventMessages{},
+// humeConfigMatches reports whether config already has every setting that
+// configReq would publish
+func humeConfigMatches(config *hume.Config, configReq hume.ConfigRequest) bool {
+	if config.EVIVersion != configReq.EVIVersion {
+		return false
 	}
-
-	// Include prompt reference if provided
-	if promptRef != nil {
-		configReq.Prompt = promptRef
+	if (config.Prompt == nil) != (configReq.Prompt == nil) {
+		return false
 	}
-
-	// EVI configs can use voice IDs or voice names from the Hume voice library
-	// Using a specific voice ID
-	defaultVoiceID := "5add9038-28df-40a6-900c-2f736d008ab3"
-	configReq.Voice.ID = &defaultVoiceID
-	configReq.Voice.Name = nil
-
-	reqBody, err := json.Marshal(configReq)
-	if err != nil {
-		return fmt.Errorf("failed to marshal config request: %w", err)
+	if config.Prompt != nil && (config.Prompt.ID != configReq.Prompt.ID || config.Prompt.Version != configReq.Prompt.Version) {
+		return false
 	}
+	return reflect.DeepEqual(config.Voice, configReq.Voice) &&
+		reflect.DeepEqual(config.LanguageModel, configReq.LanguageModel) &&
+		reflect.DeepEqual(config.EventMessages, configReq.EventMessages) &&
+		reflect.DeepEqual(config.Timeouts, configReq.Timeouts)
+}
 
-	log.Printf("Updating Hume config %s with request: %s", configID, string(reqBody))
+// updateHumeConfig creates a new version of an existing EVI config with updated prompt.
+// No version is created if the config's latest version already matches.
+func (s *Server) updateHumeConfig(ctx context.Context, configID string, req CreateVoiceRequest, promptRef *hume.PromptReference) (*hume.Config, error) {
+	configReq := humeConfigRequest(req, promptRef)
+	configReq.VersionDescription = fmt.Sprintf("Updated with prompt: %s", req.Name)
 
-	// Create a new version by POSTing to /v0/evi/configs/{id}/versions
-	// Note: This creates a new version of the existing config
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("https://api.hume.ai/v0/evi/configs/%s/versions", configID), bytes.NewBuffer(reqBody))
-	if err != nil {
-		return err
+	current, err := s.hume.GetConfig(ctx, configID)
+	if err == nil && humeConfigMatches(current, configReq) {
+		log.Printf("Hume config %s is unchanged at version %d", configID, current.Version)
+		return current, nil
 	}
-
-	httpReq.Header.Set("X-Hume-Api-Key", s.config.HumeAPIKey)
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(httpReq)
-	if err != nil {
-		return fmt.Errorf("failed to make request: %w", err)
+	if err != nil && !hume.IsNotFound(err) {
+		log.Printf("Warning: Could not fetch Hume config %s to compare: %v", configID, err)
 	}
-	defer resp.Body.Close()
 
-	bodyBytes, err := io.ReadAll(resp.Body)
+	config, err := s.hume.CreateConfigVersion(ctx, configID, configReq)
 	if err != nil {
-		return fmt.Errorf("failed to read response body: %w", err)
+		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		return fmt.Errorf("Hume API error (status %d): %s", resp.StatusCode, string(bodyBytes))
+	log.Printf("Updated Hume config: ID=%s, Version=%d", config.ID, config.Version)
+	return config, nil
+}
+
+// syncHumePrompt publishes the voice's prompt text, as a new version of its
+// existing Hume prompt when it has one
+func (s *Server) syncHumePrompt(ctx context.Context, voice *db.Voice) (*hume.PromptReference, error) {
+	promptID := voice.HumePromptID
+	if promptID == "" && voice.HumeConfigID != "" {
+		// Voices synced before prompt IDs were stored still have a prompt:
+		// the one their config points at
+		config, err := s.hume.GetConfig(ctx, voice.HumeConfigID)
+		if err == nil && config.Prompt != nil && config.Prompt.ID != "" {
+			promptID = config.Prompt.ID
+			log.Printf("Using prompt %s from config %s for voice %s", promptID, voice.HumeConfigID, voice.Name)
+		} else if err != nil && !hume.IsNotFound(err) {
+			return nil, err
+		}
 	}
 
-	var configResp HumeConfigResponse
-	if err := json.Unmarshal(bodyBytes, &configResp); err != nil {
-		return fmt.Errorf("failed to decode config response: %v, body: %s", err, string(bodyBytes))
+	if promptID != "" {
+		current, err := s.hume.GetPrompt(ctx, promptID)
+		if err == nil {
+			id, version, err := s.versionHumePrompt(ctx, current, voice.Prompt, fmt.Sprintf("Synced from voice %s", voice.Name))
+			if err != nil {
+				return nil, err
+			}
+			return &hume.PromptReference{ID: id, Version: version}, nil
+		}
+		if !hume.IsNotFound(err) {
+			return nil, err
+		}
+		log.Printf("Hume prompt %s for voice %s no longer exists, creating a new one", promptID, voice.Name)
 	}
 
-	log.Printf("Updated Hume config: ID=%s, Version=%d", configResp.ID, configResp.Version)
-	return nil
+	promptID, promptVersion, err := s.createHumePrompt(ctx, voice.Name, voice.Prompt)
+	if err != nil {
+		return nil, err
+	}
+	return &hume.PromptReference{ID: promptID, Version: promptVersion}, nil
 }
 
-// Note: updateHumeConfig is kept for potential future use but currently not working
-// because the /versions endpoint returns 404 for configs created via UI
-
-// syncVoiceToHume creates a prompt and creates a new config for an existing voice
-// Note: We create a new config instead of updating because the /versions endpoint
-// doesn't seem to be available for configs created via the UI
-func (s *Server) syncVoiceToHume(ctx context.Context, voice *db.Voice) error {
+// syncVoiceToHume publishes a voice's prompt and settings as new versions of
+// its existing Hume prompt and config. A new config is only created when the
+// voice has none or Hume no longer has the existing one; the replaced config
+// is then recorded so it can be cleaned up. The synced voice is
+// recorded as a revision described by change.
+func (s *Server) syncVoiceToHume(ctx context.Context, voice *db.Voice, change db.VoiceChange) error {
 	if voice.Prompt == "" {
 		return fmt.Errorf("voice has no prompt text")
 	}
 
-	// Create prompt in Hume
-	promptID, promptVersion, err := s.createHumePrompt(ctx, voice.Name, voice.Prompt)
+	promptRef, err := s.syncHumePrompt(ctx, voice)
 	if err != nil {
-		return fmt.Errorf("failed to create prompt: %w", err)
-	}
-
-	promptRef := &HumePromptReference{
-		ID:      promptID,
-		Version: promptVersion,
+		return fmt.Errorf("failed to sync prompt: %w", err)
 	}
 
-	// Create new config with prompt (instead of updating existing)
-	// Use a modified name to avoid conflicts with existing configs
-	configName := fmt.Sprintf("%s (%d)", voice.Name, time.Now().Unix())
 	req := CreateVoiceRequest{
-		Name:        configName,
-		Description: voice.Description,
-		Prompt:      voice.Prompt,
-		EVIVersion:  voice.EVIVersion,
-		Temperature: voice.Temperature,
+		Name:                  voice.Name,
+		Description:           voice.Description,
+		Prompt:                voice.Prompt,
+		EVIVersion:            voice.EVIVersion,
+		HumeVoiceProvider:     voice.HumeVoiceProvider,
+		HumeVoiceID:           voice.HumeVoiceID,
+		HumeVoiceName:         voice.HumeVoiceName,
+		LanguageModelProvider: voice.LanguageModelProvider,
+		LanguageModelResource: voice.LanguageModelResource,
+		Temperature:           voice.Temperature,
+		GreetingMessage:       voice.GreetingMessage,
+		InactivityMessage:     voice.InactivityMessage,
+		MaxDurationMessage:    voice.MaxDurationMessage,
+		InactivityTimeoutSecs: voice.InactivityTimeoutSecs,
+		MaxDurationSecs:       voice.MaxDurationSecs,
 	}
 
-	newConfigID, err := s.createHumeConfig(ctx, req, promptRef)
-	if err != nil {
-		return fmt.Errorf("failed to create new config: %w", err)
+	oldConfigID := voice.HumeConfigID
+	var config *hume.Config
+	if oldConfigID != "" {
+		config, err = s.updateHumeConfig(ctx, oldConfigID, req, promptRef)
+		if err != nil && !hume.IsNotFound(err) {
+			return fmt.Errorf("failed to create config version: %w", err)
+		}
+		if err != nil {
+			log.Printf("Config %s for voice %s not found in Hume (%v), creating a new config", oldConfigID, voice.Name, err)
+		}
 	}
 
-	// Store old config ID for logging
-	oldConfigID := voice.HumeConfigID
+	if config == nil {
+		config, err = s.createHumeConfig(ctx, req, promptRef)
+		if err != nil {
+			return fmt.Errorf("failed to create new config: %w", err)
+		}
+
+		if oldConfigID != "" {
+			if err := s.store.RecordReplacedHumeConfig(ctx, &voice.ID, oldConfigID, config.ID, "config not found in Hume"); err != nil {
+				log.Printf("Warning: Failed to record replaced config %s: %v", oldConfigID, err)
+			}
+			log.Printf("Created new config %s for voice %s (replacing old config %s)", config.ID, voice.Name, oldConfigID)
+		}
+	}
 
-	// Update the voice record with the new config ID
-	voice.HumeConfigID = newConfigID
-	_, err = s.db.UpdateVoice(ctx, voice.ID, voice)
+	// Update the voice record with what it now points at
+	voice.HumeConfigID = config.ID
+	voice.HumeConfigVersion = &config.Version
+	voice.HumePromptID = promptRef.ID
+	voice.HumePromptVersion = &promptRef.Version
+	updated, err := s.store.UpdateVoice(ctx, voice.ID, voice)
 	if err != nil {
-		log.Printf("Warning: Created new config %s but failed to update database: %v", newConfigID, err)
-		// Don't fail the sync if DB update fails - config was created successfully
+		log.Printf("Warning: Synced config %s but failed to update database: %v", config.ID, err)
+		// Don't fail the sync if DB update fails - Hume was updated successfully
+		return nil
 	}
+	s.recordVoiceRevision(ctx, updated, change)
 
-	log.Printf("Created new config %s for voice %s (replacing old config %s)", newConfigID, voice.Name, oldConfigID)
 	return nil
 }
 
@@ -813,14 +704,14 @@ func (s *Server) syncVoiceHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	// Get voice from database
-	voice, err := s.db.GetVoice(ctx, id)
+	voice, err := s.store.GetVoice(ctx, id)
 	if err != nil {
 		http.Error(w, "Voice not found", http.StatusNotFound)
 		return
 	}
 
 	// Sync to Hume
-	if err := s.syncVoiceToHume(ctx, voice); err != nil {
+	if err := s.syncVoiceToHume(ctx, voice, editorChange(r, db.VoiceActionSync, "")); err != nil {
 		log.Printf("Error syncing voice to Hume: %v", err)
 		http.Error(w, fmt.Sprintf("Failed to sync voice to Hume: %v", err), http.StatusInternalServerError)
 		return
@@ -831,49 +722,51 @@ func (s *Server) syncVoiceHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"status": "synced", "voice_id": id.String()})
 }
 
-func (s *Server) syncAllVoicesHandler(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+// listReplacedHumeConfigsHandler lists Hume configs no voice uses any more.
+// Pass ?all=true to include ones already cleaned up.
+func (s *Server) listReplacedHumeConfigsHandler(w http.ResponseWriter, r *http.Request) {
+	configs, err := s.store.ListReplacedHumeConfigs(r.Context(), r.URL.Query().Get("all") == "true")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list replaced configs: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if configs == nil {
+		configs = []db.ReplacedHumeConfig{}
+	}
 
-	// Get all voices
-	voices, err := s.db.ListVoices(ctx)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(configs)
+}
+
+// cleanupReplacedHumeConfigHandler deletes a replaced config from Hume
+func (s *Server) cleanupReplacedHumeConfigHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to list voices: %v", err), http.StatusInternalServerError)
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	replaced, err := s.store.GetReplacedHumeConfig(ctx, id)
+	if err != nil {
+		http.Error(w, "Replaced config not found", http.StatusNotFound)
 		return
 	}
 
-	results := make([]map[string]interface{}, 0)
-	for _, voice := range voices {
-		if voice.Prompt == "" || voice.HumeConfigID == "" {
-			results = append(results, map[string]interface{}{
-				"voice_id": voice.ID.String(),
-				"name":     voice.Name,
-				"status":    "skipped",
-				"reason":   "missing prompt or config ID",
-			})
-			continue
+	if replaced.CleanedUpAt == nil {
+		// Already gone from Hume counts as cleaned up
+		if err := s.hume.DeleteConfig(ctx, replaced.HumeConfigID); err != nil && !hume.IsNotFound(err) {
+			log.Printf("Error deleting Hume config %s: %v", replaced.HumeConfigID, err)
+			http.Error(w, fmt.Sprintf("Failed to delete Hume config: %v", err), http.StatusBadGateway)
+			return
 		}
 
-		if err := s.syncVoiceToHume(ctx, &voice); err != nil {
-			results = append(results, map[string]interface{}{
-				"voice_id": voice.ID.String(),
-				"name":     voice.Name,
-				"status":    "error",
-				"error":    err.Error(),
-			})
-		} else {
-			results = append(results, map[string]interface{}{
-				"voice_id": voice.ID.String(),
-				"name":     voice.Name,
-				"status":    "synced",
-			})
+		if err := s.store.MarkHumeConfigCleanedUp(ctx, id); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to update replaced config: %v", err), http.StatusInternalServerError)
+			return
 		}
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status":  "completed",
-		"results": results,
-	})
+	w.WriteHeader(http.StatusNoContent)
 }
-
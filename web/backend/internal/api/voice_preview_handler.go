@@ -0,0 +1,323 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"github.com/hume-evi/web/internal/db"
+	"github.com/hume-evi/web/internal/hume"
+)
+
+const (
+	// defaultPreviewText is spoken when a preview doesn't supply its own text
+	defaultPreviewText = "Hello, this is a test of the voice generation system."
+	// maxPreviewGenerations bounds how many samples one preview may request
+	maxPreviewGenerations = 5
+)
+
+type VoicePreviewRequest struct {
+	Description    string `json:"description"`
+	Text           string `json:"text"`
+	NumGenerations int    `json:"num_generations"`
+}
+
+// VoicePreviewItem is a generation with a URL its audio can be played from
+type VoicePreviewItem struct {
+	db.VoicePreview
+	AudioURL string `json:"audio_url"`
+}
+
+type VoicePreviewResponse struct {
+	// Cached is true when no new audio had to be generated
+	Cached   bool               `json:"cached"`
+	Previews []VoicePreviewItem `json:"previews"`
+}
+
+type PromoteVoicePreviewRequest struct {
+	// Name for the saved Hume voice (generated when empty)
+	Name string `json:"name"`
+	// VoiceID optionally makes a local voice speak with the saved voice
+	VoiceID string `json:"voice_id"`
+}
+
+type PromoteVoicePreviewResponse struct {
+	HumeVoice hume.Voice `json:"hume_voice"`
+	Voice     *db.Voice  `json:"voice,omitempty"`
+}
+
+// previewCacheKey identifies generations of the same text in the same voice
+func previewCacheKey(description, text string) string {
+	sum := sha256.Sum256([]byte(description + "\x00" + text))
+	return hex.EncodeToString(sum[:])
+}
+
+// previewMimeType maps a TTS encoding to a playable content type
+func previewMimeType(encoding *hume.Encoding) string {
+	if encoding != nil {
+		switch encoding.Format {
+		case "wav":
+			return "audio/wav"
+		case "pcm":
+			return "audio/L16"
+		}
+	}
+	return "audio/mpeg"
+}
+
+// previewVoice returns n generations of text spoken in the described voice.
+// Cached generations are reused; only the shortfall is synthesized.
+func (s *Server) previewVoice(ctx context.Context, description, text string, n int, createdBy *uuid.UUID) ([]db.VoicePreview, bool, error) {
+	key := previewCacheKey(description, text)
+	previews, err := s.store.ListVoicePreviews(ctx, key)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read preview cache: %w", err)
+	}
+	if len(previews) >= n {
+		return previews[:n], true, nil
+	}
+
+	ttsResp, err := s.hume.SynthesizeSpeech(ctx, hume.TTSRequest{
+		Utterances: []hume.Utterance{
+			{
+				Text:        text,
+				Description: description,
+			},
+		},
+		NumGenerations: n - len(previews),
+		Version:        "1",
+		Format:         &hume.AudioFormat{Type: "mp3"},
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(ttsResp.Generations) == 0 {
+		return nil, false, fmt.Errorf("no generations returned")
+	}
+
+	for _, gen := range ttsResp.Generations {
+		audio, err := base64.StdEncoding.DecodeString(gen.Audio)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to decode generated audio: %w", err)
+		}
+
+		preview, err := s.store.CreateVoicePreview(ctx, &db.VoicePreview{
+			CacheKey:     key,
+			Description:  description,
+			SampleText:   text,
+			GenerationID: gen.GenerationID,
+			MimeType:     previewMimeType(gen.Encoding),
+			Audio:        audio,
+			CreatedBy:    createdBy,
+		})
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to cache preview: %w", err)
+		}
+		previews = append(previews, *preview)
+	}
+
+	return previews, false, nil
+}
+
+// createHumeVoice creates a custom voice via Hume TTS API, reusing a cached
+// preview of the description (and the voice already saved from it) if any.
+// If Hume has expired the cached generation, the cache is cleared and a new
+// generation is saved instead.
+func (s *Server) createHumeVoice(ctx context.Context, voiceDescription string) (string, error) {
+	voiceID, err := s.saveFirstPreview(ctx, voiceDescription)
+	if hume.IsNotFound(err) {
+		log.Printf("Cached generation for voice description has expired, generating a new one")
+		if err := s.store.DeleteVoicePreviews(ctx, previewCacheKey(voiceDescription, defaultPreviewText)); err != nil {
+			return "", fmt.Errorf("failed to clear expired previews: %w", err)
+		}
+		voiceID, err = s.saveFirstPreview(ctx, voiceDescription)
+	}
+	return voiceID, err
+}
+
+// saveFirstPreview saves the first preview of the description as a Hume
+// voice, generating the preview if none is cached
+func (s *Server) saveFirstPreview(ctx context.Context, voiceDescription string) (string, error) {
+	previews, _, err := s.previewVoice(ctx, voiceDescription, defaultPreviewText, 1, nil)
+	if err != nil {
+		return "", err
+	}
+
+	preview := previews[0]
+	if preview.SavedVoiceID != "" {
+		return preview.SavedVoiceID, nil
+	}
+
+	// Save the voice
+	voice, err := s.hume.SaveVoice(ctx, preview.GenerationID, fmt.Sprintf("Voice-%d", time.Now().Unix()))
+	if err != nil {
+		return "", fmt.Errorf("failed to save voice: %w", err)
+	}
+
+	if err := s.store.MarkVoicePreviewSaved(ctx, preview.ID, voice.ID); err != nil {
+		log.Printf("Warning: Failed to record saved voice for preview %s: %v", preview.ID, err)
+	}
+	return voice.ID, nil
+}
+
+func (s *Server) previewVoiceHandler(w http.ResponseWriter, r *http.Request) {
+	var req VoicePreviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if req.Description == "" {
+		http.Error(w, "Description is required", http.StatusBadRequest)
+		return
+	}
+	if req.Text == "" {
+		req.Text = defaultPreviewText
+	}
+	if req.NumGenerations == 0 {
+		req.NumGenerations = 1
+	}
+	if req.NumGenerations < 1 || req.NumGenerations > maxPreviewGenerations {
+		http.Error(w, fmt.Sprintf("num_generations must be between 1 and %d", maxPreviewGenerations), http.StatusBadRequest)
+		return
+	}
+
+	var createdBy *uuid.UUID
+	if userID, err := uuid.Parse(getUserID(r)); err == nil {
+		createdBy = &userID
+	}
+
+	previews, cached, err := s.previewVoice(r.Context(), req.Description, req.Text, req.NumGenerations, createdBy)
+	if err != nil {
+		log.Printf("Error generating voice preview: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to generate preview: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	response := VoicePreviewResponse{Cached: cached, Previews: make([]VoicePreviewItem, 0, len(previews))}
+	for _, preview := range previews {
+		response.Previews = append(response.Previews, VoicePreviewItem{
+			VoicePreview: preview,
+			AudioURL:     fmt.Sprintf("/api/admin/voices/previews/%s/audio", preview.ID),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (s *Server) voicePreviewAudioHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid preview ID", http.StatusBadRequest)
+		return
+	}
+
+	preview, err := s.store.GetVoicePreview(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Preview not found", http.StatusNotFound)
+		return
+	}
+
+	// Generations never change, so the browser may keep them
+	w.Header().Set("Content-Type", preview.MimeType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(preview.Audio)))
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	w.Write(preview.Audio)
+}
+
+// promoteVoicePreviewHandler saves a previewed generation as a Hume voice
+func (s *Server) promoteVoicePreviewHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid preview ID", http.StatusBadRequest)
+		return
+	}
+
+	var req PromoteVoicePreviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	var voiceID uuid.UUID
+	if req.VoiceID != "" {
+		if voiceID, err = uuid.Parse(req.VoiceID); err != nil {
+			http.Error(w, "Invalid voice ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx := r.Context()
+	preview, err := s.store.GetVoicePreview(ctx, id)
+	if err != nil {
+		http.Error(w, "Preview not found", http.StatusNotFound)
+		return
+	}
+
+	humeVoice := hume.Voice{ID: preview.SavedVoiceID, Provider: hume.VoiceProviderCustom}
+	if preview.SavedVoiceID == "" {
+		name := req.Name
+		if name == "" {
+			name = fmt.Sprintf("Voice-%d", time.Now().Unix())
+		}
+
+		saved, err := s.hume.SaveVoice(ctx, preview.GenerationID, name)
+		if err != nil {
+			if hume.IsNotFound(err) {
+				// Hume no longer has the generation; drop the stale cache entries
+				if err := s.store.DeleteVoicePreviews(ctx, preview.CacheKey); err != nil {
+					log.Printf("Warning: Failed to clear expired previews: %v", err)
+				}
+				http.Error(w, "Generation has expired; preview the description again", http.StatusGone)
+				return
+			}
+			log.Printf("Error saving Hume voice: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to save voice: %v", err), http.StatusBadGateway)
+			return
+		}
+
+		humeVoice = *saved
+		if err := s.store.MarkVoicePreviewSaved(ctx, preview.ID, saved.ID); err != nil {
+			log.Printf("Warning: Failed to record saved voice for preview %s: %v", preview.ID, err)
+		}
+	}
+
+	response := PromoteVoicePreviewResponse{HumeVoice: humeVoice}
+	if req.VoiceID != "" {
+		voice, err := s.store.GetVoice(ctx, voiceID)
+		if err != nil {
+			http.Error(w, "Voice not found", http.StatusNotFound)
+			return
+		}
+
+		voice.VoiceDescription = preview.Description
+		voice.HumeVoiceProvider = hume.VoiceProviderCustom
+		voice.HumeVoiceID = humeVoice.ID
+		voice.HumeVoiceName = ""
+
+		updated, err := s.store.UpdateVoice(ctx, voiceID, voice)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to update voice: %v", err), http.StatusInternalServerError)
+			return
+		}
+		s.recordVoiceRevision(ctx, updated, editorChange(r, db.VoiceActionUpdate, "promoted voice preview"))
+		response.Voice = updated
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,204 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/hume-evi/web/internal/db"
+	"github.com/hume-evi/web/internal/hume"
+	"github.com/hume-evi/web/internal/hume/fakehume"
+)
+
+func TestPreviewVoiceCache(t *testing.T) {
+	s, _ := newSyncTestServer(t)
+	ctx := context.Background()
+
+	first, cached, err := s.previewVoice(ctx, "warm and low", "Hello there", 2, nil)
+	if err != nil {
+		t.Fatalf("previewVoice: %v", err)
+	}
+	if cached || len(first) != 2 || first[0].GenerationID == first[1].GenerationID {
+		t.Fatalf("first preview = %+v (cached %v), want 2 new generations", first, cached)
+	}
+	audio, err := s.store.GetVoicePreview(ctx, first[0].ID)
+	if err != nil || len(audio.Audio) == 0 || audio.MimeType != "audio/wav" {
+		t.Fatalf("cached preview = %+v, %v; want WAV audio", audio, err)
+	}
+
+	// Fewer generations than are cached come straight from the cache
+	again, cached, err := s.previewVoice(ctx, "warm and low", "Hello there", 1, nil)
+	if err != nil {
+		t.Fatalf("previewVoice: %v", err)
+	}
+	if !cached || len(again) != 1 || again[0].ID != first[0].ID {
+		t.Fatalf("repeat preview = %+v (cached %v), want the first cached generation", again, cached)
+	}
+
+	// More generations only synthesize the shortfall
+	more, cached, err := s.previewVoice(ctx, "warm and low", "Hello there", 3, nil)
+	if err != nil {
+		t.Fatalf("previewVoice: %v", err)
+	}
+	if cached || len(more) != 3 || more[0].ID != first[0].ID || more[1].ID != first[1].ID {
+		t.Fatalf("larger preview = %+v (cached %v), want the 2 cached generations and 1 new", more, cached)
+	}
+
+	// The sample text is part of the key
+	other, cached, err := s.previewVoice(ctx, "warm and low", "Goodbye", 1, nil)
+	if err != nil {
+		t.Fatalf("previewVoice: %v", err)
+	}
+	if cached || other[0].ID == first[0].ID {
+		t.Fatalf("preview of other text = %+v (cached %v), want a new generation", other, cached)
+	}
+}
+
+func TestCreateHumeVoiceReusesSavedPreview(t *testing.T) {
+	s, fake := newSyncTestServer(t)
+	ctx := context.Background()
+
+	voiceID, err := s.createHumeVoice(ctx, "warm and low")
+	if err != nil {
+		t.Fatalf("createHumeVoice: %v", err)
+	}
+	again, err := s.createHumeVoice(ctx, "warm and low")
+	if err != nil {
+		t.Fatalf("createHumeVoice: %v", err)
+	}
+	if again != voiceID {
+		t.Fatalf("second createHumeVoice = %s, want the saved voice %s", again, voiceID)
+	}
+	if voices := fake.Voices(); len(voices) != 1 || voices[0].ID != voiceID {
+		t.Fatalf("fake holds voices %+v, want just %s", voices, voiceID)
+	}
+}
+
+func TestCreateHumeVoiceRegeneratesExpiredGeneration(t *testing.T) {
+	s, fake := newSyncTestServer(t)
+	ctx := context.Background()
+
+	stale, _, err := s.previewVoice(ctx, "warm and low", defaultPreviewText, 1, nil)
+	if err != nil {
+		t.Fatalf("previewVoice: %v", err)
+	}
+	fake.ExpireGenerations()
+
+	voiceID, err := s.createHumeVoice(ctx, "warm and low")
+	if err != nil {
+		t.Fatalf("createHumeVoice with an expired generation: %v", err)
+	}
+	if voices := fake.Voices(); len(voices) != 1 || voices[0].ID != voiceID {
+		t.Fatalf("fake holds voices %+v, want just %s", voices, voiceID)
+	}
+
+	previews, err := s.store.ListVoicePreviews(ctx, previewCacheKey("warm and low", defaultPreviewText))
+	if err != nil {
+		t.Fatalf("ListVoicePreviews: %v", err)
+	}
+	if len(previews) != 1 || previews[0].ID == stale[0].ID || previews[0].SavedVoiceID != voiceID {
+		t.Fatalf("cache holds %+v, want only the new generation saved as %s", previews, voiceID)
+	}
+}
+
+func TestCreateHumeVoiceRegeneratesOnlyOnce(t *testing.T) {
+	s, fake := newSyncTestServer(t)
+	fake.Inject(fakehume.Fault{Method: http.MethodPost, PathPrefix: "/v0/tts/voices", Status: http.StatusNotFound, Times: 2})
+
+	_, err := s.createHumeVoice(context.Background(), "warm and low")
+	if !hume.IsNotFound(err) {
+		t.Fatalf("createHumeVoice = %v, want the second not found error", err)
+	}
+	if voices := fake.Voices(); len(voices) != 0 {
+		t.Fatalf("fake holds voices %+v, want none", voices)
+	}
+}
+
+// promotePreview calls promoteVoicePreviewHandler for a preview
+func promotePreview(t *testing.T, s *Server, previewID string, req PromoteVoicePreviewRequest) *httptest.ResponseRecorder {
+	t.Helper()
+	body, _ := json.Marshal(req)
+	r := httptest.NewRequest(http.MethodPost, "/api/admin/voices/previews/"+previewID+"/promote", bytes.NewReader(body))
+	r = mux.SetURLVars(r, map[string]string{"id": previewID})
+	w := httptest.NewRecorder()
+	s.promoteVoicePreviewHandler(w, r)
+	return w
+}
+
+func TestPromoteVoicePreview(t *testing.T) {
+	s, fake := newSyncTestServer(t)
+	ctx := context.Background()
+	voice := createTestVoice(t, s, &db.Voice{Name: "Sam", Prompt: "Be kind.", HumeVoiceProvider: hume.VoiceProviderHume, HumeVoiceName: "Ito"})
+
+	previews, _, err := s.previewVoice(ctx, "warm and low", "Hello there", 1, nil)
+	if err != nil {
+		t.Fatalf("previewVoice: %v", err)
+	}
+	previewID := previews[0].ID.String()
+
+	w := promotePreview(t, s, previewID, PromoteVoicePreviewRequest{Name: "Warm", VoiceID: voice.ID.String()})
+	if w.Code != http.StatusOK {
+		t.Fatalf("promote = %d %s", w.Code, w.Body)
+	}
+	var resp PromoteVoicePreviewResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decoding promote response: %v", err)
+	}
+	if resp.HumeVoice.Name != "Warm" || resp.HumeVoice.Provider != hume.VoiceProviderCustom {
+		t.Fatalf("promoted as %+v, want custom voice Warm", resp.HumeVoice)
+	}
+
+	updated, err := s.store.GetVoice(ctx, voice.ID)
+	if err != nil {
+		t.Fatalf("GetVoice: %v", err)
+	}
+	if updated.HumeVoiceID != resp.HumeVoice.ID || updated.HumeVoiceProvider != hume.VoiceProviderCustom || updated.HumeVoiceName != "" || updated.VoiceDescription != "warm and low" {
+		t.Fatalf("voice after promote = %+v", updated)
+	}
+	if revisions := recordedRevisions(t, s, voice.ID); len(revisions) != 1 || revisions[0].Action != db.VoiceActionUpdate {
+		t.Fatalf("recorded revisions %+v, want one update", revisions)
+	}
+
+	// Promoting again reuses the saved voice
+	w = promotePreview(t, s, previewID, PromoteVoicePreviewRequest{Name: "Warm again"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("second promote = %d %s", w.Code, w.Body)
+	}
+	var again PromoteVoicePreviewResponse
+	json.NewDecoder(w.Body).Decode(&again)
+	if again.HumeVoice.ID != resp.HumeVoice.ID || len(fake.Voices()) != 1 {
+		t.Fatalf("second promote saved %+v, fake holds %d voices; want the first voice reused", again.HumeVoice, len(fake.Voices()))
+	}
+}
+
+func TestPromoteExpiredVoicePreview(t *testing.T) {
+	s, fake := newSyncTestServer(t)
+	ctx := context.Background()
+
+	previews, _, err := s.previewVoice(ctx, "warm and low", "Hello there", 2, nil)
+	if err != nil {
+		t.Fatalf("previewVoice: %v", err)
+	}
+	fake.ExpireGenerations()
+
+	w := promotePreview(t, s, previews[0].ID.String(), PromoteVoicePreviewRequest{})
+	if w.Code != http.StatusGone {
+		t.Fatalf("promote of an expired generation = %d %s, want 410", w.Code, w.Body)
+	}
+	cached, err := s.store.ListVoicePreviews(ctx, previews[0].CacheKey)
+	if err != nil || len(cached) != 0 {
+		t.Fatalf("cache after expiry = %+v, %v; want the stale generations dropped", cached, err)
+	}
+
+	if w := promotePreview(t, s, "not-a-uuid", PromoteVoicePreviewRequest{}); w.Code != http.StatusBadRequest {
+		t.Fatalf("promote with a bad ID = %d, want 400", w.Code)
+	}
+	if w := promotePreview(t, s, previews[0].ID.String(), PromoteVoicePreviewRequest{}); w.Code != http.StatusNotFound {
+		t.Fatalf("promote of a dropped preview = %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,235 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"github.com/hume-evi/web/internal/db"
+	"github.com/hume-evi/web/internal/textdiff"
+)
+
+// diffContextLines is how much unchanged prompt text surrounds each change
+const diffContextLines = 3
+
+// FieldChange is a setting that differs between two revisions
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// VoiceRevisionDiff compares two revisions of a voice
+type VoiceRevisionDiff struct {
+	From       int           `json:"from"`
+	To         int           `json:"to"`
+	Changes    []FieldChange `json:"changes"`
+	PromptDiff string        `json:"prompt_diff"`
+}
+
+// editorChange builds the revision metadata for a change made by the caller
+func editorChange(r *http.Request, action, note string) db.VoiceChange {
+	return db.VoiceChange{Action: action, Note: note, EditedBy: requestUserID(r)}
+}
+
+// requestUserID returns the requesting user, if the request is authenticated
+func requestUserID(r *http.Request) *uuid.UUID {
+	if userID, err := uuid.Parse(getUserID(r)); err == nil {
+		return &userID
+	}
+	return nil
+}
+
+// recordVoiceRevision snapshots a voice; a failure is logged rather than
+// failing the change that was already saved
+func (s *Server) recordVoiceRevision(ctx context.Context, voice *db.Voice, change db.VoiceChange) {
+	if _, err := s.store.RecordVoiceRevision(ctx, voice, change); err != nil {
+		log.Printf("Warning: Failed to record revision for voice %s: %v", voice.ID, err)
+	}
+}
+
+func (s *Server) listVoiceRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid voice ID", http.StatusBadRequest)
+		return
+	}
+
+	revisions, err := s.store.ListVoiceRevisions(r.Context(), id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list revisions: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if revisions == nil {
+		revisions = []db.VoiceRevision{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(revisions)
+}
+
+// diffVoiceRevisionsHandler compares ?from=<revision> with ?to=<revision>
+// (the latest revision when omitted)
+func (s *Server) diffVoiceRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid voice ID", http.StatusBadRequest)
+		return
+	}
+
+	fromNum, err := strconv.Atoi(r.URL.Query().Get("from"))
+	if err != nil {
+		http.Error(w, "from must be a revision number", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	from, err := s.store.GetVoiceRevision(ctx, id, fromNum)
+	if err != nil {
+		http.Error(w, "Revision not found", http.StatusNotFound)
+		return
+	}
+
+	var to *db.VoiceRevision
+	if toStr := r.URL.Query().Get("to"); toStr != "" {
+		toNum, err := strconv.Atoi(toStr)
+		if err != nil {
+			http.Error(w, "to must be a revision number", http.StatusBadRequest)
+			return
+		}
+		to, err = s.store.GetVoiceRevision(ctx, id, toNum)
+		if err != nil {
+			http.Error(w, "Revision not found", http.StatusNotFound)
+			return
+		}
+	} else {
+		to, err = s.store.GetLatestVoiceRevision(ctx, id)
+		if err != nil {
+			http.Error(w, "Revision not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(diffVoiceRevisions(from, to))
+}
+
+func diffVoiceRevisions(from, to *db.VoiceRevision) VoiceRevisionDiff {
+	diff := VoiceRevisionDiff{From: from.Revision, To: to.Revision, Changes: []FieldChange{}}
+
+	fields := []struct {
+		name     string
+		from, to interface{}
+	}{
+		{"name", from.Name, to.Name},
+		{"description", from.Description, to.Description},
+		{"voice_description", from.VoiceDescription, to.VoiceDescription},
+		{"evi_version", from.EVIVersion, to.EVIVersion},
+		{"language_model_provider", from.LanguageModelProvider, to.LanguageModelProvider},
+		{"language_model_resource", from.LanguageModelResource, to.LanguageModelResource},
+		{"temperature", from.Temperature, to.Temperature},
+		{"greeting_message", from.GreetingMessage, to.GreetingMessage},
+		{"inactivity_message", from.InactivityMessage, to.InactivityMessage},
+		{"max_duration_message", from.MaxDurationMessage, to.MaxDurationMessage},
+		{"inactivity_timeout_secs", from.InactivityTimeoutSecs, to.InactivityTimeoutSecs},
+		{"max_duration_secs", from.MaxDurationSecs, to.MaxDurationSecs},
+		{"hume_voice_provider", from.HumeVoiceProvider, to.HumeVoiceProvider},
+		{"hume_voice_id", from.HumeVoiceID, to.HumeVoiceID},
+		{"hume_voice_name", from.HumeVoiceName, to.HumeVoiceName},
+		{"hume_prompt_id", from.HumePromptID, to.HumePromptID},
+		{"hume_prompt_version", intOrNil(from.HumePromptVersion), intOrNil(to.HumePromptVersion)},
+		{"hume_config_id", from.HumeConfigID, to.HumeConfigID},
+		{"hume_config_version", intOrNil(from.HumeConfigVersion), intOrNil(to.HumeConfigVersion)},
+	}
+	for _, f := range fields {
+		if f.from != f.to {
+			diff.Changes = append(diff.Changes, FieldChange{Field: f.name, From: f.from, To: f.to})
+		}
+	}
+
+	diff.PromptDiff = textdiff.Unified(
+		fmt.Sprintf("revision %d", from.Revision),
+		fmt.Sprintf("revision %d", to.Revision),
+		textdiff.Lines(from.Prompt, to.Prompt),
+		diffContextLines,
+	)
+	return diff
+}
+
+// intOrNil dereferences an optional version so it compares by value
+func intOrNil(v *int) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+// rollbackVoiceHandler restores a revision's content (prompt, model and
+// voice settings, including which Hume voice speaks) and syncs it to Hume as new prompt and config versions
+func (s *Server) rollbackVoiceHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid voice ID", http.StatusBadRequest)
+		return
+	}
+	revisionNum, err := strconv.Atoi(vars["revision"])
+	if err != nil {
+		http.Error(w, "Invalid revision", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	voice, err := s.store.GetVoice(ctx, id)
+	if err != nil {
+		http.Error(w, "Voice not found", http.StatusNotFound)
+		return
+	}
+	revision, err := s.store.GetVoiceRevision(ctx, id, revisionNum)
+	if err != nil {
+		http.Error(w, "Revision not found", http.StatusNotFound)
+		return
+	}
+
+	// Hume IDs stay current: the old text is published as new versions
+	voice.Name = revision.Name
+	voice.Description = revision.Description
+	voice.Prompt = revision.Prompt
+	voice.VoiceDescription = revision.VoiceDescription
+	voice.HumeVoiceProvider = revision.HumeVoiceProvider
+	voice.HumeVoiceID = revision.HumeVoiceID
+	voice.HumeVoiceName = revision.HumeVoiceName
+	voice.EVIVersion = revision.EVIVersion
+	voice.LanguageModelProvider = revision.LanguageModelProvider
+	voice.LanguageModelResource = revision.LanguageModelResource
+	voice.Temperature = revision.Temperature
+	voice.GreetingMessage = revision.GreetingMessage
+	voice.InactivityMessage = revision.InactivityMessage
+	voice.MaxDurationMessage = revision.MaxDurationMessage
+	voice.InactivityTimeoutSecs = revision.InactivityTimeoutSecs
+	voice.MaxDurationSecs = revision.MaxDurationSecs
+
+	change := editorChange(r, db.VoiceActionRollback, fmt.Sprintf("rolled back to revision %d", revision.Revision))
+	if err := s.syncVoiceToHume(ctx, voice, change); err != nil {
+		log.Printf("Error syncing rolled back voice to Hume: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to sync voice to Hume: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	updated, err := s.store.GetVoice(ctx, id)
+	if err != nil {
+		http.Error(w, "Voice not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/hume-evi/web/internal/db"
+)
+
+func TestDiffVoiceRevisions(t *testing.T) {
+	version := func(v int) *int { return &v }
+	editor := uuid.New()
+	from := &db.VoiceRevision{
+		ID: uuid.New(), Revision: 1, Action: db.VoiceActionCreate,
+		Name: "Sam", Prompt: "Be kind.\nListen.\n", Temperature: 0.7,
+		HumeVoiceProvider: "HUME_AI", HumeVoiceName: "Ito",
+		HumePromptVersion: version(0), HumeConfigVersion: nil,
+		CreatedAt: time.Now().Add(-time.Hour),
+	}
+	to := &db.VoiceRevision{
+		ID: uuid.New(), Revision: 4, Action: db.VoiceActionUpdate, Note: "warmer", EditedBy: &editor,
+		Name: "Sam", Prompt: "Be kind.\nListen closely.\n", Temperature: 0.9,
+		HumeVoiceProvider: "HUME_AI", HumeVoiceName: "Ito",
+		HumePromptVersion: version(0), HumeConfigVersion: version(2),
+		CreatedAt: time.Now(),
+	}
+
+	diff := diffVoiceRevisions(from, to)
+	if diff.From != 1 || diff.To != 4 {
+		t.Fatalf("diff compares %d with %d, want 1 with 4", diff.From, diff.To)
+	}
+	// Revision metadata isn't a setting, the prompt is diffed separately,
+	// and equal versions compare by value rather than by pointer
+	want := []FieldChange{
+		{Field: "temperature", From: 0.7, To: 0.9},
+		{Field: "hume_config_version", From: nil, To: 2},
+	}
+	if !reflect.DeepEqual(diff.Changes, want) {
+		t.Fatalf("changes %+v, want %+v", diff.Changes, want)
+	}
+	wantPrompt := `--- revision 1
++++ revision 4
+@@ -1,2 +1,2 @@
+ Be kind.
+-Listen.
++Listen closely.
+`
+	if diff.PromptDiff != wantPrompt {
+		t.Fatalf("prompt diff\n%s\nwant\n%s", diff.PromptDiff, wantPrompt)
+	}
+
+	same := diffVoiceRevisions(from, from)
+	if same.Changes == nil || len(same.Changes) != 0 || same.PromptDiff != "" {
+		t.Fatalf("diff of a revision with itself = %+v, want no changes", same)
+	}
+}
@@ -0,0 +1,233 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/hume-evi/web/internal/db"
+	"github.com/hume-evi/web/internal/db/memstore"
+	"github.com/hume-evi/web/internal/hume"
+	"github.com/hume-evi/web/internal/hume/fakehume"
+)
+
+// newSyncTestServer returns a server backed by a memstore and the fake Hume API
+func newSyncTestServer(t *testing.T) (*Server, *fakehume.Server) {
+	t.Helper()
+	fake, humeServer := fakehume.NewTestServer()
+	fake.APIKey = "test-key"
+	t.Cleanup(humeServer.Close)
+
+	s := &Server{
+		store: memstore.New(),
+		hume:  hume.NewClient(humeServer.URL, "test-key"),
+	}
+	return s, fake
+}
+
+// recordedRevisions returns a voice's revisions, newest first
+func recordedRevisions(t *testing.T, s *Server, voiceID uuid.UUID) []db.VoiceRevision {
+	t.Helper()
+	revisions, err := s.store.ListVoiceRevisions(context.Background(), voiceID)
+	if err != nil {
+		t.Fatalf("ListVoiceRevisions: %v", err)
+	}
+	return revisions
+}
+
+// replacedConfigIDs returns the IDs of every replaced Hume config
+func replacedConfigIDs(t *testing.T, s *Server) []string {
+	t.Helper()
+	configs, err := s.store.ListReplacedHumeConfigs(context.Background(), true)
+	if err != nil {
+		t.Fatalf("ListReplacedHumeConfigs: %v", err)
+	}
+	var ids []string
+	for _, config := range configs {
+		ids = append(ids, config.HumeConfigID)
+	}
+	return ids
+}
+
+func createTestVoice(t *testing.T, s *Server, voice *db.Voice) *db.Voice {
+	t.Helper()
+	if voice.EVIVersion == "" {
+		voice.EVIVersion = "3"
+	}
+	voice.LanguageModelProvider = hume.DefaultModelProvider
+	voice.LanguageModelResource = hume.DefaultModelResource
+	voice.Temperature = 1
+	created, err := s.store.CreateVoice(context.Background(), voice)
+	if err != nil {
+		t.Fatalf("CreateVoice: %v", err)
+	}
+	return created
+}
+
+func syncTestVoice(t *testing.T, s *Server, voice *db.Voice) *db.Voice {
+	t.Helper()
+	ctx := context.Background()
+	if err := s.syncVoiceToHume(ctx, voice, db.VoiceChange{Action: db.VoiceActionSync}); err != nil {
+		t.Fatalf("syncVoiceToHume: %v", err)
+	}
+	synced, err := s.store.GetVoice(ctx, voice.ID)
+	if err != nil {
+		t.Fatalf("GetVoice: %v", err)
+	}
+	return synced
+}
+
+func latestPromptText(t *testing.T, s *Server, promptID string) string {
+	t.Helper()
+	prompt, err := s.hume.GetPrompt(context.Background(), promptID)
+	if err != nil {
+		t.Fatalf("GetPrompt: %v", err)
+	}
+	return prompt.Text
+}
+
+func TestSyncVoiceVersionsPromptAndConfig(t *testing.T) {
+	s, fake := newSyncTestServer(t)
+	voice := createTestVoice(t, s, &db.Voice{Name: "Sam", Prompt: "Be kind."})
+
+	voice = syncTestVoice(t, s, voice)
+	if voice.HumePromptID == "" || voice.HumeConfigID == "" {
+		t.Fatalf("first sync left the voice without a prompt or config: %+v", voice)
+	}
+	configID, promptID := voice.HumeConfigID, voice.HumePromptID
+
+	voice.Prompt = "Be kinder."
+	voice = syncTestVoice(t, s, voice)
+	if voice.HumeConfigID != configID || voice.HumePromptID != promptID {
+		t.Fatalf("edit replaced the config or prompt: %+v", voice)
+	}
+	if *voice.HumePromptVersion != 1 || *voice.HumeConfigVersion != 1 {
+		t.Fatalf("got prompt version %d and config version %d, want 1 and 1", *voice.HumePromptVersion, *voice.HumeConfigVersion)
+	}
+	if text := latestPromptText(t, s, promptID); text != "Be kinder." {
+		t.Fatalf("Hume prompt text = %q, want the edit", text)
+	}
+
+	// Nothing changed, so nothing new is published
+	voice = syncTestVoice(t, s, voice)
+	if n := len(fake.ConfigVersions(configID)); n != 2 {
+		t.Fatalf("fake holds %d config versions, want 2", n)
+	}
+	if *voice.HumePromptVersion != 1 || *voice.HumeConfigVersion != 1 {
+		t.Fatalf("unchanged sync moved to prompt version %d and config version %d", *voice.HumePromptVersion, *voice.HumeConfigVersion)
+	}
+	revisions, replaced := recordedRevisions(t, s, voice.ID), replacedConfigIDs(t, s)
+	if len(revisions) != 3 || len(replaced) != 0 {
+		t.Fatalf("got %d revisions and replaced configs %v, want 3 and none", len(revisions), replaced)
+	}
+}
+
+func TestSyncVoiceReusesConflictingPrompt(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("conflict names the prompt", func(t *testing.T) {
+		s, _ := newSyncTestServer(t)
+		existing, err := s.hume.CreatePrompt(ctx, "Sam", "Old text.")
+		if err != nil {
+			t.Fatalf("CreatePrompt: %v", err)
+		}
+
+		voice := syncTestVoice(t, s, createTestVoice(t, s, &db.Voice{Name: "Sam", Prompt: "New text."}))
+		if voice.HumePromptID != existing.ID || *voice.HumePromptVersion != 1 {
+			t.Fatalf("got prompt %s version %d, want %s version 1", voice.HumePromptID, *voice.HumePromptVersion, existing.ID)
+		}
+		if text := latestPromptText(t, s, existing.ID); text != "New text." {
+			t.Fatalf("Hume prompt text = %q, want the voice's", text)
+		}
+	})
+
+	t.Run("conflict found by name", func(t *testing.T) {
+		s, fake := newSyncTestServer(t)
+		existing, err := s.hume.CreatePrompt(ctx, "Sam", "Old text.")
+		if err != nil {
+			t.Fatalf("CreatePrompt: %v", err)
+		}
+		fake.InjectConflict("/v0/evi/prompts")
+
+		voice := syncTestVoice(t, s, createTestVoice(t, s, &db.Voice{Name: "Sam", Prompt: "New text."}))
+		if voice.HumePromptID != existing.ID {
+			t.Fatalf("got prompt %s, want %s", voice.HumePromptID, existing.ID)
+		}
+		if text := latestPromptText(t, s, existing.ID); text != "New text." {
+			t.Fatalf("Hume prompt text = %q, want the voice's", text)
+		}
+	})
+
+	t.Run("unchanged text", func(t *testing.T) {
+		s, _ := newSyncTestServer(t)
+		existing, err := s.hume.CreatePrompt(ctx, "Sam", "Same text.")
+		if err != nil {
+			t.Fatalf("CreatePrompt: %v", err)
+		}
+
+		voice := syncTestVoice(t, s, createTestVoice(t, s, &db.Voice{Name: "Sam", Prompt: "Same text."}))
+		if voice.HumePromptID != existing.ID || *voice.HumePromptVersion != 0 {
+			t.Fatalf("got prompt %s version %d, want %s version 0", voice.HumePromptID, *voice.HumePromptVersion, existing.ID)
+		}
+	})
+}
+
+func TestSyncVoiceWithoutStoredPromptUsesConfigPrompt(t *testing.T) {
+	s, fake := newSyncTestServer(t)
+	ctx := context.Background()
+
+	// A voice synced before prompt IDs were stored
+	voice := syncTestVoice(t, s, createTestVoice(t, s, &db.Voice{Name: "Sam", Prompt: "Be kind."}))
+	promptID := voice.HumePromptID
+	voice.HumePromptID = ""
+	voice.HumePromptVersion = nil
+	voice.Prompt = "Be kinder."
+	if _, err := s.store.UpdateVoice(ctx, voice.ID, voice); err != nil {
+		t.Fatalf("UpdateVoice: %v", err)
+	}
+
+	voice = syncTestVoice(t, s, voice)
+	if voice.HumePromptID != promptID {
+		t.Fatalf("got prompt %s, want the config's prompt %s", voice.HumePromptID, promptID)
+	}
+	if text := latestPromptText(t, s, promptID); text != "Be kinder." {
+		t.Fatalf("Hume prompt text = %q, want the edit", text)
+	}
+	versions := fake.ConfigVersions(voice.HumeConfigID)
+	if latest := versions[len(versions)-1]; latest.Prompt == nil || latest.Prompt.Version != 1 {
+		t.Fatalf("config points at %+v, want prompt version 1", latest.Prompt)
+	}
+}
+
+func TestSyncVoiceReplacesMissingConfig(t *testing.T) {
+	s, fake := newSyncTestServer(t)
+	voice := syncTestVoice(t, s, createTestVoice(t, s, &db.Voice{Name: "Sam", Prompt: "Be kind."}))
+	oldConfigID := voice.HumeConfigID
+
+	// The config has gone from Hume (e.g. deleted in its dashboard)
+	fake.Inject(fakehume.Fault{
+		Method:     "POST",
+		PathPrefix: "/v0/evi/configs/" + oldConfigID,
+		Status:     http.StatusNotFound,
+		Times:      1,
+	})
+	voice.Prompt = "Be kinder."
+	voice = syncTestVoice(t, s, voice)
+
+	if voice.HumeConfigID == oldConfigID || voice.HumeConfigID == "" {
+		t.Fatalf("voice still points at config %q, want a new one", voice.HumeConfigID)
+	}
+	if replaced := replacedConfigIDs(t, s); len(replaced) != 1 || replaced[0] != oldConfigID {
+		t.Fatalf("replaced configs = %v, want [%s]", replaced, oldConfigID)
+	}
+	// The old config kept its name, so the new one gets a retry name
+	config, err := s.hume.GetConfig(context.Background(), voice.HumeConfigID)
+	if err != nil {
+		t.Fatalf("GetConfig: %v", err)
+	}
+	if config.Name == "Sam" {
+		t.Fatalf("new config reused the taken name %q", config.Name)
+	}
+}
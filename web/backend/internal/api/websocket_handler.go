@@ -0,0 +1,53 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/hume-evi/web/internal/websocket"
+)
+
+// websocketHandler upgrades an authenticated request into an EVI proxy session.
+// Browsers can't set headers on a WebSocket handshake, so the auth_token cookie
+// is validated here rather than through authMiddleware.
+func (s *Server) websocketHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := s.authenticateRequest(r)
+	if err != nil {
+		log.Printf("WebSocket auth failed: %v", err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	websocket.ServeWS(s.hub, w, r, claims.UserID)
+}
+
+// listSessionsHandler lists the caller's live EVI proxy sessions
+func (s *Server) listSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	sessions := s.hub.UserSessions(getUserID(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessions)
+}
+
+// kickSessionHandler disconnects one of the caller's own sessions
+func (s *Server) kickSessionHandler(w http.ResponseWriter, r *http.Request) {
+	sessionID := mux.Vars(r)["id"]
+
+	// Only allow kicking sessions that belong to the caller
+	owned := false
+	for _, session := range s.hub.UserSessions(getUserID(r)) {
+		if session.SessionID == sessionID {
+			owned = true
+			break
+		}
+	}
+	if !owned || !s.hub.KickSession(sessionID) {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidCursor is returned by DecodeCursor for anything Encode didn't make
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is a row's position in a (time, id) keyset ordering: conversations
+// by updated_at and messages by timestamp, with the id breaking ties
+type Cursor struct {
+	Time time.Time
+	ID   uuid.UUID
+}
+
+// Encode returns the cursor as an opaque, URL-safe string. Timestamps are
+// kept to the microsecond, as Postgres stores them.
+func (c Cursor) Encode() string {
+	raw := fmt.Sprintf("%d:%s", c.Time.UnixMicro(), c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor parses a string made by Encode
+func DecodeCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	micros, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	usec, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &Cursor{Time: time.UnixMicro(usec).UTC(), ID: parsedID}, nil
+}
+
+// ConversationCursor is conv's position in ListConversations order
+func ConversationCursor(conv Conversation) Cursor {
+	return Cursor{Time: conv.UpdatedAt, ID: conv.ID}
+}
+
+// MessageCursor is msg's position in ListMessages order
+func MessageCursor(msg Message) Cursor {
+	return Cursor{Time: msg.Timestamp, ID: msg.ID}
+}
+
+// MessagePage selects a window of a conversation's messages. After and
+// Before are exclusive; with neither, the window is the newest messages.
+// Setting both is an error.
+type MessagePage struct {
+	Limit  int
+	After  *Cursor
+	Before *Cursor
+}
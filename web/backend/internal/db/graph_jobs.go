@@ -0,0 +1,202 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Graph extraction job statuses
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobRetrying  = "retrying"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+type GraphExtractionJob struct {
+	ConversationID uuid.UUID  `json:"conversation_id"`
+	UserID         uuid.UUID  `json:"user_id"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	LastError      string     `json:"last_error,omitempty"`
+	Entities       int        `json:"entities"`
+	Relationships  int        `json:"relationships"`
+	Rerun          bool       `json:"rerun"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+}
+
+const graphJobColumns = `conversation_id, user_id, status, attempts, last_error, entities, relationships, rerun, next_attempt_at, created_at, updated_at, completed_at`
+
+func scanGraphJob(row pgx.Row) (*GraphExtractionJob, error) {
+	var job GraphExtractionJob
+	var lastError sql.NullString
+	err := row.Scan(&job.ConversationID, &job.UserID, &job.Status, &job.Attempts, &lastError, &job.Entities, &job.Relationships, &job.Rerun, &job.NextAttemptAt, &job.CreatedAt, &job.UpdatedAt, &job.CompletedAt)
+	if err != nil {
+		return nil, err
+	}
+	job.LastError = lastError.String
+	return &job, nil
+}
+
+// EnqueueGraphExtraction schedules extraction for a conversation. Re-enqueuing
+// a finished job (e.g. a resumed conversation that ended again) resets it; a
+// job that is currently running may have missed the latest messages, so it is
+// flagged to run again once it finishes.
+func (db *DB) EnqueueGraphExtraction(ctx context.Context, conversationID, userID uuid.UUID) error {
+	// Flag a running job first: if it finishes before the insert below, the
+	// insert resets it instead, so the request is never dropped
+	tag, err := db.Pool.Exec(ctx,
+		`UPDATE graph_extraction_jobs SET rerun = true, updated_at = CURRENT_TIMESTAMP
+		 WHERE conversation_id = $1 AND status = 'running'`,
+		conversationID,
+	)
+	if err != nil || tag.RowsAffected() > 0 {
+		return err
+	}
+
+	// A job claimed since the update started after this request, so it
+	// sees every message and needn't be flagged
+	_, err = db.Pool.Exec(ctx,
+		`INSERT INTO graph_extraction_jobs (conversation_id, user_id) VALUES ($1, $2)
+		 ON CONFLICT (conversation_id) DO UPDATE
+		 SET status = 'pending', attempts = 0, last_error = NULL, rerun = false, next_attempt_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP, completed_at = NULL
+		 WHERE graph_extraction_jobs.status <> 'running'`,
+		conversationID, userID,
+	)
+	return err
+}
+
+// ClaimGraphExtractionJob marks the next due job as running and returns it.
+// It returns nil when no job is due. SKIP LOCKED lets several replicas poll
+// the same queue.
+func (db *DB) ClaimGraphExtractionJob(ctx context.Context) (*GraphExtractionJob, error) {
+	job, err := scanGraphJob(db.Pool.QueryRow(ctx,
+		`UPDATE graph_extraction_jobs SET status = 'running', attempts = attempts + 1, updated_at = CURRENT_TIMESTAMP
+		 WHERE conversation_id = (
+			SELECT conversation_id FROM graph_extraction_jobs
+			WHERE status IN ('pending', 'retrying') AND next_attempt_at <= CURRENT_TIMESTAMP
+			ORDER BY next_attempt_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		 )
+		 RETURNING `+graphJobColumns,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return job, err
+}
+
+// CompleteGraphExtractionJob records a successful run, then requeues the
+// job if it was enqueued again meanwhile
+func (db *DB) CompleteGraphExtractionJob(ctx context.Context, conversationID uuid.UUID, entities, relationships int) error {
+	_, err := db.Pool.Exec(ctx,
+		`UPDATE graph_extraction_jobs SET status = 'succeeded', last_error = NULL, entities = $1, relationships = $2, updated_at = CURRENT_TIMESTAMP, completed_at = CURRENT_TIMESTAMP
+		 WHERE conversation_id = $3`,
+		entities, relationships, conversationID,
+	)
+	if err != nil {
+		return err
+	}
+	return db.rerunGraphExtractionJob(ctx, conversationID)
+}
+
+// FailGraphExtractionJob records an error. With a retry time the job goes
+// back in the queue; without one it is marked as permanently failed. Either
+// way a job enqueued again meanwhile starts over with fresh attempts.
+func (db *DB) FailGraphExtractionJob(ctx context.Context, conversationID uuid.UUID, errMsg string, retryAt *time.Time) error {
+	var err error
+	if retryAt == nil {
+		_, err = db.Pool.Exec(ctx,
+			`UPDATE graph_extraction_jobs SET status = 'failed', last_error = $1, updated_at = CURRENT_TIMESTAMP, completed_at = CURRENT_TIMESTAMP
+			 WHERE conversation_id = $2`,
+			errMsg, conversationID,
+		)
+	} else {
+		_, err = db.Pool.Exec(ctx,
+			`UPDATE graph_extraction_jobs SET status = 'retrying', last_error = $1, next_attempt_at = $2, updated_at = CURRENT_TIMESTAMP
+			 WHERE conversation_id = $3`,
+			errMsg, *retryAt, conversationID,
+		)
+	}
+	if err != nil {
+		return err
+	}
+	return db.rerunGraphExtractionJob(ctx, conversationID)
+}
+
+// rerunGraphExtractionJob puts a finished job flagged for a rerun back in
+// the queue
+func (db *DB) rerunGraphExtractionJob(ctx context.Context, conversationID uuid.UUID) error {
+	_, err := db.Pool.Exec(ctx,
+		`UPDATE graph_extraction_jobs SET status = 'pending', attempts = 0, rerun = false, next_attempt_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP, completed_at = NULL
+		 WHERE conversation_id = $1 AND rerun AND status <> 'running'`,
+		conversationID,
+	)
+	return err
+}
+
+// RequeueRunningGraphExtractionJobs puts jobs left running by a crashed
+// process back in the queue. Their next run sees every message, so any rerun
+// flag is cleared.
+func (db *DB) RequeueRunningGraphExtractionJobs(ctx context.Context) (int64, error) {
+	tag, err := db.Pool.Exec(ctx,
+		`UPDATE graph_extraction_jobs SET status = 'retrying', rerun = false, next_attempt_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		 WHERE status = 'running'`,
+	)
+	return tag.RowsAffected(), err
+}
+
+// ListGraphExtractionJobs lists jobs, optionally filtered by status, most recently updated first
+func (db *DB) ListGraphExtractionJobs(ctx context.Context, status string, limit int) ([]GraphExtractionJob, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT `+graphJobColumns+` FROM graph_extraction_jobs
+		 WHERE $1 = '' OR status = $1
+		 ORDER BY updated_at DESC
+		 LIMIT $2`,
+		status, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []GraphExtractionJob
+	for rows.Next() {
+		job, err := scanGraphJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, rows.Err()
+}
+
+// CountGraphExtractionJobs returns the number of jobs in each status
+func (db *DB) CountGraphExtractionJobs(ctx context.Context) (map[string]int, error) {
+	rows, err := db.Pool.Query(ctx, `SELECT status, COUNT(*) FROM graph_extraction_jobs GROUP BY status`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		counts[status] = count
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,223 @@
+package db_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/hume-evi/web/internal/db"
+)
+
+// graphJobConversations creates a user with n conversations
+func graphJobConversations(t *testing.T, database *db.DB, n int) (uuid.UUID, []uuid.UUID) {
+	t.Helper()
+	ctx := context.Background()
+	user, err := database.CreateUser(ctx, "ada", "hash", nil, false)
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	var ids []uuid.UUID
+	for i := 0; i < n; i++ {
+		conv, err := database.CreateConversation(ctx, user.ID, "Chat")
+		if err != nil {
+			t.Fatalf("CreateConversation: %v", err)
+		}
+		ids = append(ids, conv.ID)
+	}
+	return user.ID, ids
+}
+
+// setNextAttempt moves a job's next attempt relative to now
+func setNextAttempt(t *testing.T, database *db.DB, conversationID uuid.UUID, offset time.Duration) {
+	t.Helper()
+	_, err := database.Pool.Exec(context.Background(),
+		`UPDATE graph_extraction_jobs SET next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $1) WHERE conversation_id = $2`,
+		offset.Seconds(), conversationID)
+	if err != nil {
+		t.Fatalf("moving next attempt: %v", err)
+	}
+}
+
+func getGraphJob(t *testing.T, database *db.DB, conversationID uuid.UUID) db.GraphExtractionJob {
+	t.Helper()
+	jobs, err := database.ListGraphExtractionJobs(context.Background(), "", 100)
+	if err != nil {
+		t.Fatalf("ListGraphExtractionJobs: %v", err)
+	}
+	for _, job := range jobs {
+		if job.ConversationID == conversationID {
+			return job
+		}
+	}
+	t.Fatalf("no job for conversation %s", conversationID)
+	return db.GraphExtractionJob{}
+}
+
+func claim(t *testing.T, database *db.DB) *db.GraphExtractionJob {
+	t.Helper()
+	job, err := database.ClaimGraphExtractionJob(context.Background())
+	if err != nil {
+		t.Fatalf("ClaimGraphExtractionJob: %v", err)
+	}
+	return job
+}
+
+func TestClaimGraphExtractionJob(t *testing.T) {
+	database := openTestDB(t)
+	emptyTestDB(t, database)
+	ctx := context.Background()
+
+	userID, convs := graphJobConversations(t, database, 4)
+	for i, convID := range convs {
+		if err := database.EnqueueGraphExtraction(ctx, convID, userID); err != nil {
+			t.Fatalf("EnqueueGraphExtraction: %v", err)
+		}
+		setNextAttempt(t, database, convID, time.Duration(i-10)*time.Minute)
+	}
+	// The last job isn't due yet
+	setNextAttempt(t, database, convs[3], time.Hour)
+
+	// Hold the first job's row as another replica claiming it would
+	tx, err := database.Pool.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM graph_extraction_jobs WHERE conversation_id = $1 FOR UPDATE`, convs[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	// Claims skip the locked job instead of waiting on it, take due jobs in
+	// order, and leave the one that isn't due
+	for _, want := range convs[1:3] {
+		job := claim(t, database)
+		if job == nil || job.ConversationID != want {
+			t.Fatalf("claimed %+v, want conversation %s", job, want)
+		}
+		if job.Status != db.JobRunning || job.Attempts != 1 {
+			t.Fatalf("claimed job is %s after %d attempts, want running after 1", job.Status, job.Attempts)
+		}
+	}
+	if job := claim(t, database); job != nil {
+		t.Fatalf("claimed %+v, want nothing while the only due job is locked", job)
+	}
+
+	tx.Rollback(ctx)
+	if job := claim(t, database); job == nil || job.ConversationID != convs[0] {
+		t.Fatalf("claimed %+v once unlocked, want conversation %s", job, convs[0])
+	}
+	if job := claim(t, database); job != nil {
+		t.Fatalf("claimed %+v, want nothing before the last job is due", job)
+	}
+}
+
+func TestGraphExtractionJobRetries(t *testing.T) {
+	database := openTestDB(t)
+	emptyTestDB(t, database)
+	ctx := context.Background()
+
+	userID, convs := graphJobConversations(t, database, 1)
+	convID := convs[0]
+	if err := database.EnqueueGraphExtraction(ctx, convID, userID); err != nil {
+		t.Fatalf("EnqueueGraphExtraction: %v", err)
+	}
+
+	// A retry waits for its time
+	claim(t, database)
+	retryAt := time.Now().Add(time.Hour)
+	if err := database.FailGraphExtractionJob(ctx, convID, "extractor down", &retryAt); err != nil {
+		t.Fatalf("FailGraphExtractionJob: %v", err)
+	}
+	if job := getGraphJob(t, database, convID); job.Status != db.JobRetrying || job.LastError != "extractor down" || job.CompletedAt != nil {
+		t.Fatalf("job after a retryable failure: %+v", job)
+	}
+	if job := claim(t, database); job != nil {
+		t.Fatalf("claimed %+v before its retry time", job)
+	}
+
+	setNextAttempt(t, database, convID, -time.Second)
+	job := claim(t, database)
+	if job == nil || job.Attempts != 2 {
+		t.Fatalf("claimed %+v, want the retry as attempt 2", job)
+	}
+
+	// Without a retry time the job fails for good
+	if err := database.FailGraphExtractionJob(ctx, convID, "still down", nil); err != nil {
+		t.Fatalf("FailGraphExtractionJob: %v", err)
+	}
+	if job := getGraphJob(t, database, convID); job.Status != db.JobFailed || job.LastError != "still down" || job.CompletedAt == nil {
+		t.Fatalf("job after a permanent failure: %+v", job)
+	}
+	if job := claim(t, database); job != nil {
+		t.Fatalf("claimed failed job %+v", job)
+	}
+
+	// Enqueuing again starts over
+	if err := database.EnqueueGraphExtraction(ctx, convID, userID); err != nil {
+		t.Fatalf("EnqueueGraphExtraction: %v", err)
+	}
+	if job := getGraphJob(t, database, convID); job.Status != db.JobPending || job.Attempts != 0 || job.LastError != "" || job.CompletedAt != nil {
+		t.Fatalf("job enqueued after failing: %+v", job)
+	}
+}
+
+func TestGraphExtractionJobEnqueuedWhileRunning(t *testing.T) {
+	database := openTestDB(t)
+	emptyTestDB(t, database)
+	ctx := context.Background()
+
+	userID, convs := graphJobConversations(t, database, 1)
+	convID := convs[0]
+	enqueue := func() {
+		t.Helper()
+		if err := database.EnqueueGraphExtraction(ctx, convID, userID); err != nil {
+			t.Fatalf("EnqueueGraphExtraction: %v", err)
+		}
+	}
+
+	// Enqueuing a running job leaves it running but flags it
+	enqueue()
+	claim(t, database)
+	enqueue()
+	if job := getGraphJob(t, database, convID); job.Status != db.JobRunning || !job.Rerun || job.Attempts != 1 {
+		t.Fatalf("job enqueued while running: %+v", job)
+	}
+
+	// Finishing runs it again from scratch
+	if err := database.CompleteGraphExtractionJob(ctx, convID, 3, 2); err != nil {
+		t.Fatalf("CompleteGraphExtractionJob: %v", err)
+	}
+	if job := getGraphJob(t, database, convID); job.Status != db.JobPending || job.Rerun || job.Attempts != 0 || job.CompletedAt != nil {
+		t.Fatalf("flagged job after completing: %+v", job)
+	}
+	claim(t, database)
+	if err := database.CompleteGraphExtractionJob(ctx, convID, 3, 2); err != nil {
+		t.Fatalf("CompleteGraphExtractionJob: %v", err)
+	}
+	if job := getGraphJob(t, database, convID); job.Status != db.JobSucceeded || job.Entities != 3 || job.CompletedAt == nil {
+		t.Fatalf("job after its rerun: %+v", job)
+	}
+
+	// So does failing, even for good
+	enqueue()
+	claim(t, database)
+	enqueue()
+	if err := database.FailGraphExtractionJob(ctx, convID, "extractor down", nil); err != nil {
+		t.Fatalf("FailGraphExtractionJob: %v", err)
+	}
+	if job := getGraphJob(t, database, convID); job.Status != db.JobPending || job.Rerun || job.Attempts != 0 {
+		t.Fatalf("flagged job after failing: %+v", job)
+	}
+
+	// A job requeued after a crash sees every message anyway
+	claim(t, database)
+	enqueue()
+	if _, err := database.RequeueRunningGraphExtractionJobs(ctx); err != nil {
+		t.Fatalf("RequeueRunningGraphExtractionJobs: %v", err)
+	}
+	if job := getGraphJob(t, database, convID); job.Status != db.JobRetrying || job.Rerun {
+		t.Fatalf("requeued job: %+v", job)
+	}
+}
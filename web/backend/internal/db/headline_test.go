@@ -0,0 +1,65 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseHeadline(t *testing.T) {
+	tests := []struct {
+		name     string
+		headline string
+		want     []SnippetPart
+	}{
+		{"empty", "", nil},
+		{"no matches", "we flew south", []SnippetPart{{Text: "we flew south"}}},
+		{
+			name:     "match at the start",
+			headline: "\x01Lisbon\x02 in spring",
+			want:     []SnippetPart{{Text: "Lisbon", Match: true}, {Text: " in spring"}},
+		},
+		{
+			name:     "match at the end",
+			headline: "we flew to \x01Lisbon\x02",
+			want:     []SnippetPart{{Text: "we flew to "}, {Text: "Lisbon", Match: true}},
+		},
+		{
+			name:     "whole headline matches",
+			headline: "\x01Lisbon\x02",
+			want:     []SnippetPart{{Text: "Lisbon", Match: true}},
+		},
+		{
+			name:     "several matches",
+			headline: "\x01Lisbon\x02 then \x01Porto\x02\x01Porto\x02 … home",
+			want: []SnippetPart{
+				{Text: "Lisbon", Match: true},
+				{Text: " then "},
+				{Text: "Porto", Match: true},
+				{Text: "Porto", Match: true},
+				{Text: " … home"},
+			},
+		},
+		{
+			name:     "empty match",
+			headline: "we \x01\x02flew",
+			want:     []SnippetPart{{Text: "we "}, {Text: "flew"}},
+		},
+		{
+			name:     "unterminated match",
+			headline: "we flew to \x01Lisbon in spring",
+			want:     []SnippetPart{{Text: "we flew to "}, {Text: "Lisbon in spring", Match: true}},
+		},
+		{
+			name:     "unterminated marker at the end",
+			headline: "we flew\x01",
+			want:     []SnippetPart{{Text: "we flew"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseHeadline(tt.headline); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("parseHeadline(%q) = %+v, want %+v", tt.headline, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReplacedHumeConfig is a Hume config that no voice points at any more
+type ReplacedHumeConfig struct {
+	ID           uuid.UUID  `json:"id"`
+	VoiceID      *uuid.UUID `json:"voice_id,omitempty"`
+	HumeConfigID string     `json:"hume_config_id"`
+	ReplacedBy   string     `json:"replaced_by,omitempty"`
+	Reason       string     `json:"reason"`
+	CreatedAt    time.Time  `json:"created_at"`
+	CleanedUpAt  *time.Time `json:"cleaned_up_at,omitempty"`
+}
+
+// RecordReplacedHumeConfig notes that a voice stopped using a Hume config.
+// replacedBy is empty when nothing replaced it (e.g. the voice was deleted).
+func (db *DB) RecordReplacedHumeConfig(ctx context.Context, voiceID *uuid.UUID, configID, replacedBy, reason string) error {
+	_, err := db.Pool.Exec(ctx,
+		`INSERT INTO replaced_hume_configs (voice_id, hume_config_id, replaced_by, reason)
+		 VALUES ($1, $2, NULLIF($3, ''), $4)`,
+		voiceID, configID, replacedBy, reason,
+	)
+	return err
+}
+
+// ListReplacedHumeConfigs lists replaced configs, oldest first. Only configs
+// still awaiting cleanup are returned unless includeCleaned is set.
+func (db *DB) ListReplacedHumeConfigs(ctx context.Context, includeCleaned bool) ([]ReplacedHumeConfig, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT id, voice_id, hume_config_id, COALESCE(replaced_by, ''), COALESCE(reason, ''), created_at, cleaned_up_at
+		 FROM replaced_hume_configs
+		 WHERE $1 OR cleaned_up_at IS NULL
+		 ORDER BY created_at`,
+		includeCleaned,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var configs []ReplacedHumeConfig
+	for rows.Next() {
+		var c ReplacedHumeConfig
+		if err := rows.Scan(&c.ID, &c.VoiceID, &c.HumeConfigID, &c.ReplacedBy, &c.Reason, &c.CreatedAt, &c.CleanedUpAt); err != nil {
+			return nil, err
+		}
+		configs = append(configs, c)
+	}
+	return configs, rows.Err()
+}
+
+// GetReplacedHumeConfig fetches one replaced config record
+func (db *DB) GetReplacedHumeConfig(ctx context.Context, id uuid.UUID) (*ReplacedHumeConfig, error) {
+	var c ReplacedHumeConfig
+	err := db.Pool.QueryRow(ctx,
+		`SELECT id, voice_id, hume_config_id, COALESCE(replaced_by, ''), COALESCE(reason, ''), created_at, cleaned_up_at
+		 FROM replaced_hume_configs WHERE id = $1`,
+		id,
+	).Scan(&c.ID, &c.VoiceID, &c.HumeConfigID, &c.ReplacedBy, &c.Reason, &c.CreatedAt, &c.CleanedUpAt)
+	return &c, err
+}
+
+// MarkHumeConfigCleanedUp records that a replaced config was deleted from Hume
+func (db *DB) MarkHumeConfigCleanedUp(ctx context.Context, id uuid.UUID) error {
+	_, err := db.Pool.Exec(ctx,
+		`UPDATE replaced_hume_configs SET cleaned_up_at = CURRENT_TIMESTAMP WHERE id = $1`,
+		id,
+	)
+	return err
+}
@@ -0,0 +1,138 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Hume sync run kinds
+const (
+	HumeSyncKindSync      = "sync"
+	HumeSyncKindReconcile = "reconcile"
+)
+
+// ErrHumeSyncRunning is returned when a run of the same kind is in progress
+var ErrHumeSyncRunning = errors.New("a run of this kind is already in progress")
+
+// HumeSyncResult is the outcome for one voice (or one orphaned config) in a
+// run. Drift lists what a reconciliation found out of step with Hume.
+type HumeSyncResult struct {
+	VoiceID      *uuid.UUID `json:"voice_id,omitempty"`
+	Name         string     `json:"name"`
+	HumeConfigID string     `json:"hume_config_id,omitempty"`
+	Status       string     `json:"status"`
+	Drift        []string   `json:"drift,omitempty"`
+	Detail       string     `json:"detail,omitempty"`
+}
+
+// HumeSyncRun is a bulk sync or reconciliation. Results are appended as
+// voices are processed, so a running run reports its progress.
+type HumeSyncRun struct {
+	ID         uuid.UUID        `json:"id"`
+	Kind       string           `json:"kind"`
+	Status     string           `json:"status"`
+	Heal       bool             `json:"heal"`
+	Total      int              `json:"total"`
+	Processed  int              `json:"processed"`
+	Results    []HumeSyncResult `json:"results"`
+	Error      string           `json:"error,omitempty"`
+	StartedBy  *uuid.UUID       `json:"started_by,omitempty"`
+	StartedAt  time.Time        `json:"started_at"`
+	FinishedAt *time.Time       `json:"finished_at,omitempty"`
+}
+
+const humeSyncRunColumns = `id, kind, status, heal, total, processed, results, error, started_by, started_at, finished_at`
+
+func scanHumeSyncRun(row pgx.Row) (*HumeSyncRun, error) {
+	var run HumeSyncRun
+	var runError sql.NullString
+	err := row.Scan(&run.ID, &run.Kind, &run.Status, &run.Heal, &run.Total, &run.Processed, &run.Results, &runError, &run.StartedBy, &run.StartedAt, &run.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	run.Error = runError.String
+	if run.Results == nil {
+		run.Results = []HumeSyncResult{}
+	}
+	return &run, nil
+}
+
+// StartHumeSyncRun records a new running run of the given kind. It returns
+// ErrHumeSyncRunning if one is already running.
+func (db *DB) StartHumeSyncRun(ctx context.Context, kind string, heal bool, total int, startedBy *uuid.UUID) (*HumeSyncRun, error) {
+	run, err := scanHumeSyncRun(db.Pool.QueryRow(ctx,
+		`INSERT INTO hume_sync_runs (kind, heal, total, started_by) VALUES ($1, $2, $3, $4)
+		 RETURNING `+humeSyncRunColumns,
+		kind, heal, total, startedBy,
+	))
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return nil, ErrHumeSyncRunning
+	}
+	return run, err
+}
+
+// SetHumeSyncRunTotal updates how many results a run expects
+func (db *DB) SetHumeSyncRunTotal(ctx context.Context, id uuid.UUID, total int) error {
+	_, err := db.Pool.Exec(ctx, `UPDATE hume_sync_runs SET total = $2 WHERE id = $1`, id, total)
+	return err
+}
+
+// AppendHumeSyncResult adds one result to a run and advances its progress
+func (db *DB) AppendHumeSyncResult(ctx context.Context, id uuid.UUID, result HumeSyncResult) error {
+	_, err := db.Pool.Exec(ctx,
+		`UPDATE hume_sync_runs SET results = results || jsonb_build_array($2::jsonb), processed = processed + 1
+		 WHERE id = $1`,
+		id, result,
+	)
+	return err
+}
+
+// FinishHumeSyncRun marks a run as succeeded or failed
+func (db *DB) FinishHumeSyncRun(ctx context.Context, id uuid.UUID, status, runError string) error {
+	_, err := db.Pool.Exec(ctx,
+		`UPDATE hume_sync_runs SET status = $2, error = NULLIF($3, ''), finished_at = CURRENT_TIMESTAMP
+		 WHERE id = $1`,
+		id, status, runError,
+	)
+	return err
+}
+
+// FailInterruptedHumeSyncRuns fails runs left running by a previous process
+func (db *DB) FailInterruptedHumeSyncRuns(ctx context.Context) (int64, error) {
+	tag, err := db.Pool.Exec(ctx,
+		`UPDATE hume_sync_runs SET status = 'failed', error = 'interrupted by server restart', finished_at = CURRENT_TIMESTAMP
+		 WHERE status = 'running'`,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// GetHumeSyncRun fetches a run by ID
+func (db *DB) GetHumeSyncRun(ctx context.Context, id uuid.UUID) (*HumeSyncRun, error) {
+	return scanHumeSyncRun(db.Pool.QueryRow(ctx,
+		`SELECT `+humeSyncRunColumns+` FROM hume_sync_runs WHERE id = $1`,
+		id,
+	))
+}
+
+// GetLatestHumeSyncRun fetches the newest run of a kind, or nil if there is none
+func (db *DB) GetLatestHumeSyncRun(ctx context.Context, kind string) (*HumeSyncRun, error) {
+	run, err := scanHumeSyncRun(db.Pool.QueryRow(ctx,
+		`SELECT `+humeSyncRunColumns+` FROM hume_sync_runs WHERE kind = $1
+		 ORDER BY started_at DESC LIMIT 1`,
+		kind,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return run, err
+}
@@ -0,0 +1,194 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/hume-evi/web/internal/db"
+)
+
+// graphJob carries a sequence number that is bumped on every write, so jobs
+// updated within the same clock tick still list in write order
+type graphJob struct {
+	db.GraphExtractionJob
+	seq int64
+}
+
+func copyGraphJob(j *graphJob) *db.GraphExtractionJob {
+	out := j.GraphExtractionJob
+	if j.CompletedAt != nil {
+		completedAt := *j.CompletedAt
+		out.CompletedAt = &completedAt
+	}
+	return &out
+}
+
+func (s *Store) touchJobLocked(j *graphJob) {
+	j.UpdatedAt = now()
+	j.seq = s.next()
+}
+
+// resetJobLocked puts a job back in the queue with fresh attempts
+func (s *Store) resetJobLocked(j *graphJob) {
+	j.Status = db.JobPending
+	j.Attempts = 0
+	j.Rerun = false
+	j.NextAttemptAt = now()
+	j.CompletedAt = nil
+	s.touchJobLocked(j)
+}
+
+func (s *Store) EnqueueGraphExtraction(ctx context.Context, conversationID, userID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.conversations[conversationID]; !ok {
+		return db.ErrNotFound
+	}
+	j, ok := s.graphJobs[conversationID]
+	switch {
+	case !ok:
+		created := now()
+		j = &graphJob{GraphExtractionJob: db.GraphExtractionJob{
+			ConversationID: conversationID,
+			UserID:         userID,
+			Status:         db.JobPending,
+			NextAttemptAt:  created,
+			CreatedAt:      created,
+		}}
+		s.touchJobLocked(j)
+		s.graphJobs[conversationID] = j
+	case j.Status == db.JobRunning:
+		j.Rerun = true
+		s.touchJobLocked(j)
+	default:
+		j.LastError = ""
+		s.resetJobLocked(j)
+	}
+	return nil
+}
+
+func (s *Store) ClaimGraphExtractionJob(ctx context.Context) (*db.GraphExtractionJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	due := now()
+	var next *graphJob
+	for _, j := range s.graphJobs {
+		if j.Status != db.JobPending && j.Status != db.JobRetrying || j.NextAttemptAt.After(due) {
+			continue
+		}
+		if next == nil || j.NextAttemptAt.Before(next.NextAttemptAt) ||
+			j.NextAttemptAt.Equal(next.NextAttemptAt) && j.seq < next.seq {
+			next = j
+		}
+	}
+	if next == nil {
+		return nil, nil
+	}
+	next.Status = db.JobRunning
+	next.Attempts++
+	s.touchJobLocked(next)
+	return copyGraphJob(next), nil
+}
+
+// finishJobLocked requeues a job enqueued again while it ran
+func (s *Store) finishJobLocked(j *graphJob) {
+	if j.Rerun {
+		s.resetJobLocked(j)
+	}
+}
+
+func (s *Store) CompleteGraphExtractionJob(ctx context.Context, conversationID uuid.UUID, entities, relationships int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, ok := s.graphJobs[conversationID]
+	if !ok {
+		return nil
+	}
+	completedAt := now()
+	j.Status = db.JobSucceeded
+	j.LastError = ""
+	j.Entities = entities
+	j.Relationships = relationships
+	j.CompletedAt = &completedAt
+	s.touchJobLocked(j)
+	s.finishJobLocked(j)
+	return nil
+}
+
+func (s *Store) FailGraphExtractionJob(ctx context.Context, conversationID uuid.UUID, errMsg string, retryAt *time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, ok := s.graphJobs[conversationID]
+	if !ok {
+		return nil
+	}
+	j.LastError = errMsg
+	if retryAt == nil {
+		completedAt := now()
+		j.Status = db.JobFailed
+		j.CompletedAt = &completedAt
+	} else {
+		j.Status = db.JobRetrying
+		j.NextAttemptAt = retryAt.UTC().Truncate(time.Microsecond)
+	}
+	s.touchJobLocked(j)
+	s.finishJobLocked(j)
+	return nil
+}
+
+func (s *Store) RequeueRunningGraphExtractionJobs(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var requeued int64
+	for _, j := range s.graphJobs {
+		if j.Status == db.JobRunning {
+			j.Status = db.JobRetrying
+			j.Rerun = false
+			j.NextAttemptAt = now()
+			s.touchJobLocked(j)
+			requeued++
+		}
+	}
+	return requeued, nil
+}
+
+func (s *Store) ListGraphExtractionJobs(ctx context.Context, status string, limit int) ([]db.GraphExtractionJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var matching []*graphJob
+	for _, j := range s.graphJobs {
+		if status == "" || j.Status == status {
+			matching = append(matching, j)
+		}
+	}
+	sort.Slice(matching, func(i, k int) bool { return matching[i].seq > matching[k].seq })
+	if len(matching) > limit {
+		matching = matching[:limit]
+	}
+
+	var jobs []db.GraphExtractionJob
+	for _, j := range matching {
+		jobs = append(jobs, *copyGraphJob(j))
+	}
+	return jobs, nil
+}
+
+func (s *Store) CountGraphExtractionJobs(ctx context.Context) (map[string]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := map[string]int{}
+	for _, j := range s.graphJobs {
+		counts[j.Status]++
+	}
+	return counts, nil
+}
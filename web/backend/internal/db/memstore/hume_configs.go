@@ -0,0 +1,80 @@
+package memstore
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/hume-evi/web/internal/db"
+)
+
+func copyReplaced(r *db.ReplacedHumeConfig) *db.ReplacedHumeConfig {
+	out := *r
+	if r.VoiceID != nil {
+		voiceID := *r.VoiceID
+		out.VoiceID = &voiceID
+	}
+	if r.CleanedUpAt != nil {
+		cleanedUpAt := *r.CleanedUpAt
+		out.CleanedUpAt = &cleanedUpAt
+	}
+	return &out
+}
+
+func (s *Store) RecordReplacedHumeConfig(ctx context.Context, voiceID *uuid.UUID, configID, replacedBy, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if voiceID != nil {
+		if _, ok := s.voices[*voiceID]; !ok {
+			return db.ErrNotFound
+		}
+	}
+	s.replaced = append(s.replaced, copyReplaced(&db.ReplacedHumeConfig{
+		ID:           uuid.New(),
+		VoiceID:      voiceID,
+		HumeConfigID: configID,
+		ReplacedBy:   replacedBy,
+		Reason:       reason,
+		CreatedAt:    now(),
+	}))
+	return nil
+}
+
+func (s *Store) ListReplacedHumeConfigs(ctx context.Context, includeCleaned bool) ([]db.ReplacedHumeConfig, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var configs []db.ReplacedHumeConfig
+	for _, r := range s.replaced {
+		if includeCleaned || r.CleanedUpAt == nil {
+			configs = append(configs, *copyReplaced(r))
+		}
+	}
+	return configs, nil
+}
+
+func (s *Store) GetReplacedHumeConfig(ctx context.Context, id uuid.UUID) (*db.ReplacedHumeConfig, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, r := range s.replaced {
+		if r.ID == id {
+			return copyReplaced(r), nil
+		}
+	}
+	return nil, db.ErrNotFound
+}
+
+func (s *Store) MarkHumeConfigCleanedUp(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, r := range s.replaced {
+		if r.ID == id {
+			cleanedUpAt := now()
+			r.CleanedUpAt = &cleanedUpAt
+		}
+	}
+	return nil
+}
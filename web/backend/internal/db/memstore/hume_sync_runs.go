@@ -0,0 +1,137 @@
+package memstore
+
+import (
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+
+	"github.com/hume-evi/web/internal/db"
+)
+
+func copySyncRun(run *db.HumeSyncRun) *db.HumeSyncRun {
+	out := *run
+	out.Results = make([]db.HumeSyncResult, len(run.Results))
+	for i, result := range run.Results {
+		if result.VoiceID != nil {
+			voiceID := *result.VoiceID
+			result.VoiceID = &voiceID
+		}
+		result.Drift = slices.Clone(result.Drift)
+		out.Results[i] = result
+	}
+	if run.StartedBy != nil {
+		startedBy := *run.StartedBy
+		out.StartedBy = &startedBy
+	}
+	if run.FinishedAt != nil {
+		finishedAt := *run.FinishedAt
+		out.FinishedAt = &finishedAt
+	}
+	return &out
+}
+
+func (s *Store) syncRunLocked(id uuid.UUID) *db.HumeSyncRun {
+	for _, run := range s.syncRuns {
+		if run.ID == id {
+			return run
+		}
+	}
+	return nil
+}
+
+func (s *Store) StartHumeSyncRun(ctx context.Context, kind string, heal bool, total int, startedBy *uuid.UUID) (*db.HumeSyncRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, run := range s.syncRuns {
+		if run.Kind == kind && run.Status == db.JobRunning {
+			return nil, db.ErrHumeSyncRunning
+		}
+	}
+	run := copySyncRun(&db.HumeSyncRun{
+		ID:        uuid.New(),
+		Kind:      kind,
+		Status:    db.JobRunning,
+		Heal:      heal,
+		Total:     total,
+		StartedBy: startedBy,
+		StartedAt: now(),
+	})
+	s.syncRuns = append(s.syncRuns, run)
+	return copySyncRun(run), nil
+}
+
+func (s *Store) SetHumeSyncRunTotal(ctx context.Context, id uuid.UUID, total int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if run := s.syncRunLocked(id); run != nil {
+		run.Total = total
+	}
+	return nil
+}
+
+func (s *Store) AppendHumeSyncResult(ctx context.Context, id uuid.UUID, result db.HumeSyncResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if run := s.syncRunLocked(id); run != nil {
+		copied := copySyncRun(&db.HumeSyncRun{Results: []db.HumeSyncResult{result}})
+		run.Results = append(run.Results, copied.Results[0])
+		run.Processed++
+	}
+	return nil
+}
+
+func (s *Store) FinishHumeSyncRun(ctx context.Context, id uuid.UUID, status, runError string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if run := s.syncRunLocked(id); run != nil {
+		finishedAt := now()
+		run.Status = status
+		run.Error = runError
+		run.FinishedAt = &finishedAt
+	}
+	return nil
+}
+
+func (s *Store) FailInterruptedHumeSyncRuns(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var failed int64
+	for _, run := range s.syncRuns {
+		if run.Status == db.JobRunning {
+			finishedAt := now()
+			run.Status = db.JobFailed
+			run.Error = "interrupted by server restart"
+			run.FinishedAt = &finishedAt
+			failed++
+		}
+	}
+	return failed, nil
+}
+
+func (s *Store) GetHumeSyncRun(ctx context.Context, id uuid.UUID) (*db.HumeSyncRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if run := s.syncRunLocked(id); run != nil {
+		return copySyncRun(run), nil
+	}
+	return nil, db.ErrNotFound
+}
+
+func (s *Store) GetLatestHumeSyncRun(ctx context.Context, kind string) (*db.HumeSyncRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := len(s.syncRuns) - 1; i >= 0; i-- {
+		if s.syncRuns[i].Kind == kind {
+			return copySyncRun(s.syncRuns[i]), nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,641 @@
+// Package memstore is a map-backed db.Store for tests and local tools. It
+// follows the Postgres store's semantics: unique usernames, conversations
+// scoped to their owner, cascading deletes, and db.ErrNotFound for missing
+// rows.
+package memstore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/hume-evi/web/internal/db"
+)
+
+// Store is safe for concurrent use. The zero value is not usable; call New.
+type Store struct {
+	mu            sync.Mutex
+	seq           int64
+	users         map[uuid.UUID]*user
+	conversations map[uuid.UUID]*conversation
+	messages      map[uuid.UUID][]db.Message          // by conversation, oldest first
+	injections    map[uuid.UUID][]db.ContextInjection // by conversation, oldest first
+	voices        map[uuid.UUID]*voice
+	previews      []*db.VoicePreview               // oldest first
+	revisions     map[uuid.UUID][]db.VoiceRevision // by voice, oldest first
+	replaced      []*db.ReplacedHumeConfig         // oldest first
+	syncRuns      []*db.HumeSyncRun                // oldest first
+	graphJobs     map[uuid.UUID]*graphJob          // by conversation
+}
+
+// Users and voices carry a sequence number alongside their timestamps, so
+// rows written within the same clock tick still sort in write order.
+// Conversations and messages sort by cursor, as they do in Postgres.
+type user struct {
+	db.User
+	seq int64
+}
+
+type conversation struct {
+	db.Conversation
+}
+
+type voice struct {
+	db.Voice
+	seq int64
+}
+
+var _ db.Store = (*Store)(nil)
+
+// New creates an empty store
+func New() *Store {
+	return &Store{
+		users:         make(map[uuid.UUID]*user),
+		conversations: make(map[uuid.UUID]*conversation),
+		messages:      make(map[uuid.UUID][]db.Message),
+		injections:    make(map[uuid.UUID][]db.ContextInjection),
+		voices:        make(map[uuid.UUID]*voice),
+		revisions:     make(map[uuid.UUID][]db.VoiceRevision),
+		graphJobs:     make(map[uuid.UUID]*graphJob),
+	}
+}
+
+// now matches what comes back from a Postgres TIMESTAMP column
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+func (s *Store) next() int64 {
+	s.seq++
+	return s.seq
+}
+
+// cursorLess orders cursors the way Postgres orders (timestamp, uuid) rows
+func cursorLess(a, b db.Cursor) bool {
+	if !a.Time.Equal(b.Time) {
+		return a.Time.Before(b.Time)
+	}
+	return bytes.Compare(a.ID[:], b.ID[:]) < 0
+}
+
+// User methods
+
+func copyUser(u *user) *db.User {
+	out := u.User
+	if u.Name != nil {
+		name := *u.Name
+		out.Name = &name
+	}
+	return &out
+}
+
+func (s *Store) usernameTakenLocked(username string, except uuid.UUID) bool {
+	for id, u := range s.users {
+		if id != except && u.Username == username {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Store) CreateUser(ctx context.Context, username, passwordHash string, name *string, isAdmin bool) (*db.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.usernameTakenLocked(username, uuid.Nil) {
+		return nil, fmt.Errorf("username %q already exists", username)
+	}
+	u := &user{
+		User: db.User{
+			ID:           uuid.New(),
+			Username:     username,
+			PasswordHash: passwordHash,
+			IsAdmin:      isAdmin,
+			CreatedAt:    now(),
+		},
+		seq: s.next(),
+	}
+	if name != nil && *name != "" {
+		n := *name
+		u.Name = &n
+	}
+	s.users[u.ID] = u
+	return copyUser(u), nil
+}
+
+func (s *Store) CreateUserWithID(ctx context.Context, id uuid.UUID, username, passwordHash string, isAdmin bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.usernameTakenLocked(username, id) {
+		return fmt.Errorf("username %q already exists", username)
+	}
+	if u, ok := s.users[id]; ok {
+		u.Username = username
+		u.PasswordHash = passwordHash
+		u.IsAdmin = isAdmin
+		return nil
+	}
+	s.users[id] = &user{
+		User: db.User{
+			ID:           id,
+			Username:     username,
+			PasswordHash: passwordHash,
+			IsAdmin:      isAdmin,
+			CreatedAt:    now(),
+		},
+		seq: s.next(),
+	}
+	return nil
+}
+
+func (s *Store) GetUserByUsername(ctx context.Context, username string) (*db.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.Username == username {
+			return copyUser(u), nil
+		}
+	}
+	return nil, db.ErrNotFound
+}
+
+func (s *Store) GetUserByID(ctx context.Context, id uuid.UUID) (*db.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return nil, db.ErrNotFound
+	}
+	return copyUser(u), nil
+}
+
+func (s *Store) ListUsers(ctx context.Context) ([]db.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sorted := make([]*user, 0, len(s.users))
+	for _, u := range s.users {
+		sorted = append(sorted, u)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].seq > sorted[j].seq })
+
+	var users []db.User
+	for _, u := range sorted {
+		users = append(users, *copyUser(u))
+	}
+	return users, nil
+}
+
+func (s *Store) UpdateUserPassword(ctx context.Context, id uuid.UUID, passwordHash string) error {
+	return s.UpdateUser(ctx, id, &passwordHash, nil, nil)
+}
+
+func (s *Store) UpdateUserAdmin(ctx context.Context, id uuid.UUID, isAdmin bool) error {
+	return s.UpdateUser(ctx, id, nil, nil, &isAdmin)
+}
+
+func (s *Store) UpdateUser(ctx context.Context, id uuid.UUID, passwordHash *string, name *string, isAdmin *bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return nil
+	}
+	if passwordHash != nil {
+		u.PasswordHash = *passwordHash
+	}
+	if name != nil {
+		// An empty name reads back as no name, as it does from Postgres
+		u.Name = nil
+		if *name != "" {
+			n := *name
+			u.Name = &n
+		}
+	}
+	if isAdmin != nil {
+		u.IsAdmin = *isAdmin
+	}
+	return nil
+}
+
+func (s *Store) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.users, id)
+	for convID, c := range s.conversations {
+		if c.UserID == id {
+			s.deleteConversationLocked(convID)
+		}
+	}
+	for _, p := range s.previews {
+		if p.CreatedBy != nil && *p.CreatedBy == id {
+			p.CreatedBy = nil
+		}
+	}
+	for _, revisions := range s.revisions {
+		for i := range revisions {
+			if revisions[i].EditedBy != nil && *revisions[i].EditedBy == id {
+				revisions[i].EditedBy = nil
+			}
+		}
+	}
+	for _, run := range s.syncRuns {
+		if run.StartedBy != nil && *run.StartedBy == id {
+			run.StartedBy = nil
+		}
+	}
+	return nil
+}
+
+// Conversation methods
+
+func (s *Store) ownedLocked(id, userID uuid.UUID) (*conversation, bool) {
+	c, ok := s.conversations[id]
+	if !ok || c.UserID != userID {
+		return nil, false
+	}
+	return c, true
+}
+
+func copyConversation(c *conversation) *db.Conversation {
+	out := c.Conversation
+	if c.VoiceID != nil {
+		voiceID := *c.VoiceID
+		out.VoiceID = &voiceID
+	}
+	out.MessageCount = 0
+	return &out
+}
+
+// touchLocked stands in for the updated_at trigger on conversations
+func (s *Store) touchLocked(c *conversation) {
+	c.UpdatedAt = now()
+}
+
+func (s *Store) CreateConversation(ctx context.Context, userID uuid.UUID, title string) (*db.Conversation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[userID]; !ok {
+		return nil, fmt.Errorf("user %s does not exist", userID)
+	}
+	created := now()
+	c := &conversation{
+		Conversation: db.Conversation{
+			ID:        uuid.New(),
+			UserID:    userID,
+			Title:     title,
+			Status:    "active",
+			CreatedAt: created,
+			UpdatedAt: created,
+		},
+	}
+	s.conversations[c.ID] = c
+	return copyConversation(c), nil
+}
+
+func (s *Store) GetConversation(ctx context.Context, id, userID uuid.UUID) (*db.Conversation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.ownedLocked(id, userID)
+	if !ok {
+		return nil, db.ErrNotFound
+	}
+	return copyConversation(c), nil
+}
+
+func (s *Store) ListConversations(ctx context.Context, userID uuid.UUID, limit int, after *db.Cursor) ([]db.Conversation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var owned []*conversation
+	for _, c := range s.conversations {
+		if c.UserID != userID {
+			continue
+		}
+		if after != nil && !cursorLess(db.ConversationCursor(c.Conversation), *after) {
+			continue
+		}
+		owned = append(owned, c)
+	}
+	sort.Slice(owned, func(i, j int) bool {
+		return cursorLess(db.ConversationCursor(owned[j].Conversation), db.ConversationCursor(owned[i].Conversation))
+	})
+	if limit >= 0 && len(owned) > limit {
+		owned = owned[:limit]
+	}
+
+	var conversations []db.Conversation
+	for _, c := range owned {
+		conv := copyConversation(c)
+		conv.MessageCount = len(s.messages[c.ID])
+		conversations = append(conversations, *conv)
+	}
+	return conversations, nil
+}
+
+func (s *Store) UpdateConversationStatus(ctx context.Context, id, userID uuid.UUID, status string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok := s.ownedLocked(id, userID); ok {
+		c.Status = status
+		s.touchLocked(c)
+	}
+	return nil
+}
+
+func (s *Store) SetConversationVoice(ctx context.Context, id, userID, voiceID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.ownedLocked(id, userID)
+	if !ok {
+		return nil
+	}
+	if _, ok := s.voices[voiceID]; !ok {
+		return fmt.Errorf("voice %s does not exist", voiceID)
+	}
+	c.VoiceID = &voiceID
+	s.touchLocked(c)
+	return nil
+}
+
+func (s *Store) GetLastActiveConversation(ctx context.Context, userID uuid.UUID) (*db.Conversation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var last *conversation
+	for _, c := range s.conversations {
+		if c.UserID == userID && c.Status == "active" && (last == nil || cursorLess(db.ConversationCursor(last.Conversation), db.ConversationCursor(c.Conversation))) {
+			last = c
+		}
+	}
+	if last == nil {
+		return nil, db.ErrNotFound
+	}
+	return copyConversation(last), nil
+}
+
+func (s *Store) DeleteConversation(ctx context.Context, id, userID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.ownedLocked(id, userID); ok {
+		s.deleteConversationLocked(id)
+	}
+	return nil
+}
+
+func (s *Store) deleteConversationLocked(id uuid.UUID) {
+	delete(s.conversations, id)
+	delete(s.messages, id)
+	delete(s.injections, id)
+	delete(s.graphJobs, id)
+}
+
+// Message methods
+
+func copyMessage(msg db.Message) db.Message {
+	msg.Emotions = maps.Clone(msg.Emotions)
+	return msg
+}
+
+func (s *Store) AddMessage(ctx context.Context, conversationID uuid.UUID, role, content string, emotions map[string]float64) (*db.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.conversations[conversationID]
+	if !ok {
+		return nil, fmt.Errorf("conversation %s does not exist", conversationID)
+	}
+	msg := db.Message{
+		ID:             uuid.New(),
+		ConversationID: conversationID,
+		Role:           role,
+		Content:        content,
+		Emotions:       maps.Clone(emotions),
+		Timestamp:      now(),
+	}
+	s.messages[conversationID] = append(s.messages[conversationID], msg)
+	s.touchLocked(c)
+	out := copyMessage(msg)
+	return &out, nil
+}
+
+func (s *Store) GetMessages(ctx context.Context, conversationID, userID uuid.UUID) ([]db.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.ownedLocked(conversationID, userID); !ok {
+		return nil, db.ErrNotFound
+	}
+	var messages []db.Message
+	for _, msg := range s.messages[conversationID] {
+		messages = append(messages, copyMessage(msg))
+	}
+	return messages, nil
+}
+
+func (s *Store) ListMessages(ctx context.Context, conversationID, userID uuid.UUID, page db.MessagePage) ([]db.Message, error) {
+	if page.After != nil && page.Before != nil {
+		return nil, fmt.Errorf("a message page can't be both after and before a cursor")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.ownedLocked(conversationID, userID); !ok {
+		return nil, db.ErrNotFound
+	}
+
+	var window []db.Message
+	for _, msg := range s.messages[conversationID] {
+		at := db.MessageCursor(msg)
+		if page.After != nil && !cursorLess(*page.After, at) {
+			continue
+		}
+		if page.Before != nil && !cursorLess(at, *page.Before) {
+			continue
+		}
+		window = append(window, copyMessage(msg))
+	}
+	sort.Slice(window, func(i, j int) bool {
+		return cursorLess(db.MessageCursor(window[i]), db.MessageCursor(window[j]))
+	})
+
+	if len(window) > page.Limit {
+		if page.After != nil {
+			window = window[:page.Limit]
+		} else {
+			window = window[len(window)-page.Limit:]
+		}
+	}
+	if len(window) == 0 {
+		return nil, nil
+	}
+	return window, nil
+}
+
+func (s *Store) CountMessages(ctx context.Context, conversationID uuid.UUID) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.messages[conversationID]), nil
+}
+
+// Context injection methods
+
+func copyInjection(injection db.ContextInjection) db.ContextInjection {
+	if injection.MessageID != nil {
+		messageID := *injection.MessageID
+		injection.MessageID = &messageID
+	}
+	return injection
+}
+
+func (s *Store) LogContextInjection(ctx context.Context, conversationID uuid.UUID, messageID *uuid.UUID, contextText, contextType, reasoning string) (*db.ContextInjection, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.conversations[conversationID]; !ok {
+		return nil, fmt.Errorf("conversation %s does not exist", conversationID)
+	}
+	injection := copyInjection(db.ContextInjection{
+		ID:             uuid.New(),
+		ConversationID: conversationID,
+		MessageID:      messageID,
+		ContextText:    contextText,
+		ContextType:    contextType,
+		Reasoning:      reasoning,
+		CreatedAt:      now(),
+	})
+	s.injections[conversationID] = append(s.injections[conversationID], injection)
+	out := copyInjection(injection)
+	return &out, nil
+}
+
+func (s *Store) ListContextInjections(ctx context.Context, conversationID, userID uuid.UUID) ([]db.ContextInjection, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.ownedLocked(conversationID, userID); !ok {
+		return nil, nil
+	}
+	var injections []db.ContextInjection
+	for _, injection := range s.injections[conversationID] {
+		injections = append(injections, copyInjection(injection))
+	}
+	return injections, nil
+}
+
+// Voice methods
+
+func copyVoice(v *voice) *db.Voice {
+	out := v.Voice
+	if v.HumeConfigVersion != nil {
+		version := *v.HumeConfigVersion
+		out.HumeConfigVersion = &version
+	}
+	if v.HumePromptVersion != nil {
+		version := *v.HumePromptVersion
+		out.HumePromptVersion = &version
+	}
+	return &out
+}
+
+// storedVoice copies the caller's voice the way a row stores it; temperature
+// is a DECIMAL(3,2) column
+func storedVoice(in *db.Voice) db.Voice {
+	v := *copyVoice(&voice{Voice: *in})
+	v.Temperature = math.Round(v.Temperature*100) / 100
+	return v
+}
+
+func (s *Store) CreateVoice(ctx context.Context, in *db.Voice) (*db.Voice, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v := &voice{Voice: storedVoice(in), seq: s.next()}
+	v.ID = uuid.New()
+	v.CreatedAt = now()
+	v.UpdatedAt = v.CreatedAt
+	s.voices[v.ID] = v
+	return copyVoice(v), nil
+}
+
+func (s *Store) GetVoice(ctx context.Context, id uuid.UUID) (*db.Voice, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.voices[id]
+	if !ok {
+		return nil, db.ErrNotFound
+	}
+	return copyVoice(v), nil
+}
+
+func (s *Store) ListVoices(ctx context.Context) ([]db.Voice, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sorted := make([]*voice, 0, len(s.voices))
+	for _, v := range s.voices {
+		sorted = append(sorted, v)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].seq > sorted[j].seq })
+
+	var voices []db.Voice
+	for _, v := range sorted {
+		voices = append(voices, *copyVoice(v))
+	}
+	return voices, nil
+}
+
+func (s *Store) UpdateVoice(ctx context.Context, id uuid.UUID, in *db.Voice) (*db.Voice, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.voices[id]
+	if !ok {
+		return nil, db.ErrNotFound
+	}
+	updated := storedVoice(in)
+	updated.ID = id
+	updated.CreatedAt = v.CreatedAt
+	updated.UpdatedAt = now()
+	v.Voice = updated
+	return copyVoice(v), nil
+}
+
+func (s *Store) DeleteVoice(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.voices, id)
+	delete(s.revisions, id)
+	for _, c := range s.conversations {
+		if c.VoiceID != nil && *c.VoiceID == id {
+			c.VoiceID = nil
+		}
+	}
+	for _, r := range s.replaced {
+		if r.VoiceID != nil && *r.VoiceID == id {
+			r.VoiceID = nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+package memstore_test
+
+import (
+	"testing"
+
+	"github.com/hume-evi/web/internal/db"
+	"github.com/hume-evi/web/internal/db/memstore"
+	"github.com/hume-evi/web/internal/db/storetest"
+)
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) db.Store { return memstore.New() })
+}
@@ -0,0 +1,186 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+
+	"github.com/hume-evi/web/internal/db"
+)
+
+// searchWord is a word of a message, lowercased, with its byte offsets
+type searchWord struct {
+	text       string
+	start, end int
+}
+
+func searchWords(text string) []searchWord {
+	var words []searchWord
+	start := -1
+	for i, r := range text + " " {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if inWord && start < 0 {
+			start = i
+		} else if !inWord && start >= 0 {
+			words = append(words, searchWord{text: strings.ToLower(text[start:i]), start: start, end: i})
+			start = -1
+		}
+	}
+	return words
+}
+
+// searchTerm is a word or quoted phrase of a query
+type searchTerm struct {
+	words   []string
+	negated bool
+}
+
+// parseSearch reads web search syntax into clauses that must all hold; a
+// clause holds when any of its terms, joined by OR, does
+func parseSearch(query string) [][]searchTerm {
+	var clauses [][]searchTerm
+	or := false
+	for query = strings.TrimSpace(query); query != ""; query = strings.TrimSpace(query) {
+		negated := false
+		if len(query) > 1 && query[0] == '-' {
+			negated = true
+			query = query[1:]
+		}
+
+		var token string
+		quoted := query[0] == '"'
+		if quoted {
+			query = query[1:]
+			end := strings.IndexByte(query, '"')
+			if end < 0 {
+				end = len(query)
+			}
+			token, query = query[:end], strings.TrimPrefix(query[end:], `"`)
+		} else {
+			end := strings.IndexFunc(query, unicode.IsSpace)
+			if end < 0 {
+				end = len(query)
+			}
+			token, query = query[:end], query[end:]
+		}
+
+		if token == "OR" && !quoted && !negated {
+			or = len(clauses) > 0
+			continue
+		}
+		term := searchTerm{negated: negated}
+		for _, word := range searchWords(token) {
+			term.words = append(term.words, word.text)
+		}
+		if len(term.words) == 0 {
+			continue
+		}
+		if or {
+			clauses[len(clauses)-1] = append(clauses[len(clauses)-1], term)
+		} else {
+			clauses = append(clauses, []searchTerm{term})
+		}
+		or = false
+	}
+	return clauses
+}
+
+// matchTerm returns the index of every word where the term starts
+func matchTerm(words []searchWord, term searchTerm) []int {
+	var starts []int
+	for i := 0; i+len(term.words) <= len(words); i++ {
+		matched := true
+		for k, word := range term.words {
+			if words[i+k].text != word {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			starts = append(starts, i)
+		}
+	}
+	return starts
+}
+
+// SearchMessages approximates Postgres full-text search: words match
+// exactly, ignoring case, with no stemming or stop words. Rank is the number
+// of matches and the snippet is the whole message.
+func (s *Store) SearchMessages(ctx context.Context, userID uuid.UUID, query string, limit int) ([]db.SearchHit, error) {
+	clauses := parseSearch(query)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var hits []db.SearchHit
+	for convID, c := range s.conversations {
+		if c.UserID != userID {
+			continue
+		}
+		for _, msg := range s.messages[convID] {
+			words := searchWords(msg.Content)
+			highlighted := make([]bool, len(words))
+			matches := 0
+			holds := len(clauses) > 0
+			for _, clause := range clauses {
+				clauseHolds := false
+				for _, term := range clause {
+					starts := matchTerm(words, term)
+					if term.negated {
+						clauseHolds = clauseHolds || len(starts) == 0
+						continue
+					}
+					clauseHolds = clauseHolds || len(starts) > 0
+					matches += len(starts)
+					for _, start := range starts {
+						for k := range term.words {
+							highlighted[start+k] = true
+						}
+					}
+				}
+				holds = holds && clauseHolds
+			}
+			if !holds || matches == 0 {
+				continue
+			}
+
+			hit := db.SearchHit{
+				MessageID:         msg.ID,
+				ConversationID:    convID,
+				ConversationTitle: c.Title,
+				Role:              msg.Role,
+				Timestamp:         msg.Timestamp,
+				Rank:              float64(matches),
+			}
+			last := 0
+			for i, word := range words {
+				if !highlighted[i] {
+					continue
+				}
+				if word.start > last {
+					hit.Snippet = append(hit.Snippet, db.SnippetPart{Text: msg.Content[last:word.start]})
+				}
+				hit.Snippet = append(hit.Snippet, db.SnippetPart{Text: msg.Content[word.start:word.end], Match: true})
+				last = word.end
+			}
+			if last < len(msg.Content) {
+				hit.Snippet = append(hit.Snippet, db.SnippetPart{Text: msg.Content[last:]})
+			}
+			hits = append(hits, hit)
+		}
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Rank != hits[j].Rank {
+			return hits[i].Rank > hits[j].Rank
+		}
+		return hits[i].Timestamp.After(hits[j].Timestamp)
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
@@ -0,0 +1,87 @@
+package memstore
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/hume-evi/web/internal/db"
+)
+
+func copyPreview(p *db.VoicePreview, withAudio bool) *db.VoicePreview {
+	out := *p
+	out.Audio = nil
+	if withAudio {
+		out.Audio = bytes.Clone(p.Audio)
+	}
+	if p.CreatedBy != nil {
+		createdBy := *p.CreatedBy
+		out.CreatedBy = &createdBy
+	}
+	return &out
+}
+
+func (s *Store) CreateVoicePreview(ctx context.Context, in *db.VoicePreview) (*db.VoicePreview, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := copyPreview(in, true)
+	p.ID = uuid.New()
+	p.SavedVoiceID = ""
+	p.CreatedAt = now()
+	s.previews = append(s.previews, p)
+	return copyPreview(p, true), nil
+}
+
+func (s *Store) ListVoicePreviews(ctx context.Context, cacheKey string) ([]db.VoicePreview, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var previews []db.VoicePreview
+	for _, p := range s.previews {
+		if p.CacheKey == cacheKey {
+			previews = append(previews, *copyPreview(p, false))
+		}
+	}
+	return previews, nil
+}
+
+func (s *Store) GetVoicePreview(ctx context.Context, id uuid.UUID) (*db.VoicePreview, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, p := range s.previews {
+		if p.ID == id {
+			return copyPreview(p, true), nil
+		}
+	}
+	return nil, db.ErrNotFound
+}
+
+func (s *Store) MarkVoicePreviewSaved(ctx context.Context, id uuid.UUID, humeVoiceID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, p := range s.previews {
+		if p.ID == id {
+			p.SavedVoiceID = humeVoiceID
+		}
+	}
+	return nil
+}
+
+func (s *Store) DeleteVoicePreviews(ctx context.Context, cacheKey string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.previews[:0]
+	for _, p := range s.previews {
+		if p.CacheKey != cacheKey {
+			kept = append(kept, p)
+		}
+	}
+	clear(s.previews[len(kept):])
+	s.previews = kept
+	return nil
+}
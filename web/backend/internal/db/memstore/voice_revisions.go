@@ -0,0 +1,101 @@
+package memstore
+
+import (
+	"context"
+	"math"
+
+	"github.com/google/uuid"
+
+	"github.com/hume-evi/web/internal/db"
+)
+
+func copyRevision(rev db.VoiceRevision) *db.VoiceRevision {
+	if rev.HumePromptVersion != nil {
+		version := *rev.HumePromptVersion
+		rev.HumePromptVersion = &version
+	}
+	if rev.HumeConfigVersion != nil {
+		version := *rev.HumeConfigVersion
+		rev.HumeConfigVersion = &version
+	}
+	if rev.EditedBy != nil {
+		editedBy := *rev.EditedBy
+		rev.EditedBy = &editedBy
+	}
+	return &rev
+}
+
+func (s *Store) RecordVoiceRevision(ctx context.Context, voice *db.Voice, change db.VoiceChange) (*db.VoiceRevision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.voices[voice.ID]; !ok {
+		return nil, db.ErrNotFound
+	}
+	revisions := s.revisions[voice.ID]
+	rev := copyRevision(db.VoiceRevision{
+		ID:                    uuid.New(),
+		VoiceID:               voice.ID,
+		Revision:              len(revisions) + 1,
+		Action:                change.Action,
+		Note:                  change.Note,
+		Name:                  voice.Name,
+		Description:           voice.Description,
+		Prompt:                voice.Prompt,
+		VoiceDescription:      voice.VoiceDescription,
+		EVIVersion:            voice.EVIVersion,
+		LanguageModelProvider: voice.LanguageModelProvider,
+		LanguageModelResource: voice.LanguageModelResource,
+		Temperature:           math.Round(voice.Temperature*100) / 100,
+		GreetingMessage:       voice.GreetingMessage,
+		InactivityMessage:     voice.InactivityMessage,
+		MaxDurationMessage:    voice.MaxDurationMessage,
+		InactivityTimeoutSecs: voice.InactivityTimeoutSecs,
+		MaxDurationSecs:       voice.MaxDurationSecs,
+		HumeVoiceID:           voice.HumeVoiceID,
+		HumeVoiceProvider:     voice.HumeVoiceProvider,
+		HumeVoiceName:         voice.HumeVoiceName,
+		HumePromptID:          voice.HumePromptID,
+		HumePromptVersion:     voice.HumePromptVersion,
+		HumeConfigID:          voice.HumeConfigID,
+		HumeConfigVersion:     voice.HumeConfigVersion,
+		EditedBy:              change.EditedBy,
+		CreatedAt:             now(),
+	})
+	s.revisions[voice.ID] = append(revisions, *rev)
+	return copyRevision(*rev), nil
+}
+
+func (s *Store) ListVoiceRevisions(ctx context.Context, voiceID uuid.UUID) ([]db.VoiceRevision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var revisions []db.VoiceRevision
+	stored := s.revisions[voiceID]
+	for i := len(stored) - 1; i >= 0; i-- {
+		revisions = append(revisions, *copyRevision(stored[i]))
+	}
+	return revisions, nil
+}
+
+func (s *Store) GetVoiceRevision(ctx context.Context, voiceID uuid.UUID, revision int) (*db.VoiceRevision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := s.revisions[voiceID]
+	if revision < 1 || revision > len(stored) {
+		return nil, db.ErrNotFound
+	}
+	return copyRevision(stored[revision-1]), nil
+}
+
+func (s *Store) GetLatestVoiceRevision(ctx context.Context, voiceID uuid.UUID) (*db.VoiceRevision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := s.revisions[voiceID]
+	if len(stored) == 0 {
+		return nil, db.ErrNotFound
+	}
+	return copyRevision(stored[len(stored)-1]), nil
+}
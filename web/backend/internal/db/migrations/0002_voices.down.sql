@@ -0,0 +1,2 @@
+ALTER TABLE conversations DROP COLUMN IF EXISTS voice_id;
+DROP TABLE IF EXISTS voices;
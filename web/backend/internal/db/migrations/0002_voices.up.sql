@@ -0,0 +1,28 @@
+-- Voice personas, each backed by a Hume EVI config
+CREATE TABLE IF NOT EXISTS voices (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	name VARCHAR(255) NOT NULL,
+	description TEXT,
+	prompt TEXT NOT NULL,
+	voice_description TEXT,
+	hume_voice_id VARCHAR(255),
+	hume_config_id VARCHAR(255),
+	evi_version VARCHAR(10) DEFAULT '3',
+	language_model_provider VARCHAR(50) DEFAULT 'ANTHROPIC',
+	language_model_resource VARCHAR(100) DEFAULT 'claude-3-7-sonnet-latest',
+	temperature DECIMAL(3,2) DEFAULT 1.0,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_voices_name ON voices(name);
+CREATE INDEX IF NOT EXISTS idx_voices_hume_config_id ON voices(hume_config_id);
+
+DROP TRIGGER IF EXISTS update_voices_updated_at ON voices;
+CREATE TRIGGER update_voices_updated_at
+	BEFORE UPDATE ON voices
+	FOR EACH ROW
+	EXECUTE FUNCTION update_updated_at_column();
+
+-- Record which voice a conversation was held with
+ALTER TABLE conversations ADD COLUMN IF NOT EXISTS voice_id UUID REFERENCES voices(id) ON DELETE SET NULL;
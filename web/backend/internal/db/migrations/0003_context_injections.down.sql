@@ -0,0 +1 @@
+DROP TABLE IF EXISTS context_injections;
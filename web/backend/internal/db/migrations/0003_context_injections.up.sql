@@ -0,0 +1,12 @@
+-- Audit log of context injected into EVI sessions by the proxy
+CREATE TABLE IF NOT EXISTS context_injections (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	conversation_id UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+	message_id UUID REFERENCES messages(id) ON DELETE SET NULL,
+	context_text TEXT NOT NULL,
+	context_type VARCHAR(20) NOT NULL,
+	reasoning TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_context_injections_conversation_id ON context_injections(conversation_id);
@@ -0,0 +1,16 @@
+-- Queue of post-conversation knowledge graph extraction jobs
+CREATE TABLE IF NOT EXISTS graph_extraction_jobs (
+	conversation_id UUID PRIMARY KEY REFERENCES conversations(id) ON DELETE CASCADE,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	status VARCHAR(20) NOT NULL DEFAULT 'pending',
+	attempts INT NOT NULL DEFAULT 0,
+	last_error TEXT,
+	entities INT NOT NULL DEFAULT 0,
+	relationships INT NOT NULL DEFAULT 0,
+	next_attempt_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	completed_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_graph_extraction_jobs_due ON graph_extraction_jobs(status, next_attempt_at);
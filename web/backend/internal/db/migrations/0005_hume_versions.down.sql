@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS replaced_hume_configs;
+ALTER TABLE voices DROP COLUMN IF EXISTS hume_prompt_version;
+ALTER TABLE voices DROP COLUMN IF EXISTS hume_prompt_id;
+ALTER TABLE voices DROP COLUMN IF EXISTS hume_config_version;
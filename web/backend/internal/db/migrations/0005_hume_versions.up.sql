@@ -0,0 +1,17 @@
+-- Track the Hume prompt and config versions each voice is synced to
+ALTER TABLE voices ADD COLUMN IF NOT EXISTS hume_config_version INT;
+ALTER TABLE voices ADD COLUMN IF NOT EXISTS hume_prompt_id VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE voices ADD COLUMN IF NOT EXISTS hume_prompt_version INT;
+
+-- Hume configs a voice no longer uses, kept so they can be deleted from Hume
+CREATE TABLE IF NOT EXISTS replaced_hume_configs (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	voice_id UUID REFERENCES voices(id) ON DELETE SET NULL,
+	hume_config_id VARCHAR(255) NOT NULL,
+	replaced_by VARCHAR(255),
+	reason TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	cleaned_up_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_replaced_hume_configs_pending ON replaced_hume_configs(created_at) WHERE cleaned_up_at IS NULL;
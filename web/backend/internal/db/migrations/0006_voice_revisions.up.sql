@@ -0,0 +1,30 @@
+-- Snapshot of a voice after every create, update, sync and rollback
+CREATE TABLE IF NOT EXISTS voice_revisions (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	voice_id UUID NOT NULL REFERENCES voices(id) ON DELETE CASCADE,
+	revision INT NOT NULL,
+	action VARCHAR(20) NOT NULL,
+	note TEXT,
+	name VARCHAR(255) NOT NULL,
+	description TEXT,
+	prompt TEXT NOT NULL,
+	voice_description TEXT,
+	evi_version VARCHAR(10),
+	language_model_provider VARCHAR(50),
+	language_model_resource VARCHAR(100),
+	temperature DECIMAL(3,2),
+	hume_voice_id VARCHAR(255),
+	hume_prompt_id VARCHAR(255),
+	hume_prompt_version INT,
+	hume_config_id VARCHAR(255),
+	hume_config_version INT,
+	edited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (voice_id, revision)
+);
+
+-- Voices created before revisions were tracked start from their current state
+INSERT INTO voice_revisions (voice_id, revision, action, note, name, description, prompt, voice_description, evi_version, language_model_provider, language_model_resource, temperature, hume_voice_id, hume_prompt_id, hume_prompt_version, hume_config_id, hume_config_version)
+SELECT v.id, 1, 'create', 'baseline', v.name, v.description, v.prompt, v.voice_description, v.evi_version, v.language_model_provider, v.language_model_resource, v.temperature, v.hume_voice_id, v.hume_prompt_id, v.hume_prompt_version, v.hume_config_id, v.hume_config_version
+FROM voices v
+WHERE NOT EXISTS (SELECT 1 FROM voice_revisions r WHERE r.voice_id = v.id);
@@ -0,0 +1,4 @@
+ALTER TABLE voice_revisions DROP COLUMN IF EXISTS hume_voice_name;
+ALTER TABLE voice_revisions DROP COLUMN IF EXISTS hume_voice_provider;
+ALTER TABLE voices DROP COLUMN IF EXISTS hume_voice_name;
+ALTER TABLE voices DROP COLUMN IF EXISTS hume_voice_provider;
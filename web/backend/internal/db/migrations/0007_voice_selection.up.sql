@@ -0,0 +1,11 @@
+-- Which Hume voice a voice's EVI config speaks with
+ALTER TABLE voices ADD COLUMN IF NOT EXISTS hume_voice_provider VARCHAR(20) NOT NULL DEFAULT '';
+ALTER TABLE voices ADD COLUMN IF NOT EXISTS hume_voice_name VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE voice_revisions ADD COLUMN IF NOT EXISTS hume_voice_provider VARCHAR(20);
+ALTER TABLE voice_revisions ADD COLUMN IF NOT EXISTS hume_voice_name VARCHAR(255);
+
+-- Voices designed before the choice was recorded speak with their custom voice
+UPDATE voices SET hume_voice_provider = 'CUSTOM_VOICE'
+WHERE COALESCE(hume_voice_id, '') <> '' AND hume_voice_provider = '';
+UPDATE voice_revisions SET hume_voice_provider = 'CUSTOM_VOICE'
+WHERE COALESCE(hume_voice_id, '') <> '' AND hume_voice_provider IS NULL;
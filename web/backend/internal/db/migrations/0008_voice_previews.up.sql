@@ -0,0 +1,16 @@
+-- Cached TTS generations for voice design previews, keyed by a hash of the
+-- description and sample text
+CREATE TABLE IF NOT EXISTS voice_previews (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	cache_key CHAR(64) NOT NULL,
+	description TEXT NOT NULL,
+	sample_text TEXT NOT NULL,
+	generation_id VARCHAR(255) NOT NULL,
+	mime_type VARCHAR(50) NOT NULL,
+	audio BYTEA NOT NULL,
+	saved_voice_id VARCHAR(255),
+	created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_voice_previews_cache_key ON voice_previews(cache_key, created_at);
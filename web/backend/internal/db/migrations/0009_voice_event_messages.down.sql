@@ -0,0 +1,10 @@
+ALTER TABLE voice_revisions DROP COLUMN IF EXISTS max_duration_secs;
+ALTER TABLE voice_revisions DROP COLUMN IF EXISTS inactivity_timeout_secs;
+ALTER TABLE voice_revisions DROP COLUMN IF EXISTS max_duration_message;
+ALTER TABLE voice_revisions DROP COLUMN IF EXISTS inactivity_message;
+ALTER TABLE voice_revisions DROP COLUMN IF EXISTS greeting_message;
+ALTER TABLE voices DROP COLUMN IF EXISTS max_duration_secs;
+ALTER TABLE voices DROP COLUMN IF EXISTS inactivity_timeout_secs;
+ALTER TABLE voices DROP COLUMN IF EXISTS max_duration_message;
+ALTER TABLE voices DROP COLUMN IF EXISTS inactivity_message;
+ALTER TABLE voices DROP COLUMN IF EXISTS greeting_message;
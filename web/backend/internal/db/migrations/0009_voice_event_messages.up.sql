@@ -0,0 +1,11 @@
+-- Per-voice EVI event messages and timeouts; 0 seconds keeps Hume's default
+ALTER TABLE voices ADD COLUMN IF NOT EXISTS greeting_message TEXT NOT NULL DEFAULT '';
+ALTER TABLE voices ADD COLUMN IF NOT EXISTS inactivity_message TEXT NOT NULL DEFAULT '';
+ALTER TABLE voices ADD COLUMN IF NOT EXISTS max_duration_message TEXT NOT NULL DEFAULT '';
+ALTER TABLE voices ADD COLUMN IF NOT EXISTS inactivity_timeout_secs INT NOT NULL DEFAULT 0;
+ALTER TABLE voices ADD COLUMN IF NOT EXISTS max_duration_secs INT NOT NULL DEFAULT 0;
+ALTER TABLE voice_revisions ADD COLUMN IF NOT EXISTS greeting_message TEXT NOT NULL DEFAULT '';
+ALTER TABLE voice_revisions ADD COLUMN IF NOT EXISTS inactivity_message TEXT NOT NULL DEFAULT '';
+ALTER TABLE voice_revisions ADD COLUMN IF NOT EXISTS max_duration_message TEXT NOT NULL DEFAULT '';
+ALTER TABLE voice_revisions ADD COLUMN IF NOT EXISTS inactivity_timeout_secs INT NOT NULL DEFAULT 0;
+ALTER TABLE voice_revisions ADD COLUMN IF NOT EXISTS max_duration_secs INT NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS hume_sync_runs;
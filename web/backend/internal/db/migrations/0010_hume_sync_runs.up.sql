@@ -0,0 +1,18 @@
+-- Bulk syncs and drift reconciliations against Hume; the partial unique index
+-- allows one running run of each kind across replicas
+CREATE TABLE IF NOT EXISTS hume_sync_runs (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	kind VARCHAR(20) NOT NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'running',
+	heal BOOLEAN NOT NULL DEFAULT FALSE,
+	total INT NOT NULL DEFAULT 0,
+	processed INT NOT NULL DEFAULT 0,
+	results JSONB NOT NULL DEFAULT '[]',
+	error TEXT,
+	started_by UUID REFERENCES users(id) ON DELETE SET NULL,
+	started_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	finished_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_hume_sync_runs_kind ON hume_sync_runs(kind, started_at DESC);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_hume_sync_runs_running ON hume_sync_runs(kind) WHERE status = 'running';
@@ -0,0 +1,4 @@
+-- Keyset pagination walks conversations by (updated_at, id) within a user and
+-- messages by (timestamp, id) within a conversation
+CREATE INDEX idx_conversations_user_updated ON conversations(user_id, updated_at DESC, id DESC);
+CREATE INDEX idx_messages_conversation_timestamp ON messages(conversation_id, timestamp, id);
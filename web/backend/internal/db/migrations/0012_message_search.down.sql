@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS update_messages_content_tsv ON messages;
+DROP FUNCTION IF EXISTS update_messages_content_tsv();
+DROP INDEX IF EXISTS idx_messages_content_tsv;
+ALTER TABLE messages DROP COLUMN IF EXISTS content_tsv;
@@ -0,0 +1,20 @@
+-- Full-text search over message content. The trigger keeps content_tsv in
+-- step with content; existing messages are indexed here.
+ALTER TABLE messages ADD COLUMN content_tsv tsvector;
+
+UPDATE messages SET content_tsv = to_tsvector('english', content);
+
+CREATE INDEX idx_messages_content_tsv ON messages USING GIN (content_tsv);
+
+CREATE FUNCTION update_messages_content_tsv()
+RETURNS TRIGGER AS $$
+BEGIN
+	NEW.content_tsv = to_tsvector('english', COALESCE(NEW.content, ''));
+	RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER update_messages_content_tsv
+	BEFORE INSERT OR UPDATE OF content ON messages
+	FOR EACH ROW
+	EXECUTE FUNCTION update_messages_content_tsv();
@@ -0,0 +1,3 @@
+-- Hume prosody scores for each message, keyed by expression name
+-- (e.g. {"Anxiety": 0.42}); NULL when EVI didn't send any
+ALTER TABLE messages ADD COLUMN emotions JSONB;
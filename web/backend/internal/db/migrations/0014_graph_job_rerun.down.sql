@@ -0,0 +1 @@
+ALTER TABLE graph_extraction_jobs DROP COLUMN IF EXISTS rerun;
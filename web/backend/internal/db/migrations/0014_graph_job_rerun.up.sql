@@ -0,0 +1,3 @@
+-- Set when a conversation is enqueued again while its extraction is running,
+-- so the job runs once more after that run finishes
+ALTER TABLE graph_extraction_jobs ADD COLUMN rerun BOOLEAN NOT NULL DEFAULT false;
@@ -1,6 +0,0 @@
--- Add is_admin column to users table
-ALTER TABLE users ADD COLUMN IF NOT EXISTS is_admin BOOLEAN DEFAULT FALSE;
-
--- Create index for admin lookups
-CREATE INDEX IF NOT EXISTS idx_users_is_admin ON users(is_admin);
-
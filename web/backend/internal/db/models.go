@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
 type User struct {
@@ -20,13 +21,14 @@ type User struct {
 }
 
 type Conversation struct {
-	ID        uuid.UUID `json:"id"`
-	UserID    uuid.UUID `json:"user_id"`
-	Title     string    `json:"title"`
-	Status    string    `json:"status"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	MessageCount int    `json:"message_count,omitempty"`
+	ID        uuid.UUID  `json:"id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	Title     string     `json:"title"`
+	Status    string     `json:"status"`
+	VoiceID   *uuid.UUID `json:"voice_id,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	MessageCount int     `json:"message_count,omitempty"`
 }
 
 type Message struct {
@@ -34,7 +36,20 @@ type Message struct {
 	ConversationID uuid.UUID `json:"conversation_id"`
 	Role           string    `json:"role"`
 	Content        string    `json:"content"`
-	Timestamp      time.Time `json:"timestamp"`
+	// Emotions holds the Hume prosody scores EVI measured for the message,
+	// all of them, keyed by expression name; nil when there were none
+	Emotions  map[string]float64 `json:"emotions,omitempty"`
+	Timestamp time.Time          `json:"timestamp"`
+}
+
+type ContextInjection struct {
+	ID             uuid.UUID  `json:"id"`
+	ConversationID uuid.UUID  `json:"conversation_id"`
+	MessageID      *uuid.UUID `json:"message_id,omitempty"`
+	ContextText    string     `json:"context_text"`
+	ContextType    string     `json:"context_type"`
+	Reasoning      string     `json:"reasoning"`
+	CreatedAt      time.Time  `json:"created_at"`
 }
 
 type Voice struct {
@@ -44,11 +59,27 @@ type Voice struct {
 	Prompt                string    `json:"prompt"`
 	VoiceDescription      string    `json:"voice_description"`
 	HumeVoiceID           string    `json:"hume_voice_id"`
+	// HumeVoiceProvider is CUSTOM_VOICE for a designed voice or HUME_AI for
+	// the voice library; library voices may be chosen by HumeVoiceName
+	HumeVoiceProvider     string    `json:"hume_voice_provider"`
+	HumeVoiceName         string    `json:"hume_voice_name"`
 	HumeConfigID          string    `json:"hume_config_id"`
+	// Hume versions are numbered from 0; nil means not yet known
+	HumeConfigVersion     *int      `json:"hume_config_version"`
+	HumePromptID          string    `json:"hume_prompt_id"`
+	HumePromptVersion     *int      `json:"hume_prompt_version"`
 	EVIVersion            string    `json:"evi_version"`
 	LanguageModelProvider string    `json:"language_model_provider"`
 	LanguageModelResource string    `json:"language_model_resource"`
 	Temperature           float64   `json:"temperature"`
+	// Event messages are sent by EVI when a chat starts or times out; an
+	// empty message leaves that event disabled
+	GreetingMessage       string    `json:"greeting_message"`
+	InactivityMessage     string    `json:"inactivity_message"`
+	MaxDurationMessage    string    `json:"max_duration_message"`
+	// Timeouts in seconds; 0 keeps Hume's default
+	InactivityTimeoutSecs int       `json:"inactivity_timeout_secs"`
+	MaxDurationSecs       int       `json:"max_duration_secs"`
 	CreatedAt             time.Time `json:"created_at"`
 	UpdatedAt             time.Time `json:"updated_at"`
 }
@@ -199,31 +230,39 @@ func (db *DB) DeleteUser(ctx context.Context, id uuid.UUID) error {
 func (db *DB) CreateConversation(ctx context.Context, userID uuid.UUID, title string) (*Conversation, error) {
 	var conv Conversation
 	err := db.Pool.QueryRow(ctx,
-		`INSERT INTO conversations (user_id, title) VALUES ($1, $2) RETURNING id, user_id, title, status, created_at, updated_at`,
+		`INSERT INTO conversations (user_id, title) VALUES ($1, $2) RETURNING id, user_id, title, status, voice_id, created_at, updated_at`,
 		userID, title,
-	).Scan(&conv.ID, &conv.UserID, &conv.Title, &conv.Status, &conv.CreatedAt, &conv.UpdatedAt)
+	).Scan(&conv.ID, &conv.UserID, &conv.Title, &conv.Status, &conv.VoiceID, &conv.CreatedAt, &conv.UpdatedAt)
 	return &conv, err
 }
 
 func (db *DB) GetConversation(ctx context.Context, id, userID uuid.UUID) (*Conversation, error) {
 	var conv Conversation
 	err := db.Pool.QueryRow(ctx,
-		`SELECT id, user_id, title, status, created_at, updated_at FROM conversations WHERE id = $1 AND user_id = $2`,
+		`SELECT id, user_id, title, status, voice_id, created_at, updated_at FROM conversations WHERE id = $1 AND user_id = $2`,
 		id, userID,
-	).Scan(&conv.ID, &conv.UserID, &conv.Title, &conv.Status, &conv.CreatedAt, &conv.UpdatedAt)
+	).Scan(&conv.ID, &conv.UserID, &conv.Title, &conv.Status, &conv.VoiceID, &conv.CreatedAt, &conv.UpdatedAt)
 	return &conv, err
 }
 
-func (db *DB) ListConversations(ctx context.Context, userID uuid.UUID, limit int) ([]Conversation, error) {
+// ListConversations returns up to limit conversations, most recently
+// updated first, starting after the after cursor when it is set
+func (db *DB) ListConversations(ctx context.Context, userID uuid.UUID, limit int, after *Cursor) ([]Conversation, error) {
+	var afterTime *time.Time
+	var afterID *uuid.UUID
+	if after != nil {
+		afterTime, afterID = &after.Time, &after.ID
+	}
+
 	rows, err := db.Pool.Query(ctx,
-		`SELECT c.id, c.user_id, c.title, c.status, c.created_at, c.updated_at, COUNT(m.id) as message_count
+		`SELECT c.id, c.user_id, c.title, c.status, c.voice_id, c.created_at, c.updated_at, COUNT(m.id) as message_count
 		 FROM conversations c
 		 LEFT JOIN messages m ON c.id = m.conversation_id
-		 WHERE c.user_id = $1
+		 WHERE c.user_id = $1 AND ($3::timestamp IS NULL OR (c.updated_at, c.id) < ($3::timestamp, $4::uuid))
 		 GROUP BY c.id
-		 ORDER BY c.updated_at DESC
+		 ORDER BY c.updated_at DESC, c.id DESC
 		 LIMIT $2`,
-		userID, limit,
+		userID, limit, afterTime, afterID,
 	)
 	if err != nil {
 		return nil, err
@@ -233,7 +272,7 @@ func (db *DB) ListConversations(ctx context.Context, userID uuid.UUID, limit int
 	var conversations []Conversation
 	for rows.Next() {
 		var conv Conversation
-		err := rows.Scan(&conv.ID, &conv.UserID, &conv.Title, &conv.Status, &conv.CreatedAt, &conv.UpdatedAt, &conv.MessageCount)
+		err := rows.Scan(&conv.ID, &conv.UserID, &conv.Title, &conv.Status, &conv.VoiceID, &conv.CreatedAt, &conv.UpdatedAt, &conv.MessageCount)
 		if err != nil {
 			return nil, err
 		}
@@ -250,14 +289,23 @@ func (db *DB) UpdateConversationStatus(ctx context.Context, id, userID uuid.UUID
 	return err
 }
 
+// SetConversationVoice records the voice used for a conversation
+func (db *DB) SetConversationVoice(ctx context.Context, id, userID, voiceID uuid.UUID) error {
+	_, err := db.Pool.Exec(ctx,
+		`UPDATE conversations SET voice_id = $1 WHERE id = $2 AND user_id = $3`,
+		voiceID, id, userID,
+	)
+	return err
+}
+
 func (db *DB) GetLastActiveConversation(ctx context.Context, userID uuid.UUID) (*Conversation, error) {
 	var conv Conversation
 	err := db.Pool.QueryRow(ctx,
-		`SELECT id, user_id, title, status, created_at, updated_at FROM conversations 
+		`SELECT id, user_id, title, status, voice_id, created_at, updated_at FROM conversations 
 		 WHERE user_id = $1 AND status = 'active' 
 		 ORDER BY updated_at DESC LIMIT 1`,
 		userID,
-	).Scan(&conv.ID, &conv.UserID, &conv.Title, &conv.Status, &conv.CreatedAt, &conv.UpdatedAt)
+	).Scan(&conv.ID, &conv.UserID, &conv.Title, &conv.Status, &conv.VoiceID, &conv.CreatedAt, &conv.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -273,12 +321,12 @@ func (db *DB) DeleteConversation(ctx context.Context, id, userID uuid.UUID) erro
 }
 
 // Message methods
-func (db *DB) AddMessage(ctx context.Context, conversationID uuid.UUID, role, content string) (*Message, error) {
+func (db *DB) AddMessage(ctx context.Context, conversationID uuid.UUID, role, content string, emotions map[string]float64) (*Message, error) {
 	var msg Message
 	err := db.Pool.QueryRow(ctx,
-		`INSERT INTO messages (conversation_id, role, content) VALUES ($1, $2, $3) RETURNING id, conversation_id, role, content, timestamp`,
-		conversationID, role, content,
-	).Scan(&msg.ID, &msg.ConversationID, &msg.Role, &msg.Content, &msg.Timestamp)
+		`INSERT INTO messages (conversation_id, role, content, emotions) VALUES ($1, $2, $3, $4) RETURNING id, conversation_id, role, content, emotions, timestamp`,
+		conversationID, role, content, emotions,
+	).Scan(&msg.ID, &msg.ConversationID, &msg.Role, &msg.Content, &msg.Emotions, &msg.Timestamp)
 	
 	// Update conversation updated_at
 	_, _ = db.Pool.Exec(ctx,
@@ -301,7 +349,7 @@ func (db *DB) GetMessages(ctx context.Context, conversationID, userID uuid.UUID)
 	}
 
 	rows, err := db.Pool.Query(ctx,
-		`SELECT id, conversation_id, role, content, timestamp FROM messages 
+		`SELECT id, conversation_id, role, content, emotions, timestamp FROM messages 
 		 WHERE conversation_id = $1 ORDER BY timestamp ASC`,
 		conversationID,
 	)
@@ -313,7 +361,7 @@ func (db *DB) GetMessages(ctx context.Context, conversationID, userID uuid.UUID)
 	var messages []Message
 	for rows.Next() {
 		var msg Message
-		err := rows.Scan(&msg.ID, &msg.ConversationID, &msg.Role, &msg.Content, &msg.Timestamp)
+		err := rows.Scan(&msg.ID, &msg.ConversationID, &msg.Role, &msg.Content, &msg.Emotions, &msg.Timestamp)
 		if err != nil {
 			return nil, err
 		}
@@ -322,31 +370,155 @@ func (db *DB) GetMessages(ctx context.Context, conversationID, userID uuid.UUID)
 	return messages, rows.Err()
 }
 
+// ListMessages returns one page of a conversation's messages, oldest first,
+// or pgx.ErrNoRows if the user doesn't own the conversation
+func (db *DB) ListMessages(ctx context.Context, conversationID, userID uuid.UUID, page MessagePage) ([]Message, error) {
+	if page.After != nil && page.Before != nil {
+		return nil, fmt.Errorf("a message page can't be both after and before a cursor")
+	}
+
+	var convID uuid.UUID
+	err := db.Pool.QueryRow(ctx,
+		`SELECT id FROM conversations WHERE id = $1 AND user_id = $2`,
+		conversationID, userID,
+	).Scan(&convID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pages before a cursor, or the newest page, are read newest first so
+	// LIMIT keeps the messages nearest the cursor, then put back in order
+	var rows pgx.Rows
+	if page.After != nil {
+		rows, err = db.Pool.Query(ctx,
+			`SELECT id, conversation_id, role, content, emotions, timestamp FROM messages
+			 WHERE conversation_id = $1 AND (timestamp, id) > ($3::timestamp, $4::uuid)
+			 ORDER BY timestamp ASC, id ASC LIMIT $2`,
+			conversationID, page.Limit, page.After.Time, page.After.ID,
+		)
+	} else {
+		var beforeTime *time.Time
+		var beforeID *uuid.UUID
+		if page.Before != nil {
+			beforeTime, beforeID = &page.Before.Time, &page.Before.ID
+		}
+		rows, err = db.Pool.Query(ctx,
+			`SELECT id, conversation_id, role, content, emotions, timestamp FROM messages
+			 WHERE conversation_id = $1 AND ($3::timestamp IS NULL OR (timestamp, id) < ($3::timestamp, $4::uuid))
+			 ORDER BY timestamp DESC, id DESC LIMIT $2`,
+			conversationID, page.Limit, beforeTime, beforeID,
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []Message
+	for rows.Next() {
+		var msg Message
+		if err := rows.Scan(&msg.ID, &msg.ConversationID, &msg.Role, &msg.Content, &msg.Emotions, &msg.Timestamp); err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if page.After == nil {
+		for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+			messages[i], messages[j] = messages[j], messages[i]
+		}
+	}
+	return messages, nil
+}
+
+// CountMessages returns how many messages a conversation has. Callers check
+// ownership first.
+func (db *DB) CountMessages(ctx context.Context, conversationID uuid.UUID) (int, error) {
+	var count int
+	err := db.Pool.QueryRow(ctx,
+		`SELECT COUNT(*) FROM messages WHERE conversation_id = $1`,
+		conversationID,
+	).Scan(&count)
+	return count, err
+}
+
+// Context injection methods
+func (db *DB) LogContextInjection(ctx context.Context, conversationID uuid.UUID, messageID *uuid.UUID, contextText, contextType, reasoning string) (*ContextInjection, error) {
+	var injection ContextInjection
+	var reasoningResult sql.NullString
+	err := db.Pool.QueryRow(ctx,
+		`INSERT INTO context_injections (conversation_id, message_id, context_text, context_type, reasoning) VALUES ($1, $2, $3, $4, $5)
+		 RETURNING id, conversation_id, message_id, context_text, context_type, reasoning, created_at`,
+		conversationID, messageID, contextText, contextType, reasoning,
+	).Scan(&injection.ID, &injection.ConversationID, &injection.MessageID, &injection.ContextText, &injection.ContextType, &reasoningResult, &injection.CreatedAt)
+	injection.Reasoning = reasoningResult.String
+	return &injection, err
+}
+
+func (db *DB) ListContextInjections(ctx context.Context, conversationID, userID uuid.UUID) ([]ContextInjection, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT ci.id, ci.conversation_id, ci.message_id, ci.context_text, ci.context_type, ci.reasoning, ci.created_at
+		 FROM context_injections ci
+		 JOIN conversations c ON c.id = ci.conversation_id
+		 WHERE ci.conversation_id = $1 AND c.user_id = $2
+		 ORDER BY ci.created_at ASC`,
+		conversationID, userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var injections []ContextInjection
+	for rows.Next() {
+		var injection ContextInjection
+		var reasoning sql.NullString
+		err := rows.Scan(&injection.ID, &injection.ConversationID, &injection.MessageID, &injection.ContextText, &injection.ContextType, &reasoning, &injection.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		injection.Reasoning = reasoning.String
+		injections = append(injections, injection)
+	}
+	return injections, rows.Err()
+}
+
+// voiceColumns is the column list matching scanVoice
+const voiceColumns = `id, name, description, prompt, voice_description, hume_voice_id, hume_voice_provider, hume_voice_name, hume_config_id, hume_config_version, hume_prompt_id, hume_prompt_version, evi_version, language_model_provider, language_model_resource, temperature, greeting_message, inactivity_message, max_duration_message, inactivity_timeout_secs, max_duration_secs, created_at, updated_at`
+
+func scanVoice(row pgx.Row) (*Voice, error) {
+	var voice Voice
+	err := row.Scan(&voice.ID, &voice.Name, &voice.Description, &voice.Prompt, &voice.VoiceDescription, &voice.HumeVoiceID, &voice.HumeVoiceProvider, &voice.HumeVoiceName, &voice.HumeConfigID, &voice.HumeConfigVersion, &voice.HumePromptID, &voice.HumePromptVersion, &voice.EVIVersion, &voice.LanguageModelProvider, &voice.LanguageModelResource, &voice.Temperature, &voice.GreetingMessage, &voice.InactivityMessage, &voice.MaxDurationMessage, &voice.InactivityTimeoutSecs, &voice.MaxDurationSecs, &voice.CreatedAt, &voice.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &voice, nil
+}
+
 // Voice methods
 func (db *DB) CreateVoice(ctx context.Context, voice *Voice) (*Voice, error) {
-	var created Voice
-	err := db.Pool.QueryRow(ctx,
-		`INSERT INTO voices (name, description, prompt, voice_description, hume_voice_id, hume_config_id, evi_version, language_model_provider, language_model_resource, temperature)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-		 RETURNING id, name, description, prompt, voice_description, hume_voice_id, hume_config_id, evi_version, language_model_provider, language_model_resource, temperature, created_at, updated_at`,
-		voice.Name, voice.Description, voice.Prompt, voice.VoiceDescription, voice.HumeVoiceID, voice.HumeConfigID, voice.EVIVersion, voice.LanguageModelProvider, voice.LanguageModelResource, voice.Temperature,
-	).Scan(&created.ID, &created.Name, &created.Description, &created.Prompt, &created.VoiceDescription, &created.HumeVoiceID, &created.HumeConfigID, &created.EVIVersion, &created.LanguageModelProvider, &created.LanguageModelResource, &created.Temperature, &created.CreatedAt, &created.UpdatedAt)
-	return &created, err
+	return scanVoice(db.Pool.QueryRow(ctx,
+		`INSERT INTO voices (name, description, prompt, voice_description, hume_voice_id, hume_voice_provider, hume_voice_name, hume_config_id, hume_config_version, hume_prompt_id, hume_prompt_version, evi_version, language_model_provider, language_model_resource, temperature, greeting_message, inactivity_message, max_duration_message, inactivity_timeout_secs, max_duration_secs)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)
+		 RETURNING `+voiceColumns,
+		voice.Name, voice.Description, voice.Prompt, voice.VoiceDescription, voice.HumeVoiceID, voice.HumeVoiceProvider, voice.HumeVoiceName, voice.HumeConfigID, voice.HumeConfigVersion, voice.HumePromptID, voice.HumePromptVersion, voice.EVIVersion, voice.LanguageModelProvider, voice.LanguageModelResource, voice.Temperature, voice.GreetingMessage, voice.InactivityMessage, voice.MaxDurationMessage, voice.InactivityTimeoutSecs, voice.MaxDurationSecs,
+	))
 }
 
 func (db *DB) GetVoice(ctx context.Context, id uuid.UUID) (*Voice, error) {
-	var voice Voice
-	err := db.Pool.QueryRow(ctx,
-		`SELECT id, name, description, prompt, voice_description, hume_voice_id, hume_config_id, evi_version, language_model_provider, language_model_resource, temperature, created_at, updated_at
+	return scanVoice(db.Pool.QueryRow(ctx,
+		`SELECT `+voiceColumns+`
 		 FROM voices WHERE id = $1`,
 		id,
-	).Scan(&voice.ID, &voice.Name, &voice.Description, &voice.Prompt, &voice.VoiceDescription, &voice.HumeVoiceID, &voice.HumeConfigID, &voice.EVIVersion, &voice.LanguageModelProvider, &voice.LanguageModelResource, &voice.Temperature, &voice.CreatedAt, &voice.UpdatedAt)
-	return &voice, err
+	))
 }
 
 func (db *DB) ListVoices(ctx context.Context) ([]Voice, error) {
 	rows, err := db.Pool.Query(ctx,
-		`SELECT id, name, description, prompt, voice_description, hume_voice_id, hume_config_id, evi_version, language_model_provider, language_model_resource, temperature, created_at, updated_at
+		`SELECT `+voiceColumns+`
 		 FROM voices ORDER BY created_at DESC`,
 	)
 	if err != nil {
@@ -356,25 +528,22 @@ func (db *DB) ListVoices(ctx context.Context) ([]Voice, error) {
 
 	var voices []Voice
 	for rows.Next() {
-		var voice Voice
-		err := rows.Scan(&voice.ID, &voice.Name, &voice.Description, &voice.Prompt, &voice.VoiceDescription, &voice.HumeVoiceID, &voice.HumeConfigID, &voice.EVIVersion, &voice.LanguageModelProvider, &voice.LanguageModelResource, &voice.Temperature, &voice.CreatedAt, &voice.UpdatedAt)
+		voice, err := scanVoice(rows)
 		if err != nil {
 			return nil, err
 		}
-		voices = append(voices, voice)
+		voices = append(voices, *voice)
 	}
 	return voices, rows.Err()
 }
 
 func (db *DB) UpdateVoice(ctx context.Context, id uuid.UUID, voice *Voice) (*Voice, error) {
-	var updated Voice
-	err := db.Pool.QueryRow(ctx,
-		`UPDATE voices SET name = $1, description = $2, prompt = $3, voice_description = $4, hume_voice_id = $5, hume_config_id = $6, evi_version = $7, language_model_provider = $8, language_model_resource = $9, temperature = $10
-		 WHERE id = $11
-		 RETURNING id, name, description, prompt, voice_description, hume_voice_id, hume_config_id, evi_version, language_model_provider, language_model_resource, temperature, created_at, updated_at`,
-		voice.Name, voice.Description, voice.Prompt, voice.VoiceDescription, voice.HumeVoiceID, voice.HumeConfigID, voice.EVIVersion, voice.LanguageModelProvider, voice.LanguageModelResource, voice.Temperature, id,
-	).Scan(&updated.ID, &updated.Name, &updated.Description, &updated.Prompt, &updated.VoiceDescription, &updated.HumeVoiceID, &updated.HumeConfigID, &updated.EVIVersion, &updated.LanguageModelProvider, &updated.LanguageModelResource, &updated.Temperature, &updated.CreatedAt, &updated.UpdatedAt)
-	return &updated, err
+	return scanVoice(db.Pool.QueryRow(ctx,
+		`UPDATE voices SET name = $1, description = $2, prompt = $3, voice_description = $4, hume_voice_id = $5, hume_voice_provider = $6, hume_voice_name = $7, hume_config_id = $8, hume_config_version = $9, hume_prompt_id = $10, hume_prompt_version = $11, evi_version = $12, language_model_provider = $13, language_model_resource = $14, temperature = $15, greeting_message = $16, inactivity_message = $17, max_duration_message = $18, inactivity_timeout_secs = $19, max_duration_secs = $20
+		 WHERE id = $21
+		 RETURNING `+voiceColumns,
+		voice.Name, voice.Description, voice.Prompt, voice.VoiceDescription, voice.HumeVoiceID, voice.HumeVoiceProvider, voice.HumeVoiceName, voice.HumeConfigID, voice.HumeConfigVersion, voice.HumePromptID, voice.HumePromptVersion, voice.EVIVersion, voice.LanguageModelProvider, voice.LanguageModelResource, voice.Temperature, voice.GreetingMessage, voice.InactivityMessage, voice.MaxDurationMessage, voice.InactivityTimeoutSecs, voice.MaxDurationSecs, id,
+	))
 }
 
 func (db *DB) DeleteVoice(ctx context.Context, id uuid.UUID) error {
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ts_headline wraps matched words in these control characters, which don't
+// turn up in spoken transcripts
+const (
+	highlightStart = "\x01"
+	highlightStop  = "\x02"
+)
+
+var headlineOptions = fmt.Sprintf(`StartSel="%s", StopSel="%s", MaxWords=35, MinWords=15, MaxFragments=2, FragmentDelimiter=" … "`, highlightStart, highlightStop)
+
+// SnippetPart is a run of snippet text; Match marks the words that matched
+// the query
+type SnippetPart struct {
+	Text  string `json:"text"`
+	Match bool   `json:"match,omitempty"`
+}
+
+// SearchHit is a message matching a search, with the conversation it is in
+type SearchHit struct {
+	MessageID         uuid.UUID     `json:"message_id"`
+	ConversationID    uuid.UUID     `json:"conversation_id"`
+	ConversationTitle string        `json:"conversation_title"`
+	Role              string        `json:"role"`
+	Timestamp         time.Time     `json:"timestamp"`
+	Rank              float64       `json:"rank"`
+	Snippet           []SnippetPart `json:"snippet"`
+}
+
+// SearchMessages finds up to limit of the user's messages matching query,
+// best match first. The query takes web search syntax: quoted phrases, OR,
+// and -word to exclude.
+func (db *DB) SearchMessages(ctx context.Context, userID uuid.UUID, query string, limit int) ([]SearchHit, error) {
+	// Rank and limit first, so headlines are only built for the hits returned
+	rows, err := db.Pool.Query(ctx,
+		`SELECT hit.id, hit.conversation_id, hit.title, hit.role, hit.timestamp, hit.rank,
+		        ts_headline('english', hit.content, websearch_to_tsquery('english', $2), $4)
+		 FROM (
+			SELECT m.id, m.conversation_id, c.title, m.role, m.content, m.timestamp,
+			       ts_rank(m.content_tsv, websearch_to_tsquery('english', $2))::float8 AS rank
+			FROM messages m
+			JOIN conversations c ON c.id = m.conversation_id
+			WHERE c.user_id = $1 AND m.content_tsv @@ websearch_to_tsquery('english', $2)
+			ORDER BY rank DESC, m.timestamp DESC
+			LIMIT $3
+		 ) hit
+		 ORDER BY hit.rank DESC, hit.timestamp DESC`,
+		userID, query, limit, headlineOptions,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hits []SearchHit
+	for rows.Next() {
+		var hit SearchHit
+		var headline string
+		if err := rows.Scan(&hit.MessageID, &hit.ConversationID, &hit.ConversationTitle, &hit.Role, &hit.Timestamp, &hit.Rank, &headline); err != nil {
+			return nil, err
+		}
+		hit.Snippet = parseHeadline(headline)
+		hits = append(hits, hit)
+	}
+	return hits, rows.Err()
+}
+
+// parseHeadline splits a ts_headline result on its highlight markers. A
+// match without a stop marker runs to the end, and empty matches are dropped.
+func parseHeadline(headline string) []SnippetPart {
+	var parts []SnippetPart
+	for headline != "" {
+		start := strings.Index(headline, highlightStart)
+		if start < 0 {
+			parts = append(parts, SnippetPart{Text: headline})
+			break
+		}
+		if start > 0 {
+			parts = append(parts, SnippetPart{Text: headline[:start]})
+		}
+		headline = headline[start+len(highlightStart):]
+
+		stop := strings.Index(headline, highlightStop)
+		if stop < 0 {
+			stop = len(headline)
+		}
+		if stop > 0 {
+			parts = append(parts, SnippetPart{Text: headline[:stop], Match: true})
+		}
+		headline = strings.TrimPrefix(headline[stop:], highlightStop)
+	}
+	return parts
+}
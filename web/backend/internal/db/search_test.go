@@ -0,0 +1,69 @@
+package db_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/hume-evi/web/internal/db"
+)
+
+func searchUser(t *testing.T, database *db.DB, username string, messages ...string) (uuid.UUID, uuid.UUID) {
+	t.Helper()
+	ctx := context.Background()
+	user, err := database.CreateUser(ctx, username, "hash", nil, false)
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	conv, err := database.CreateConversation(ctx, user.ID, username+"'s chat")
+	if err != nil {
+		t.Fatalf("CreateConversation: %v", err)
+	}
+	for _, content := range messages {
+		if _, err := database.AddMessage(ctx, conv.ID, "user", content, nil); err != nil {
+			t.Fatalf("AddMessage: %v", err)
+		}
+	}
+	return user.ID, conv.ID
+}
+
+func TestSearchMessagesIsScopedToUser(t *testing.T) {
+	database := openTestDB(t)
+	emptyTestDB(t, database)
+	ctx := context.Background()
+
+	ada, adaConv := searchUser(t, database, "ada", "I walked along the river in Lisbon", "The weather was awful")
+	bob, bobConv := searchUser(t, database, "bob", "Lisbon walks are the best", "Lisbon again, walking all day")
+
+	// Stemming matches walked, walks and walking alike; only the searcher's
+	// own messages come back
+	for _, tt := range []struct {
+		user uuid.UUID
+		conv uuid.UUID
+		want int
+	}{
+		{ada, adaConv, 1},
+		{bob, bobConv, 2},
+	} {
+		hits, err := database.SearchMessages(ctx, tt.user, "walking lisbon", 10)
+		if err != nil {
+			t.Fatalf("SearchMessages: %v", err)
+		}
+		if len(hits) != tt.want {
+			t.Fatalf("SearchMessages found %d hits, want %d: %+v", len(hits), tt.want, hits)
+		}
+		for _, hit := range hits {
+			if hit.ConversationID != tt.conv {
+				t.Fatalf("SearchMessages returned %+v from another user's conversation", hit)
+			}
+		}
+	}
+
+	if hits, err := database.SearchMessages(ctx, bob, "weather", 10); err != nil || len(hits) != 0 {
+		t.Fatalf("searching for another user's words found %+v, %v; want nothing", hits, err)
+	}
+	if hits, err := database.SearchMessages(ctx, uuid.New(), "lisbon", 10); err != nil || len(hits) != 0 {
+		t.Fatalf("an unknown user's search found %+v, %v; want nothing", hits, err)
+	}
+}
@@ -0,0 +1,181 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrNotFound is returned when no row matches. It is pgx.ErrNoRows, so
+// in-memory stores and Postgres fail the same way.
+var ErrNotFound = pgx.ErrNoRows
+
+// UserStore persists users. Usernames are unique.
+type UserStore interface {
+	CreateUser(ctx context.Context, username, passwordHash string, name *string, isAdmin bool) (*User, error)
+	// CreateUserWithID creates the user or overwrites the one with that ID
+	CreateUserWithID(ctx context.Context, id uuid.UUID, username, passwordHash string, isAdmin bool) error
+	GetUserByUsername(ctx context.Context, username string) (*User, error)
+	GetUserByID(ctx context.Context, id uuid.UUID) (*User, error)
+	// ListUsers returns users newest first
+	ListUsers(ctx context.Context) ([]User, error)
+	UpdateUserPassword(ctx context.Context, id uuid.UUID, passwordHash string) error
+	UpdateUserAdmin(ctx context.Context, id uuid.UUID, isAdmin bool) error
+	// UpdateUser changes only the fields that are non-nil
+	UpdateUser(ctx context.Context, id uuid.UUID, passwordHash *string, name *string, isAdmin *bool) error
+	// DeleteUser also deletes the user's conversations and their messages
+	DeleteUser(ctx context.Context, id uuid.UUID) error
+}
+
+// ConversationStore persists conversations. Every method is scoped to the
+// owning user: another user's conversation is not found, and writes to it
+// are silently ignored.
+type ConversationStore interface {
+	CreateConversation(ctx context.Context, userID uuid.UUID, title string) (*Conversation, error)
+	GetConversation(ctx context.Context, id, userID uuid.UUID) (*Conversation, error)
+	// ListConversations returns up to limit conversations ordered by
+	// (updated_at, id) descending, with their message counts, starting after
+	// the after cursor when it is set
+	ListConversations(ctx context.Context, userID uuid.UUID, limit int, after *Cursor) ([]Conversation, error)
+	UpdateConversationStatus(ctx context.Context, id, userID uuid.UUID, status string) error
+	SetConversationVoice(ctx context.Context, id, userID, voiceID uuid.UUID) error
+	// GetLastActiveConversation returns the most recently updated active one
+	GetLastActiveConversation(ctx context.Context, userID uuid.UUID) (*Conversation, error)
+	// DeleteConversation also deletes its messages
+	DeleteConversation(ctx context.Context, id, userID uuid.UUID) error
+}
+
+// MessageStore persists conversation messages
+type MessageStore interface {
+	// AddMessage appends a message, with its prosody scores if any, and
+	// bumps the conversation's updated_at; the conversation must exist
+	AddMessage(ctx context.Context, conversationID uuid.UUID, role, content string, emotions map[string]float64) (*Message, error)
+	// GetMessages returns a conversation's messages oldest first, or
+	// ErrNotFound if the user doesn't own it
+	GetMessages(ctx context.Context, conversationID, userID uuid.UUID) ([]Message, error)
+	// ListMessages returns up to page.Limit messages ordered by
+	// (timestamp, id), oldest first: those just after page.After, just
+	// before page.Before, or the newest. Ownership is checked as in
+	// GetMessages.
+	ListMessages(ctx context.Context, conversationID, userID uuid.UUID, page MessagePage) ([]Message, error)
+	// CountMessages returns how many messages a conversation has. Callers
+	// check ownership first.
+	CountMessages(ctx context.Context, conversationID uuid.UUID) (int, error)
+}
+
+// ContextInjectionStore records the context injected into EVI sessions
+type ContextInjectionStore interface {
+	// LogContextInjection records an injection against the conversation and,
+	// when known, the message that prompted it
+	LogContextInjection(ctx context.Context, conversationID uuid.UUID, messageID *uuid.UUID, contextText, contextType, reasoning string) (*ContextInjection, error)
+	// ListContextInjections returns a conversation's injections oldest
+	// first, or none if the user doesn't own it
+	ListContextInjections(ctx context.Context, conversationID, userID uuid.UUID) ([]ContextInjection, error)
+}
+
+// VoiceStore persists voices
+type VoiceStore interface {
+	CreateVoice(ctx context.Context, voice *Voice) (*Voice, error)
+	GetVoice(ctx context.Context, id uuid.UUID) (*Voice, error)
+	// ListVoices returns voices newest first
+	ListVoices(ctx context.Context) ([]Voice, error)
+	UpdateVoice(ctx context.Context, id uuid.UUID, voice *Voice) (*Voice, error)
+	// DeleteVoice leaves conversations held with the voice in place, with
+	// no voice
+	DeleteVoice(ctx context.Context, id uuid.UUID) error
+}
+
+// VoicePreviewStore caches TTS generations for voice design previews
+type VoicePreviewStore interface {
+	CreateVoicePreview(ctx context.Context, p *VoicePreview) (*VoicePreview, error)
+	// ListVoicePreviews returns the cached generations for a key, oldest
+	// first, without their audio
+	ListVoicePreviews(ctx context.Context, cacheKey string) ([]VoicePreview, error)
+	// GetVoicePreview returns a generation with its audio
+	GetVoicePreview(ctx context.Context, id uuid.UUID) (*VoicePreview, error)
+	MarkVoicePreviewSaved(ctx context.Context, id uuid.UUID, humeVoiceID string) error
+	DeleteVoicePreviews(ctx context.Context, cacheKey string) error
+}
+
+// MessageSearcher searches transcripts
+type MessageSearcher interface {
+	// SearchMessages finds up to limit of the user's messages matching
+	// query, best match first
+	SearchMessages(ctx context.Context, userID uuid.UUID, query string, limit int) ([]SearchHit, error)
+}
+
+// VoiceRevisionStore keeps numbered snapshots of voices, which are deleted
+// with their voice
+type VoiceRevisionStore interface {
+	// RecordVoiceRevision snapshots the voice as its next revision; the
+	// voice must exist
+	RecordVoiceRevision(ctx context.Context, voice *Voice, change VoiceChange) (*VoiceRevision, error)
+	// ListVoiceRevisions returns a voice's revisions, newest first
+	ListVoiceRevisions(ctx context.Context, voiceID uuid.UUID) ([]VoiceRevision, error)
+	GetVoiceRevision(ctx context.Context, voiceID uuid.UUID, revision int) (*VoiceRevision, error)
+	GetLatestVoiceRevision(ctx context.Context, voiceID uuid.UUID) (*VoiceRevision, error)
+}
+
+// ReplacedHumeConfigStore tracks Hume configs no voice points at any more,
+// until they are deleted from Hume
+type ReplacedHumeConfigStore interface {
+	RecordReplacedHumeConfig(ctx context.Context, voiceID *uuid.UUID, configID, replacedBy, reason string) error
+	// ListReplacedHumeConfigs returns configs oldest first, only those
+	// awaiting cleanup unless includeCleaned is set
+	ListReplacedHumeConfigs(ctx context.Context, includeCleaned bool) ([]ReplacedHumeConfig, error)
+	GetReplacedHumeConfig(ctx context.Context, id uuid.UUID) (*ReplacedHumeConfig, error)
+	MarkHumeConfigCleanedUp(ctx context.Context, id uuid.UUID) error
+}
+
+// HumeSyncRunStore records bulk syncs and reconciliations against Hume
+type HumeSyncRunStore interface {
+	// StartHumeSyncRun returns ErrHumeSyncRunning while a run of the same
+	// kind is running
+	StartHumeSyncRun(ctx context.Context, kind string, heal bool, total int, startedBy *uuid.UUID) (*HumeSyncRun, error)
+	SetHumeSyncRunTotal(ctx context.Context, id uuid.UUID, total int) error
+	AppendHumeSyncResult(ctx context.Context, id uuid.UUID, result HumeSyncResult) error
+	FinishHumeSyncRun(ctx context.Context, id uuid.UUID, status, runError string) error
+	// FailInterruptedHumeSyncRuns fails every running run and returns how
+	// many there were
+	FailInterruptedHumeSyncRuns(ctx context.Context) (int64, error)
+	GetHumeSyncRun(ctx context.Context, id uuid.UUID) (*HumeSyncRun, error)
+	// GetLatestHumeSyncRun returns nil when there is no run of the kind
+	GetLatestHumeSyncRun(ctx context.Context, kind string) (*HumeSyncRun, error)
+}
+
+// GraphJobStore is the graph extraction queue: one job per conversation,
+// deleted with it
+type GraphJobStore interface {
+	EnqueueGraphExtraction(ctx context.Context, conversationID, userID uuid.UUID) error
+	// ClaimGraphExtractionJob marks the job due soonest as running, or
+	// returns nil when none is due
+	ClaimGraphExtractionJob(ctx context.Context) (*GraphExtractionJob, error)
+	CompleteGraphExtractionJob(ctx context.Context, conversationID uuid.UUID, entities, relationships int) error
+	FailGraphExtractionJob(ctx context.Context, conversationID uuid.UUID, errMsg string, retryAt *time.Time) error
+	RequeueRunningGraphExtractionJobs(ctx context.Context) (int64, error)
+	// ListGraphExtractionJobs returns jobs most recently updated first;
+	// an empty status lists every job
+	ListGraphExtractionJobs(ctx context.Context, status string, limit int) ([]GraphExtractionJob, error)
+	CountGraphExtractionJobs(ctx context.Context) (map[string]int, error)
+}
+
+// Store is every store the API handlers, background jobs and the EVI proxy
+// need. *DB implements it against Postgres; memstore implements it in
+// memory.
+type Store interface {
+	UserStore
+	ConversationStore
+	MessageStore
+	MessageSearcher
+	ContextInjectionStore
+	VoiceStore
+	VoicePreviewStore
+	VoiceRevisionStore
+	ReplacedHumeConfigStore
+	HumeSyncRunStore
+	GraphJobStore
+}
+
+var _ Store = (*DB)(nil)
@@ -0,0 +1,96 @@
+package db_test
+
+import (
+	"context"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/hume-evi/web/internal/db"
+	"github.com/hume-evi/web/internal/db/storetest"
+)
+
+// openTestDB connects to and migrates the database given as
+// TEST_DATABASE_URL, skipping the test when it isn't set. The database may
+// be wiped.
+func openTestDB(t *testing.T) *db.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	database, err := db.New(dsn)
+	if err != nil {
+		t.Fatalf("connecting to test database: %v", err)
+	}
+	t.Cleanup(database.Close)
+
+	if err := database.RunMigrations(context.Background()); err != nil {
+		t.Fatalf("migrating test database: %v", err)
+	}
+	return database
+}
+
+// emptyTestDB removes every row; conversations, messages and everything
+// else hang off users and voices
+func emptyTestDB(t *testing.T, database *db.DB) {
+	t.Helper()
+	if _, err := database.Pool.Exec(context.Background(), `TRUNCATE users, voices CASCADE`); err != nil {
+		t.Fatalf("emptying test database: %v", err)
+	}
+}
+
+// TestStore runs the conformance suite against Postgres
+func TestStore(t *testing.T) {
+	database := openTestDB(t)
+
+	storetest.Run(t, func(t *testing.T) db.Store {
+		emptyTestDB(t, database)
+		return database
+	})
+}
+
+func TestConcurrentVoiceRevisions(t *testing.T) {
+	database := openTestDB(t)
+	emptyTestDB(t, database)
+
+	ctx := context.Background()
+	voice, err := database.CreateVoice(ctx, &db.Voice{Name: "Sam", Prompt: "Be kind.", Temperature: 1})
+	if err != nil {
+		t.Fatalf("CreateVoice: %v", err)
+	}
+
+	const edits = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, edits)
+	for i := 0; i < edits; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := database.RecordVoiceRevision(ctx, voice, db.VoiceChange{Action: db.VoiceActionUpdate})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("RecordVoiceRevision: %v", err)
+		}
+	}
+
+	revisions, err := database.ListVoiceRevisions(ctx, voice.ID)
+	if err != nil {
+		t.Fatalf("ListVoiceRevisions: %v", err)
+	}
+	numbers := make([]int, 0, len(revisions))
+	for _, rev := range revisions {
+		numbers = append(numbers, rev.Revision)
+	}
+	sort.Ints(numbers)
+	if len(numbers) != edits || numbers[0] != 1 || numbers[edits-1] != edits {
+		t.Fatalf("got revisions %v, want 1..%d", numbers, edits)
+	}
+}
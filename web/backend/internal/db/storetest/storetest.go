@@ -0,0 +1,1181 @@
+// Package storetest is a conformance suite for db.Store implementations, so
+// the Postgres store and memstore are held to the same behaviour. Wire it
+// into a package's tests with
+//
+//	func TestStore(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) db.Store { return memstore.New() })
+//	}
+//
+// open must return an empty store for each call.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/hume-evi/web/internal/db"
+)
+
+// Run runs every conformance check as a subtest, each against a fresh store
+func Run(t *testing.T, open func(t *testing.T) db.Store) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s db.Store)
+	}{
+		{"Users", testUsers},
+		{"UsernamesUnique", testUsernamesUnique},
+		{"CreateUserWithIDUpserts", testCreateUserWithIDUpserts},
+		{"UpdateUser", testUpdateUser},
+		{"DeleteUserCascades", testDeleteUserCascades},
+		{"Conversations", testConversations},
+		{"ConversationOwnership", testConversationOwnership},
+		{"ListConversations", testListConversations},
+		{"ConversationPages", testConversationPages},
+		{"LastActiveConversation", testLastActiveConversation},
+		{"DeleteConversationCascades", testDeleteConversationCascades},
+		{"Messages", testMessages},
+		{"MessageNeedsConversation", testMessageNeedsConversation},
+		{"MessagePages", testMessagePages},
+		{"CountMessages", testCountMessages},
+		{"ContextInjections", testContextInjections},
+		{"Voices", testVoices},
+		{"DeleteVoiceKeepsConversations", testDeleteVoiceKeepsConversations},
+		{"VoicePreviews", testVoicePreviews},
+		{"VoiceRevisions", testVoiceRevisions},
+		{"ReplacedHumeConfigs", testReplacedHumeConfigs},
+		{"HumeSyncRuns", testHumeSyncRuns},
+		{"GraphJobs", testGraphJobs},
+		{"GraphJobRerun", testGraphJobRerun},
+		{"SearchMessages", testSearchMessages},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, open(t))
+		})
+	}
+}
+
+func isNotFound(err error) bool {
+	return errors.Is(err, db.ErrNotFound)
+}
+
+func mustUser(t *testing.T, s db.Store, username string) *db.User {
+	t.Helper()
+	user, err := s.CreateUser(context.Background(), username, "hash-"+username, nil, false)
+	if err != nil {
+		t.Fatalf("CreateUser(%q): %v", username, err)
+	}
+	return user
+}
+
+func mustConversation(t *testing.T, s db.Store, userID uuid.UUID, title string) *db.Conversation {
+	t.Helper()
+	conv, err := s.CreateConversation(context.Background(), userID, title)
+	if err != nil {
+		t.Fatalf("CreateConversation(%q): %v", title, err)
+	}
+	return conv
+}
+
+func mustMessage(t *testing.T, s db.Store, conversationID uuid.UUID, role, content string) *db.Message {
+	t.Helper()
+	msg, err := s.AddMessage(context.Background(), conversationID, role, content, nil)
+	if err != nil {
+		t.Fatalf("AddMessage(%q): %v", content, err)
+	}
+	return msg
+}
+
+func mustVoice(t *testing.T, s db.Store, name string) *db.Voice {
+	t.Helper()
+	voice, err := s.CreateVoice(context.Background(), testVoice(name))
+	if err != nil {
+		t.Fatalf("CreateVoice(%q): %v", name, err)
+	}
+	return voice
+}
+
+func testVoice(name string) *db.Voice {
+	configVersion := 2
+	return &db.Voice{
+		Name:                  name,
+		Description:           name + " description",
+		Prompt:                "You are " + name,
+		VoiceDescription:      "a calm voice",
+		HumeVoiceProvider:     "HUME_AI",
+		HumeVoiceName:         "ITO",
+		HumeConfigID:          "config-" + name,
+		HumeConfigVersion:     &configVersion,
+		EVIVersion:            "3",
+		LanguageModelProvider: "ANTHROPIC",
+		LanguageModelResource: "claude-3-5-sonnet-latest",
+		Temperature:           0.7,
+		GreetingMessage:       "Hello",
+		InactivityTimeoutSecs: 120,
+	}
+}
+
+// tick waits long enough for timestamps written afterwards to sort later
+func tick() {
+	time.Sleep(5 * time.Millisecond)
+}
+
+func testUsers(t *testing.T, s db.Store) {
+	ctx := context.Background()
+	name := "Ada"
+	created, err := s.CreateUser(ctx, "ada", "hash", &name, true)
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if created.ID == uuid.Nil || created.CreatedAt.IsZero() {
+		t.Fatalf("CreateUser didn't set id and created_at: %+v", created)
+	}
+	if created.Username != "ada" || created.PasswordHash != "hash" || !created.IsAdmin || created.Name == nil || *created.Name != "Ada" {
+		t.Fatalf("CreateUser returned %+v", created)
+	}
+
+	byName, err := s.GetUserByUsername(ctx, "ada")
+	if err != nil || byName.ID != created.ID {
+		t.Fatalf("GetUserByUsername = %+v, %v", byName, err)
+	}
+	byID, err := s.GetUserByID(ctx, created.ID)
+	if err != nil || byID.Username != "ada" {
+		t.Fatalf("GetUserByID = %+v, %v", byID, err)
+	}
+
+	if _, err := s.GetUserByUsername(ctx, "nobody"); !isNotFound(err) {
+		t.Fatalf("GetUserByUsername for a missing user: want ErrNotFound, got %v", err)
+	}
+	if _, err := s.GetUserByID(ctx, uuid.New()); !isNotFound(err) {
+		t.Fatalf("GetUserByID for a missing user: want ErrNotFound, got %v", err)
+	}
+
+	empty := ""
+	unnamed, err := s.CreateUser(ctx, "grace", "hash", &empty, false)
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if unnamed.Name != nil {
+		t.Fatalf("an empty name should read back as nil, got %q", *unnamed.Name)
+	}
+
+	tick()
+	newest := mustUser(t, s, "linus")
+	users, err := s.ListUsers(ctx)
+	if err != nil {
+		t.Fatalf("ListUsers: %v", err)
+	}
+	if len(users) != 3 || users[0].ID != newest.ID {
+		t.Fatalf("ListUsers should return 3 users newest first, got %+v", users)
+	}
+}
+
+func testUsernamesUnique(t *testing.T, s db.Store) {
+	mustUser(t, s, "ada")
+	if _, err := s.CreateUser(context.Background(), "ada", "other", nil, false); err == nil {
+		t.Fatal("creating a second user named ada should fail")
+	}
+}
+
+func testCreateUserWithIDUpserts(t *testing.T, s db.Store) {
+	ctx := context.Background()
+	id := uuid.New()
+	if err := s.CreateUserWithID(ctx, id, "service", "hash1", false); err != nil {
+		t.Fatalf("CreateUserWithID: %v", err)
+	}
+	if err := s.CreateUserWithID(ctx, id, "service2", "hash2", true); err != nil {
+		t.Fatalf("CreateUserWithID again: %v", err)
+	}
+
+	user, err := s.GetUserByID(ctx, id)
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	if user.Username != "service2" || user.PasswordHash != "hash2" || !user.IsAdmin {
+		t.Fatalf("CreateUserWithID should overwrite the existing user, got %+v", user)
+	}
+}
+
+func testUpdateUser(t *testing.T, s db.Store) {
+	ctx := context.Background()
+	user := mustUser(t, s, "ada")
+
+	name := "Ada Lovelace"
+	if err := s.UpdateUser(ctx, user.ID, nil, &name, nil); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	if err := s.UpdateUserPassword(ctx, user.ID, "new-hash"); err != nil {
+		t.Fatalf("UpdateUserPassword: %v", err)
+	}
+	if err := s.UpdateUserAdmin(ctx, user.ID, true); err != nil {
+		t.Fatalf("UpdateUserAdmin: %v", err)
+	}
+
+	got, err := s.GetUserByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	if got.Name == nil || *got.Name != name || got.PasswordHash != "new-hash" || !got.IsAdmin {
+		t.Fatalf("updates weren't applied: %+v", got)
+	}
+
+	// Fields left nil are unchanged
+	if err := s.UpdateUser(ctx, user.ID, nil, nil, nil); err != nil {
+		t.Fatalf("UpdateUser with no changes: %v", err)
+	}
+	got, _ = s.GetUserByID(ctx, user.ID)
+	if got.Name == nil || *got.Name != name || got.PasswordHash != "new-hash" || !got.IsAdmin {
+		t.Fatalf("UpdateUser with no changes altered the user: %+v", got)
+	}
+}
+
+func testDeleteUserCascades(t *testing.T, s db.Store) {
+	ctx := context.Background()
+	user := mustUser(t, s, "ada")
+	other := mustUser(t, s, "grace")
+	conv := mustConversation(t, s, user.ID, "mine")
+	mustMessage(t, s, conv.ID, "user", "hello")
+	kept := mustConversation(t, s, other.ID, "theirs")
+
+	if err := s.DeleteUser(ctx, user.ID); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if _, err := s.GetUserByID(ctx, user.ID); !isNotFound(err) {
+		t.Fatalf("deleted user: want ErrNotFound, got %v", err)
+	}
+	if _, err := s.GetConversation(ctx, conv.ID, user.ID); !isNotFound(err) {
+		t.Fatalf("deleted user's conversation: want ErrNotFound, got %v", err)
+	}
+	if _, err := s.AddMessage(ctx, conv.ID, "user", "still there?", nil); err == nil {
+		t.Fatal("AddMessage to a deleted user's conversation should fail")
+	}
+	if _, err := s.GetConversation(ctx, kept.ID, other.ID); err != nil {
+		t.Fatalf("another user's conversation should survive: %v", err)
+	}
+}
+
+func testConversations(t *testing.T, s db.Store) {
+	ctx := context.Background()
+	user := mustUser(t, s, "ada")
+	conv := mustConversation(t, s, user.ID, "First chat")
+	if conv.ID == uuid.Nil || conv.UserID != user.ID || conv.Title != "First chat" || conv.Status != "active" || conv.VoiceID != nil {
+		t.Fatalf("CreateConversation returned %+v", conv)
+	}
+	if conv.CreatedAt.IsZero() || conv.UpdatedAt.IsZero() {
+		t.Fatalf("CreateConversation didn't set timestamps: %+v", conv)
+	}
+
+	if _, err := s.CreateConversation(ctx, uuid.New(), "orphan"); err == nil {
+		t.Fatal("CreateConversation for a missing user should fail")
+	}
+
+	if err := s.UpdateConversationStatus(ctx, conv.ID, user.ID, "completed"); err != nil {
+		t.Fatalf("UpdateConversationStatus: %v", err)
+	}
+	voice := mustVoice(t, s, "guide")
+	if err := s.SetConversationVoice(ctx, conv.ID, user.ID, voice.ID); err != nil {
+		t.Fatalf("SetConversationVoice: %v", err)
+	}
+
+	got, err := s.GetConversation(ctx, conv.ID, user.ID)
+	if err != nil {
+		t.Fatalf("GetConversation: %v", err)
+	}
+	if got.Status != "completed" || got.VoiceID == nil || *got.VoiceID != voice.ID {
+		t.Fatalf("updates weren't applied: %+v", got)
+	}
+	if _, err := s.GetConversation(ctx, uuid.New(), user.ID); !isNotFound(err) {
+		t.Fatalf("GetConversation for a missing conversation: want ErrNotFound, got %v", err)
+	}
+}
+
+func testConversationOwnership(t *testing.T, s db.Store) {
+	ctx := context.Background()
+	owner := mustUser(t, s, "ada")
+	intruder := mustUser(t, s, "mallory")
+	conv := mustConversation(t, s, owner.ID, "private")
+	mustMessage(t, s, conv.ID, "user", "secret")
+
+	if _, err := s.GetConversation(ctx, conv.ID, intruder.ID); !isNotFound(err) {
+		t.Fatalf("GetConversation as another user: want ErrNotFound, got %v", err)
+	}
+	if _, err := s.GetMessages(ctx, conv.ID, intruder.ID); !isNotFound(err) {
+		t.Fatalf("GetMessages as another user: want ErrNotFound, got %v", err)
+	}
+	if convs, err := s.ListConversations(ctx, intruder.ID, 10, nil); err != nil || len(convs) != 0 {
+		t.Fatalf("ListConversations as another user = %+v, %v", convs, err)
+	}
+
+	// Writes by another user are ignored rather than failing
+	voice := mustVoice(t, s, "guide")
+	if err := s.UpdateConversationStatus(ctx, conv.ID, intruder.ID, "completed"); err != nil {
+		t.Fatalf("UpdateConversationStatus as another user: %v", err)
+	}
+	if err := s.SetConversationVoice(ctx, conv.ID, intruder.ID, voice.ID); err != nil {
+		t.Fatalf("SetConversationVoice as another user: %v", err)
+	}
+	if err := s.DeleteConversation(ctx, conv.ID, intruder.ID); err != nil {
+		t.Fatalf("DeleteConversation as another user: %v", err)
+	}
+
+	got, err := s.GetConversation(ctx, conv.ID, owner.ID)
+	if err != nil {
+		t.Fatalf("another user deleted the conversation: %v", err)
+	}
+	if got.Status != "active" || got.VoiceID != nil {
+		t.Fatalf("another user changed the conversation: %+v", got)
+	}
+}
+
+func testListConversations(t *testing.T, s db.Store) {
+	ctx := context.Background()
+	user := mustUser(t, s, "ada")
+	older := mustConversation(t, s, user.ID, "older")
+	tick()
+	newer := mustConversation(t, s, user.ID, "newer")
+	tick()
+
+	// A new message moves the older conversation to the top
+	mustMessage(t, s, older.ID, "user", "one")
+	mustMessage(t, s, older.ID, "assistant", "two")
+
+	convs, err := s.ListConversations(ctx, user.ID, 10, nil)
+	if err != nil {
+		t.Fatalf("ListConversations: %v", err)
+	}
+	if len(convs) != 2 || convs[0].ID != older.ID || convs[1].ID != newer.ID {
+		t.Fatalf("ListConversations should order by last update, got %+v", convs)
+	}
+	if convs[0].MessageCount != 2 || convs[1].MessageCount != 0 {
+		t.Fatalf("message counts = %d, %d; want 2, 0", convs[0].MessageCount, convs[1].MessageCount)
+	}
+	if !convs[0].UpdatedAt.After(convs[0].CreatedAt) {
+		t.Fatalf("AddMessage didn't bump updated_at: %+v", convs[0])
+	}
+
+	limited, err := s.ListConversations(ctx, user.ID, 1, nil)
+	if err != nil {
+		t.Fatalf("ListConversations: %v", err)
+	}
+	if len(limited) != 1 || limited[0].ID != older.ID {
+		t.Fatalf("ListConversations with limit 1 = %+v", limited)
+	}
+}
+
+func testConversationPages(t *testing.T, s db.Store) {
+	ctx := context.Background()
+	user := mustUser(t, s, "ada")
+	var want []uuid.UUID
+	for _, title := range []string{"a", "b", "c", "d", "e"} {
+		conv := mustConversation(t, s, user.ID, title)
+		want = append([]uuid.UUID{conv.ID}, want...)
+		tick()
+	}
+
+	var got []uuid.UUID
+	var after *db.Cursor
+	for pages := 0; ; pages++ {
+		if pages > len(want) {
+			t.Fatal("ListConversations never ran out of pages")
+		}
+		page, err := s.ListConversations(ctx, user.ID, 2, after)
+		if err != nil {
+			t.Fatalf("ListConversations: %v", err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		for _, conv := range page {
+			got = append(got, conv.ID)
+		}
+		cursor := db.ConversationCursor(page[len(page)-1])
+		after = &cursor
+	}
+	if len(got) != len(want) {
+		t.Fatalf("paging returned %d conversations, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("page order = %v, want %v", got, want)
+		}
+	}
+
+	// Cursors survive being encoded for a client
+	decoded, err := db.DecodeCursor(db.ConversationCursor(mustGetConversation(t, s, want[1], user.ID)).Encode())
+	if err != nil {
+		t.Fatalf("DecodeCursor: %v", err)
+	}
+	rest, err := s.ListConversations(ctx, user.ID, 10, decoded)
+	if err != nil {
+		t.Fatalf("ListConversations: %v", err)
+	}
+	if len(rest) != 3 || rest[0].ID != want[2] {
+		t.Fatalf("ListConversations after a decoded cursor = %+v", rest)
+	}
+}
+
+func mustGetConversation(t *testing.T, s db.Store, id, userID uuid.UUID) db.Conversation {
+	t.Helper()
+	conv, err := s.GetConversation(context.Background(), id, userID)
+	if err != nil {
+		t.Fatalf("GetConversation: %v", err)
+	}
+	return *conv
+}
+
+func testLastActiveConversation(t *testing.T, s db.Store) {
+	ctx := context.Background()
+	user := mustUser(t, s, "ada")
+	if _, err := s.GetLastActiveConversation(ctx, user.ID); !isNotFound(err) {
+		t.Fatalf("GetLastActiveConversation with none: want ErrNotFound, got %v", err)
+	}
+
+	active := mustConversation(t, s, user.ID, "active")
+	tick()
+	done := mustConversation(t, s, user.ID, "done")
+	if err := s.UpdateConversationStatus(ctx, done.ID, user.ID, "completed"); err != nil {
+		t.Fatalf("UpdateConversationStatus: %v", err)
+	}
+
+	got, err := s.GetLastActiveConversation(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetLastActiveConversation: %v", err)
+	}
+	if got.ID != active.ID {
+		t.Fatalf("GetLastActiveConversation = %s, want %s", got.ID, active.ID)
+	}
+}
+
+func testDeleteConversationCascades(t *testing.T, s db.Store) {
+	ctx := context.Background()
+	user := mustUser(t, s, "ada")
+	conv := mustConversation(t, s, user.ID, "doomed")
+	mustMessage(t, s, conv.ID, "user", "hello")
+
+	if err := s.DeleteConversation(ctx, conv.ID, user.ID); err != nil {
+		t.Fatalf("DeleteConversation: %v", err)
+	}
+	if _, err := s.GetConversation(ctx, conv.ID, user.ID); !isNotFound(err) {
+		t.Fatalf("deleted conversation: want ErrNotFound, got %v", err)
+	}
+	if _, err := s.GetMessages(ctx, conv.ID, user.ID); !isNotFound(err) {
+		t.Fatalf("deleted conversation's messages: want ErrNotFound, got %v", err)
+	}
+	if err := s.DeleteConversation(ctx, conv.ID, user.ID); err != nil {
+		t.Fatalf("deleting a missing conversation should be a no-op: %v", err)
+	}
+}
+
+func testMessages(t *testing.T, s db.Store) {
+	ctx := context.Background()
+	user := mustUser(t, s, "ada")
+	conv := mustConversation(t, s, user.ID, "chat")
+
+	messages, err := s.GetMessages(ctx, conv.ID, user.ID)
+	if err != nil || len(messages) != 0 {
+		t.Fatalf("GetMessages on a new conversation = %+v, %v", messages, err)
+	}
+
+	first := mustMessage(t, s, conv.ID, "user", "hi")
+	if first.ID == uuid.Nil || first.ConversationID != conv.ID || first.Role != "user" || first.Content != "hi" || first.Timestamp.IsZero() || first.Emotions != nil {
+		t.Fatalf("AddMessage returned %+v", first)
+	}
+	tick()
+	emotions := map[string]float64{"Joy": 0.61, "Anxiety": 0.125}
+	second, err := s.AddMessage(ctx, conv.ID, "assistant", "hello", emotions)
+	if err != nil {
+		t.Fatalf("AddMessage with emotions: %v", err)
+	}
+	if len(second.Emotions) != 2 || second.Emotions["Joy"] != 0.61 {
+		t.Fatalf("AddMessage returned emotions %v", second.Emotions)
+	}
+	// The store keeps its own copy
+	emotions["Joy"] = 0
+
+	messages, err = s.GetMessages(ctx, conv.ID, user.ID)
+	if err != nil {
+		t.Fatalf("GetMessages: %v", err)
+	}
+	if len(messages) != 2 || messages[0].Content != "hi" || messages[1].Content != "hello" {
+		t.Fatalf("GetMessages should return messages oldest first, got %+v", messages)
+	}
+	if messages[0].Emotions != nil {
+		t.Fatalf("a message saved without emotions read back with %v", messages[0].Emotions)
+	}
+	if messages[1].Emotions["Joy"] != 0.61 || messages[1].Emotions["Anxiety"] != 0.125 {
+		t.Fatalf("GetMessages returned emotions %v", messages[1].Emotions)
+	}
+
+	page, err := s.ListMessages(ctx, conv.ID, user.ID, db.MessagePage{Limit: 10})
+	if err != nil {
+		t.Fatalf("ListMessages: %v", err)
+	}
+	if len(page) != 2 || page[1].Emotions["Joy"] != 0.61 {
+		t.Fatalf("ListMessages returned %+v", page)
+	}
+}
+
+func testMessageNeedsConversation(t *testing.T, s db.Store) {
+	if _, err := s.AddMessage(context.Background(), uuid.New(), "user", "hello?", nil); err == nil {
+		t.Fatal("AddMessage to a missing conversation should fail")
+	}
+}
+
+func testMessagePages(t *testing.T, s db.Store) {
+	ctx := context.Background()
+	user := mustUser(t, s, "ada")
+	other := mustUser(t, s, "mallory")
+	conv := mustConversation(t, s, user.ID, "long chat")
+	var all []db.Message
+	for _, content := range []string{"1", "2", "3", "4", "5", "6", "7"} {
+		all = append(all, *mustMessage(t, s, conv.ID, "user", content))
+		tick()
+	}
+
+	contents := func(messages []db.Message) string {
+		var out string
+		for _, msg := range messages {
+			out += msg.Content
+		}
+		return out
+	}
+	list := func(page db.MessagePage) string {
+		t.Helper()
+		messages, err := s.ListMessages(ctx, conv.ID, user.ID, page)
+		if err != nil {
+			t.Fatalf("ListMessages(%+v): %v", page, err)
+		}
+		return contents(messages)
+	}
+	cursor := func(i int) *db.Cursor {
+		c := db.MessageCursor(all[i])
+		return &c
+	}
+
+	if got := list(db.MessagePage{Limit: 3}); got != "567" {
+		t.Fatalf("newest page = %q, want 567", got)
+	}
+	if got := list(db.MessagePage{Limit: 3, Before: cursor(4)}); got != "234" {
+		t.Fatalf("page before 5 = %q, want 234", got)
+	}
+	if got := list(db.MessagePage{Limit: 3, Before: cursor(1)}); got != "1" {
+		t.Fatalf("page before 2 = %q, want 1", got)
+	}
+	if got := list(db.MessagePage{Limit: 3, After: cursor(1)}); got != "345" {
+		t.Fatalf("page after 2 = %q, want 345", got)
+	}
+	if got := list(db.MessagePage{Limit: 3, After: cursor(6)}); got != "" {
+		t.Fatalf("page after the newest = %q, want nothing", got)
+	}
+	if got := list(db.MessagePage{Limit: 100}); got != "1234567" {
+		t.Fatalf("a page larger than the transcript = %q, want 1234567", got)
+	}
+
+	if _, err := s.ListMessages(ctx, conv.ID, user.ID, db.MessagePage{Limit: 3, After: cursor(1), Before: cursor(5)}); err == nil {
+		t.Fatal("ListMessages with both after and before should fail")
+	}
+	if _, err := s.ListMessages(ctx, conv.ID, other.ID, db.MessagePage{Limit: 3}); !isNotFound(err) {
+		t.Fatalf("ListMessages as another user: want ErrNotFound, got %v", err)
+	}
+}
+
+func testCountMessages(t *testing.T, s db.Store) {
+	ctx := context.Background()
+	user := mustUser(t, s, "ada")
+	conv := mustConversation(t, s, user.ID, "chat")
+	empty := mustConversation(t, s, user.ID, "empty")
+	for _, content := range []string{"1", "2", "3"} {
+		mustMessage(t, s, conv.ID, "user", content)
+	}
+
+	if n, err := s.CountMessages(ctx, conv.ID); err != nil || n != 3 {
+		t.Fatalf("CountMessages = %d, %v; want 3", n, err)
+	}
+	if n, err := s.CountMessages(ctx, empty.ID); err != nil || n != 0 {
+		t.Fatalf("CountMessages on an empty conversation = %d, %v; want 0", n, err)
+	}
+}
+
+func testContextInjections(t *testing.T, s db.Store) {
+	ctx := context.Background()
+	user := mustUser(t, s, "ada")
+	other := mustUser(t, s, "mallory")
+	conv := mustConversation(t, s, user.ID, "chat")
+	msg := mustMessage(t, s, conv.ID, "user", "I feel stuck")
+
+	first, err := s.LogContextInjection(ctx, conv.ID, &msg.ID, "Slow down", "temporary", "user sounds anxious")
+	if err != nil {
+		t.Fatalf("LogContextInjection: %v", err)
+	}
+	if first.ID == uuid.Nil || first.ConversationID != conv.ID || first.MessageID == nil || *first.MessageID != msg.ID || first.CreatedAt.IsZero() {
+		t.Fatalf("LogContextInjection returned %+v", first)
+	}
+	tick()
+	if _, err := s.LogContextInjection(ctx, conv.ID, nil, "Be warm", "persistent", ""); err != nil {
+		t.Fatalf("LogContextInjection without a message: %v", err)
+	}
+
+	injections, err := s.ListContextInjections(ctx, conv.ID, user.ID)
+	if err != nil {
+		t.Fatalf("ListContextInjections: %v", err)
+	}
+	if len(injections) != 2 || injections[0].ContextText != "Slow down" || injections[0].Reasoning != "user sounds anxious" || injections[1].ContextType != "persistent" || injections[1].MessageID != nil {
+		t.Fatalf("ListContextInjections should return injections oldest first, got %+v", injections)
+	}
+
+	if injections, err := s.ListContextInjections(ctx, conv.ID, other.ID); err != nil || len(injections) != 0 {
+		t.Fatalf("ListContextInjections as another user = %+v, %v; want none", injections, err)
+	}
+	if _, err := s.LogContextInjection(ctx, uuid.New(), nil, "Hello", "temporary", ""); err == nil {
+		t.Fatal("LogContextInjection for a missing conversation should fail")
+	}
+
+	if err := s.DeleteConversation(ctx, conv.ID, user.ID); err != nil {
+		t.Fatalf("DeleteConversation: %v", err)
+	}
+	if injections, err := s.ListContextInjections(ctx, conv.ID, user.ID); err != nil || len(injections) != 0 {
+		t.Fatalf("a deleted conversation's injections = %+v, %v; want none", injections, err)
+	}
+}
+
+func testVoices(t *testing.T, s db.Store) {
+	ctx := context.Background()
+	want := testVoice("guide")
+	created, err := s.CreateVoice(ctx, want)
+	if err != nil {
+		t.Fatalf("CreateVoice: %v", err)
+	}
+	if created.ID == uuid.Nil || created.CreatedAt.IsZero() || created.UpdatedAt.IsZero() {
+		t.Fatalf("CreateVoice didn't set id and timestamps: %+v", created)
+	}
+	if created.Name != want.Name || created.Prompt != want.Prompt || created.HumeVoiceName != want.HumeVoiceName ||
+		created.Temperature != want.Temperature || created.GreetingMessage != want.GreetingMessage ||
+		created.InactivityTimeoutSecs != want.InactivityTimeoutSecs || created.HumeConfigVersion == nil ||
+		*created.HumeConfigVersion != *want.HumeConfigVersion || created.HumePromptVersion != nil {
+		t.Fatalf("CreateVoice returned %+v", created)
+	}
+
+	got, err := s.GetVoice(ctx, created.ID)
+	if err != nil || got.Name != "guide" {
+		t.Fatalf("GetVoice = %+v, %v", got, err)
+	}
+	if _, err := s.GetVoice(ctx, uuid.New()); !isNotFound(err) {
+		t.Fatalf("GetVoice for a missing voice: want ErrNotFound, got %v", err)
+	}
+
+	tick()
+	update := *created
+	update.Name = "renamed"
+	update.Temperature = 0.25
+	update.MaxDurationSecs = 600
+	updated, err := s.UpdateVoice(ctx, created.ID, &update)
+	if err != nil {
+		t.Fatalf("UpdateVoice: %v", err)
+	}
+	if updated.ID != created.ID || updated.Name != "renamed" || updated.Temperature != 0.25 || updated.MaxDurationSecs != 600 {
+		t.Fatalf("UpdateVoice returned %+v", updated)
+	}
+	if !updated.CreatedAt.Equal(created.CreatedAt) || !updated.UpdatedAt.After(created.UpdatedAt) {
+		t.Fatalf("UpdateVoice should keep created_at and bump updated_at: %+v", updated)
+	}
+	if _, err := s.UpdateVoice(ctx, uuid.New(), &update); !isNotFound(err) {
+		t.Fatalf("UpdateVoice for a missing voice: want ErrNotFound, got %v", err)
+	}
+
+	newest := mustVoice(t, s, "newest")
+	voices, err := s.ListVoices(ctx)
+	if err != nil {
+		t.Fatalf("ListVoices: %v", err)
+	}
+	if len(voices) != 2 || voices[0].ID != newest.ID {
+		t.Fatalf("ListVoices should return 2 voices newest first, got %+v", voices)
+	}
+
+	if err := s.DeleteVoice(ctx, created.ID); err != nil {
+		t.Fatalf("DeleteVoice: %v", err)
+	}
+	if _, err := s.GetVoice(ctx, created.ID); !isNotFound(err) {
+		t.Fatalf("deleted voice: want ErrNotFound, got %v", err)
+	}
+}
+
+func testDeleteVoiceKeepsConversations(t *testing.T, s db.Store) {
+	ctx := context.Background()
+	user := mustUser(t, s, "ada")
+	voice := mustVoice(t, s, "guide")
+	conv := mustConversation(t, s, user.ID, "chat")
+	if err := s.SetConversationVoice(ctx, conv.ID, user.ID, voice.ID); err != nil {
+		t.Fatalf("SetConversationVoice: %v", err)
+	}
+
+	if err := s.DeleteVoice(ctx, voice.ID); err != nil {
+		t.Fatalf("DeleteVoice: %v", err)
+	}
+	got, err := s.GetConversation(ctx, conv.ID, user.ID)
+	if err != nil {
+		t.Fatalf("the conversation should survive its voice: %v", err)
+	}
+	if got.VoiceID != nil {
+		t.Fatalf("conversation still points at the deleted voice %s", *got.VoiceID)
+	}
+}
+
+func testVoicePreviews(t *testing.T, s db.Store) {
+	ctx := context.Background()
+	user := mustUser(t, s, "ada")
+	key := strings.Repeat("a", 64)
+	other := strings.Repeat("b", 64)
+
+	first, err := s.CreateVoicePreview(ctx, &db.VoicePreview{
+		CacheKey:     key,
+		Description:  "warm and low",
+		SampleText:   "Hello",
+		GenerationID: "gen-1",
+		MimeType:     "audio/wav",
+		Audio:        []byte("RIFF1"),
+		CreatedBy:    &user.ID,
+	})
+	if err != nil {
+		t.Fatalf("CreateVoicePreview: %v", err)
+	}
+	if first.ID == uuid.Nil || first.CreatedAt.IsZero() || first.GenerationID != "gen-1" {
+		t.Fatalf("CreateVoicePreview returned %+v", first)
+	}
+	tick()
+	second, err := s.CreateVoicePreview(ctx, &db.VoicePreview{CacheKey: key, Description: "warm and low", SampleText: "Hello", GenerationID: "gen-2", MimeType: "audio/wav", Audio: []byte("RIFF2")})
+	if err != nil {
+		t.Fatalf("CreateVoicePreview: %v", err)
+	}
+	if _, err := s.CreateVoicePreview(ctx, &db.VoicePreview{CacheKey: other, Description: "bright", SampleText: "Hello", GenerationID: "gen-3", MimeType: "audio/wav", Audio: []byte("RIFF3")}); err != nil {
+		t.Fatalf("CreateVoicePreview: %v", err)
+	}
+
+	previews, err := s.ListVoicePreviews(ctx, key)
+	if err != nil {
+		t.Fatalf("ListVoicePreviews: %v", err)
+	}
+	if len(previews) != 2 || previews[0].ID != first.ID || previews[1].ID != second.ID {
+		t.Fatalf("ListVoicePreviews should return the key's previews oldest first, got %+v", previews)
+	}
+	if previews[0].Audio != nil || previews[0].CreatedBy == nil || *previews[0].CreatedBy != user.ID {
+		t.Fatalf("ListVoicePreviews returned %+v, want no audio and the creator", previews[0])
+	}
+
+	if err := s.MarkVoicePreviewSaved(ctx, second.ID, "hume-voice-1"); err != nil {
+		t.Fatalf("MarkVoicePreviewSaved: %v", err)
+	}
+	got, err := s.GetVoicePreview(ctx, second.ID)
+	if err != nil {
+		t.Fatalf("GetVoicePreview: %v", err)
+	}
+	if string(got.Audio) != "RIFF2" || got.SavedVoiceID != "hume-voice-1" || got.CacheKey != key {
+		t.Fatalf("GetVoicePreview returned %+v", got)
+	}
+	if _, err := s.GetVoicePreview(ctx, uuid.New()); !isNotFound(err) {
+		t.Fatalf("GetVoicePreview of a missing preview: want ErrNotFound, got %v", err)
+	}
+
+	if err := s.DeleteVoicePreviews(ctx, key); err != nil {
+		t.Fatalf("DeleteVoicePreviews: %v", err)
+	}
+	if previews, err := s.ListVoicePreviews(ctx, key); err != nil || len(previews) != 0 {
+		t.Fatalf("ListVoicePreviews after delete = %+v, %v; want none", previews, err)
+	}
+	if previews, err := s.ListVoicePreviews(ctx, other); err != nil || len(previews) != 1 {
+		t.Fatalf("DeleteVoicePreviews touched another key: %+v, %v", previews, err)
+	}
+}
+
+func testVoiceRevisions(t *testing.T, s db.Store) {
+	ctx := context.Background()
+	editor := mustUser(t, s, "ada")
+	voice := mustVoice(t, s, "guide")
+
+	first, err := s.RecordVoiceRevision(ctx, voice, db.VoiceChange{Action: db.VoiceActionCreate})
+	if err != nil {
+		t.Fatalf("RecordVoiceRevision: %v", err)
+	}
+	if first.Revision != 1 || first.VoiceID != voice.ID || first.Prompt != voice.Prompt || first.Temperature != voice.Temperature ||
+		first.HumeConfigVersion == nil || *first.HumeConfigVersion != 2 || first.EditedBy != nil || first.CreatedAt.IsZero() {
+		t.Fatalf("RecordVoiceRevision returned %+v", first)
+	}
+
+	voice.Prompt = "You are kinder"
+	second, err := s.RecordVoiceRevision(ctx, voice, db.VoiceChange{Action: db.VoiceActionUpdate, Note: "kinder", EditedBy: &editor.ID})
+	if err != nil {
+		t.Fatalf("RecordVoiceRevision: %v", err)
+	}
+	if second.Revision != 2 || second.Note != "kinder" || second.EditedBy == nil || *second.EditedBy != editor.ID {
+		t.Fatalf("RecordVoiceRevision returned %+v", second)
+	}
+	if _, err := s.RecordVoiceRevision(ctx, &db.Voice{ID: uuid.New(), Name: "ghost"}, db.VoiceChange{Action: db.VoiceActionCreate}); err == nil {
+		t.Fatal("RecordVoiceRevision for a missing voice should fail")
+	}
+
+	revisions, err := s.ListVoiceRevisions(ctx, voice.ID)
+	if err != nil {
+		t.Fatalf("ListVoiceRevisions: %v", err)
+	}
+	if len(revisions) != 2 || revisions[0].Revision != 2 || revisions[1].Revision != 1 {
+		t.Fatalf("ListVoiceRevisions should return revisions newest first, got %+v", revisions)
+	}
+	if got, err := s.GetVoiceRevision(ctx, voice.ID, 1); err != nil || got.ID != first.ID || got.Prompt != "You are guide" {
+		t.Fatalf("GetVoiceRevision(1) = %+v, %v", got, err)
+	}
+	if got, err := s.GetLatestVoiceRevision(ctx, voice.ID); err != nil || got.ID != second.ID {
+		t.Fatalf("GetLatestVoiceRevision = %+v, %v; want revision 2", got, err)
+	}
+	if _, err := s.GetVoiceRevision(ctx, voice.ID, 3); !isNotFound(err) {
+		t.Fatalf("GetVoiceRevision of a missing revision: want ErrNotFound, got %v", err)
+	}
+
+	if err := s.DeleteUser(ctx, editor.ID); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if got, err := s.GetVoiceRevision(ctx, voice.ID, 2); err != nil || got.EditedBy != nil {
+		t.Fatalf("revision by a deleted user = %+v, %v; want no editor", got, err)
+	}
+
+	if err := s.DeleteVoice(ctx, voice.ID); err != nil {
+		t.Fatalf("DeleteVoice: %v", err)
+	}
+	if _, err := s.GetLatestVoiceRevision(ctx, voice.ID); !isNotFound(err) {
+		t.Fatalf("a deleted voice's revisions: want ErrNotFound, got %v", err)
+	}
+}
+
+func testReplacedHumeConfigs(t *testing.T, s db.Store) {
+	ctx := context.Background()
+	voice := mustVoice(t, s, "guide")
+
+	if err := s.RecordReplacedHumeConfig(ctx, &voice.ID, "config-old", "config-new", "config not found in Hume"); err != nil {
+		t.Fatalf("RecordReplacedHumeConfig: %v", err)
+	}
+	tick()
+	if err := s.RecordReplacedHumeConfig(ctx, nil, "config-deleted", "", "voice deleted"); err != nil {
+		t.Fatalf("RecordReplacedHumeConfig without a voice: %v", err)
+	}
+
+	configs, err := s.ListReplacedHumeConfigs(ctx, false)
+	if err != nil {
+		t.Fatalf("ListReplacedHumeConfigs: %v", err)
+	}
+	if len(configs) != 2 || configs[0].HumeConfigID != "config-old" || configs[0].ReplacedBy != "config-new" ||
+		configs[0].VoiceID == nil || *configs[0].VoiceID != voice.ID || configs[1].VoiceID != nil || configs[1].ReplacedBy != "" {
+		t.Fatalf("ListReplacedHumeConfigs should return configs oldest first, got %+v", configs)
+	}
+
+	if err := s.MarkHumeConfigCleanedUp(ctx, configs[0].ID); err != nil {
+		t.Fatalf("MarkHumeConfigCleanedUp: %v", err)
+	}
+	if got, err := s.GetReplacedHumeConfig(ctx, configs[0].ID); err != nil || got.CleanedUpAt == nil || got.Reason != "config not found in Hume" {
+		t.Fatalf("GetReplacedHumeConfig after cleanup = %+v, %v", got, err)
+	}
+	if pending, err := s.ListReplacedHumeConfigs(ctx, false); err != nil || len(pending) != 1 || pending[0].HumeConfigID != "config-deleted" {
+		t.Fatalf("ListReplacedHumeConfigs awaiting cleanup = %+v, %v; want only config-deleted", pending, err)
+	}
+	if all, err := s.ListReplacedHumeConfigs(ctx, true); err != nil || len(all) != 2 {
+		t.Fatalf("ListReplacedHumeConfigs including cleaned = %+v, %v; want both", all, err)
+	}
+	if _, err := s.GetReplacedHumeConfig(ctx, uuid.New()); !isNotFound(err) {
+		t.Fatalf("GetReplacedHumeConfig of a missing record: want ErrNotFound, got %v", err)
+	}
+
+	// The record outlives its voice
+	if err := s.DeleteVoice(ctx, voice.ID); err != nil {
+		t.Fatalf("DeleteVoice: %v", err)
+	}
+	if got, err := s.GetReplacedHumeConfig(ctx, configs[0].ID); err != nil || got.VoiceID != nil {
+		t.Fatalf("record of a deleted voice = %+v, %v; want it kept without the voice", got, err)
+	}
+}
+
+func testHumeSyncRuns(t *testing.T, s db.Store) {
+	ctx := context.Background()
+	admin := mustUser(t, s, "ada")
+
+	if run, err := s.GetLatestHumeSyncRun(ctx, db.HumeSyncKindSync); err != nil || run != nil {
+		t.Fatalf("GetLatestHumeSyncRun with no runs = %+v, %v; want nil", run, err)
+	}
+
+	run, err := s.StartHumeSyncRun(ctx, db.HumeSyncKindSync, false, 2, &admin.ID)
+	if err != nil {
+		t.Fatalf("StartHumeSyncRun: %v", err)
+	}
+	if run.Status != db.JobRunning || run.Total != 2 || run.Processed != 0 || run.Results == nil || run.StartedBy == nil || *run.StartedBy != admin.ID {
+		t.Fatalf("StartHumeSyncRun returned %+v", run)
+	}
+	if _, err := s.StartHumeSyncRun(ctx, db.HumeSyncKindSync, false, 1, nil); !errors.Is(err, db.ErrHumeSyncRunning) {
+		t.Fatalf("second StartHumeSyncRun = %v, want ErrHumeSyncRunning", err)
+	}
+	reconcile, err := s.StartHumeSyncRun(ctx, db.HumeSyncKindReconcile, true, 0, nil)
+	if err != nil {
+		t.Fatalf("a reconcile alongside a sync: %v", err)
+	}
+
+	voiceID := uuid.New()
+	if err := s.SetHumeSyncRunTotal(ctx, run.ID, 3); err != nil {
+		t.Fatalf("SetHumeSyncRunTotal: %v", err)
+	}
+	if err := s.AppendHumeSyncResult(ctx, run.ID, db.HumeSyncResult{VoiceID: &voiceID, Name: "guide", Status: "drift", Drift: []string{"prompt_mismatch"}}); err != nil {
+		t.Fatalf("AppendHumeSyncResult: %v", err)
+	}
+	if err := s.AppendHumeSyncResult(ctx, run.ID, db.HumeSyncResult{Name: "orphan", HumeConfigID: "config-1", Status: "error", Detail: "boom"}); err != nil {
+		t.Fatalf("AppendHumeSyncResult: %v", err)
+	}
+	if err := s.FinishHumeSyncRun(ctx, run.ID, db.JobFailed, "1 of 3 voices failed"); err != nil {
+		t.Fatalf("FinishHumeSyncRun: %v", err)
+	}
+
+	got, err := s.GetHumeSyncRun(ctx, run.ID)
+	if err != nil {
+		t.Fatalf("GetHumeSyncRun: %v", err)
+	}
+	if got.Status != db.JobFailed || got.Error != "1 of 3 voices failed" || got.FinishedAt == nil || got.Total != 3 || got.Processed != 2 {
+		t.Fatalf("GetHumeSyncRun returned %+v", got)
+	}
+	if len(got.Results) != 2 || got.Results[0].VoiceID == nil || *got.Results[0].VoiceID != voiceID ||
+		len(got.Results[0].Drift) != 1 || got.Results[1].HumeConfigID != "config-1" || got.Results[1].Detail != "boom" {
+		t.Fatalf("GetHumeSyncRun results %+v", got.Results)
+	}
+	if _, err := s.GetHumeSyncRun(ctx, uuid.New()); !isNotFound(err) {
+		t.Fatalf("GetHumeSyncRun of a missing run: want ErrNotFound, got %v", err)
+	}
+
+	// A finished run frees its kind
+	tick()
+	next, err := s.StartHumeSyncRun(ctx, db.HumeSyncKindSync, false, 0, nil)
+	if err != nil {
+		t.Fatalf("StartHumeSyncRun after the first finished: %v", err)
+	}
+	if latest, err := s.GetLatestHumeSyncRun(ctx, db.HumeSyncKindSync); err != nil || latest.ID != next.ID {
+		t.Fatalf("GetLatestHumeSyncRun = %+v, %v; want the newest sync", latest, err)
+	}
+
+	failed, err := s.FailInterruptedHumeSyncRuns(ctx)
+	if err != nil || failed != 2 {
+		t.Fatalf("FailInterruptedHumeSyncRuns = %d, %v; want the sync and the reconcile", failed, err)
+	}
+	if got, err := s.GetHumeSyncRun(ctx, reconcile.ID); err != nil || got.Status != db.JobFailed || got.Error == "" || got.FinishedAt == nil {
+		t.Fatalf("interrupted run = %+v, %v", got, err)
+	}
+
+	if err := s.DeleteUser(ctx, admin.ID); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if got, err := s.GetHumeSyncRun(ctx, run.ID); err != nil || got.StartedBy != nil {
+		t.Fatalf("run started by a deleted user = %+v, %v; want it kept without the user", got, err)
+	}
+}
+
+func mustGraphJob(t *testing.T, s db.Store, conversationID uuid.UUID) db.GraphExtractionJob {
+	t.Helper()
+	jobs, err := s.ListGraphExtractionJobs(context.Background(), "", 100)
+	if err != nil {
+		t.Fatalf("ListGraphExtractionJobs: %v", err)
+	}
+	for _, job := range jobs {
+		if job.ConversationID == conversationID {
+			return job
+		}
+	}
+	t.Fatalf("no graph job for conversation %s", conversationID)
+	return db.GraphExtractionJob{}
+}
+
+func mustClaim(t *testing.T, s db.Store) *db.GraphExtractionJob {
+	t.Helper()
+	job, err := s.ClaimGraphExtractionJob(context.Background())
+	if err != nil {
+		t.Fatalf("ClaimGraphExtractionJob: %v", err)
+	}
+	return job
+}
+
+func testGraphJobs(t *testing.T, s db.Store) {
+	ctx := context.Background()
+	user := mustUser(t, s, "ada")
+	first := mustConversation(t, s, user.ID, "first")
+	second := mustConversation(t, s, user.ID, "second")
+
+	if job := mustClaim(t, s); job != nil {
+		t.Fatalf("claimed %+v from an empty queue", job)
+	}
+	for _, conv := range []*db.Conversation{first, second} {
+		if err := s.EnqueueGraphExtraction(ctx, conv.ID, user.ID); err != nil {
+			t.Fatalf("EnqueueGraphExtraction: %v", err)
+		}
+		tick()
+	}
+
+	job := mustClaim(t, s)
+	if job == nil || job.ConversationID != first.ID || job.UserID != user.ID || job.Status != db.JobRunning || job.Attempts != 1 {
+		t.Fatalf("claimed %+v, want the first conversation's job on attempt 1", job)
+	}
+	if err := s.CompleteGraphExtractionJob(ctx, first.ID, 4, 3); err != nil {
+		t.Fatalf("CompleteGraphExtractionJob: %v", err)
+	}
+	if got := mustGraphJob(t, s, first.ID); got.Status != db.JobSucceeded || got.Entities != 4 || got.Relationships != 3 || got.CompletedAt == nil {
+		t.Fatalf("completed job %+v", got)
+	}
+
+	// A retry waits for its time; a failure without one is final
+	job = mustClaim(t, s)
+	if job == nil || job.ConversationID != second.ID {
+		t.Fatalf("claimed %+v, want the second conversation's job", job)
+	}
+	later := time.Now().Add(48 * time.Hour)
+	if err := s.FailGraphExtractionJob(ctx, second.ID, "extractor down", &later); err != nil {
+		t.Fatalf("FailGraphExtractionJob: %v", err)
+	}
+	if got := mustGraphJob(t, s, second.ID); got.Status != db.JobRetrying || got.LastError != "extractor down" || got.CompletedAt != nil {
+		t.Fatalf("job to retry %+v", got)
+	}
+	if job := mustClaim(t, s); job != nil {
+		t.Fatalf("claimed %+v before its retry time", job)
+	}
+	earlier := time.Now().Add(-48 * time.Hour)
+	if err := s.FailGraphExtractionJob(ctx, second.ID, "extractor down", &earlier); err != nil {
+		t.Fatalf("FailGraphExtractionJob: %v", err)
+	}
+	if job := mustClaim(t, s); job == nil || job.ConversationID != second.ID || job.Attempts != 2 {
+		t.Fatalf("claimed %+v, want the due retry on attempt 2", job)
+	}
+	if err := s.FailGraphExtractionJob(ctx, second.ID, "still down", nil); err != nil {
+		t.Fatalf("FailGraphExtractionJob: %v", err)
+	}
+	if got := mustGraphJob(t, s, second.ID); got.Status != db.JobFailed || got.LastError != "still down" || got.CompletedAt == nil {
+		t.Fatalf("failed job %+v", got)
+	}
+
+	counts, err := s.CountGraphExtractionJobs(ctx)
+	if err != nil || len(counts) != 2 || counts[db.JobSucceeded] != 1 || counts[db.JobFailed] != 1 {
+		t.Fatalf("CountGraphExtractionJobs = %v, %v", counts, err)
+	}
+	jobs, err := s.ListGraphExtractionJobs(ctx, "", 10)
+	if err != nil || len(jobs) != 2 || jobs[0].ConversationID != second.ID {
+		t.Fatalf("ListGraphExtractionJobs should return jobs most recently updated first, got %+v, %v", jobs, err)
+	}
+	if jobs, err := s.ListGraphExtractionJobs(ctx, db.JobSucceeded, 10); err != nil || len(jobs) != 1 || jobs[0].ConversationID != first.ID {
+		t.Fatalf("ListGraphExtractionJobs(succeeded) = %+v, %v", jobs, err)
+	}
+	if jobs, err := s.ListGraphExtractionJobs(ctx, "", 1); err != nil || len(jobs) != 1 {
+		t.Fatalf("ListGraphExtractionJobs with limit 1 = %+v, %v", jobs, err)
+	}
+
+	// Enqueuing a finished job starts it over
+	if err := s.EnqueueGraphExtraction(ctx, second.ID, user.ID); err != nil {
+		t.Fatalf("EnqueueGraphExtraction: %v", err)
+	}
+	if got := mustGraphJob(t, s, second.ID); got.Status != db.JobPending || got.Attempts != 0 || got.LastError != "" || got.CompletedAt != nil {
+		t.Fatalf("re-enqueued job %+v", got)
+	}
+
+	// Jobs go with their conversation
+	if err := s.DeleteConversation(ctx, first.ID, user.ID); err != nil {
+		t.Fatalf("DeleteConversation: %v", err)
+	}
+	if jobs, err := s.ListGraphExtractionJobs(ctx, "", 10); err != nil || len(jobs) != 1 {
+		t.Fatalf("jobs after deleting a conversation = %+v, %v; want 1", jobs, err)
+	}
+}
+
+func testGraphJobRerun(t *testing.T, s db.Store) {
+	ctx := context.Background()
+	user := mustUser(t, s, "ada")
+	conv := mustConversation(t, s, user.ID, "chat")
+	enqueue := func() {
+		t.Helper()
+		if err := s.EnqueueGraphExtraction(ctx, conv.ID, user.ID); err != nil {
+			t.Fatalf("EnqueueGraphExtraction: %v", err)
+		}
+	}
+
+	enqueue()
+	mustClaim(t, s)
+	enqueue()
+	if got := mustGraphJob(t, s, conv.ID); got.Status != db.JobRunning || !got.Rerun || got.Attempts != 1 {
+		t.Fatalf("job enqueued while running %+v, want it still running and flagged", got)
+	}
+	if job := mustClaim(t, s); job != nil {
+		t.Fatalf("claimed %+v while it was running", job)
+	}
+
+	if err := s.CompleteGraphExtractionJob(ctx, conv.ID, 1, 0); err != nil {
+		t.Fatalf("CompleteGraphExtractionJob: %v", err)
+	}
+	if got := mustGraphJob(t, s, conv.ID); got.Status != db.JobPending || got.Rerun || got.Attempts != 0 || got.CompletedAt != nil {
+		t.Fatalf("flagged job after completing %+v, want it pending again", got)
+	}
+
+	mustClaim(t, s)
+	enqueue()
+	if err := s.FailGraphExtractionJob(ctx, conv.ID, "extractor down", nil); err != nil {
+		t.Fatalf("FailGraphExtractionJob: %v", err)
+	}
+	if got := mustGraphJob(t, s, conv.ID); got.Status != db.JobPending || got.Rerun || got.Attempts != 0 {
+		t.Fatalf("flagged job after failing %+v, want it pending again", got)
+	}
+
+	mustClaim(t, s)
+	enqueue()
+	if requeued, err := s.RequeueRunningGraphExtractionJobs(ctx); err != nil || requeued != 1 {
+		t.Fatalf("RequeueRunningGraphExtractionJobs = %d, %v; want 1", requeued, err)
+	}
+	if got := mustGraphJob(t, s, conv.ID); got.Status != db.JobRetrying || got.Rerun {
+		t.Fatalf("requeued job %+v, want it retrying without the flag", got)
+	}
+}
+
+func testSearchMessages(t *testing.T, s db.Store) {
+	ctx := context.Background()
+	user := mustUser(t, s, "ada")
+	other := mustUser(t, s, "mallory")
+	trip := mustConversation(t, s, user.ID, "trip")
+	mustMessage(t, s, trip.ID, "user", "We flew to Lisbon in spring")
+	tick()
+	reply := mustMessage(t, s, trip.ID, "assistant", "Lisbon sounds lovely")
+	mustMessage(t, s, trip.ID, "user", "Porto was rainy")
+	theirs := mustConversation(t, s, other.ID, "theirs")
+	mustMessage(t, s, theirs.ID, "user", "Lisbon again")
+
+	hits, err := s.SearchMessages(ctx, user.ID, "lisbon", 10)
+	if err != nil {
+		t.Fatalf("SearchMessages: %v", err)
+	}
+	if len(hits) != 2 {
+		t.Fatalf("SearchMessages found %+v, want the user's 2 messages", hits)
+	}
+	for _, hit := range hits {
+		if hit.ConversationID != trip.ID || hit.ConversationTitle != "trip" || hit.Rank <= 0 {
+			t.Fatalf("SearchMessages hit %+v", hit)
+		}
+		var matched []string
+		for _, part := range hit.Snippet {
+			if part.Match {
+				matched = append(matched, part.Text)
+			}
+		}
+		if len(matched) != 1 || matched[0] != "Lisbon" {
+			t.Fatalf("snippet %+v, want Lisbon highlighted", hit.Snippet)
+		}
+	}
+
+	if hits, err := s.SearchMessages(ctx, user.ID, "lisbon -spring", 10); err != nil || len(hits) != 1 || hits[0].MessageID != reply.ID {
+		t.Fatalf("SearchMessages excluding a word = %+v, %v; want only the reply", hits, err)
+	}
+	if hits, err := s.SearchMessages(ctx, user.ID, "spring OR porto", 10); err != nil || len(hits) != 2 {
+		t.Fatalf("SearchMessages with OR = %+v, %v; want 2", hits, err)
+	}
+	if hits, err := s.SearchMessages(ctx, user.ID, "lisbon", 1); err != nil || len(hits) != 1 {
+		t.Fatalf("SearchMessages with limit 1 = %+v, %v", hits, err)
+	}
+	if hits, err := s.SearchMessages(ctx, other.ID, "spring", 10); err != nil || len(hits) != 0 {
+		t.Fatalf("another user's search found %+v, %v; want nothing", hits, err)
+	}
+}
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// VoicePreview is one cached TTS generation for a voice description
+type VoicePreview struct {
+	ID           uuid.UUID  `json:"id"`
+	CacheKey     string     `json:"-"`
+	Description  string     `json:"description"`
+	SampleText   string     `json:"sample_text"`
+	GenerationID string     `json:"generation_id"`
+	MimeType     string     `json:"mime_type"`
+	Audio        []byte     `json:"-"`
+	SavedVoiceID string     `json:"saved_voice_id,omitempty"`
+	CreatedBy    *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// CreateVoicePreview caches a generation
+func (db *DB) CreateVoicePreview(ctx context.Context, p *VoicePreview) (*VoicePreview, error) {
+	created := *p
+	err := db.Pool.QueryRow(ctx,
+		`INSERT INTO voice_previews (cache_key, description, sample_text, generation_id, mime_type, audio, created_by)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 RETURNING id, created_at`,
+		p.CacheKey, p.Description, p.SampleText, p.GenerationID, p.MimeType, p.Audio, p.CreatedBy,
+	).Scan(&created.ID, &created.CreatedAt)
+	return &created, err
+}
+
+// ListVoicePreviews returns the cached generations for a key, oldest first,
+// without their audio
+func (db *DB) ListVoicePreviews(ctx context.Context, cacheKey string) ([]VoicePreview, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT id, cache_key, description, sample_text, generation_id, mime_type, COALESCE(saved_voice_id, ''), created_by, created_at
+		 FROM voice_previews WHERE cache_key = $1
+		 ORDER BY created_at`,
+		cacheKey,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var previews []VoicePreview
+	for rows.Next() {
+		var p VoicePreview
+		if err := rows.Scan(&p.ID, &p.CacheKey, &p.Description, &p.SampleText, &p.GenerationID, &p.MimeType, &p.SavedVoiceID, &p.CreatedBy, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		previews = append(previews, p)
+	}
+	return previews, rows.Err()
+}
+
+// GetVoicePreview fetches a cached generation including its audio
+func (db *DB) GetVoicePreview(ctx context.Context, id uuid.UUID) (*VoicePreview, error) {
+	var p VoicePreview
+	err := db.Pool.QueryRow(ctx,
+		`SELECT id, cache_key, description, sample_text, generation_id, mime_type, audio, COALESCE(saved_voice_id, ''), created_by, created_at
+		 FROM voice_previews WHERE id = $1`,
+		id,
+	).Scan(&p.ID, &p.CacheKey, &p.Description, &p.SampleText, &p.GenerationID, &p.MimeType, &p.Audio, &p.SavedVoiceID, &p.CreatedBy, &p.CreatedAt)
+	return &p, err
+}
+
+// MarkVoicePreviewSaved records the Hume voice a generation was saved as
+func (db *DB) MarkVoicePreviewSaved(ctx context.Context, id uuid.UUID, humeVoiceID string) error {
+	_, err := db.Pool.Exec(ctx,
+		`UPDATE voice_previews SET saved_voice_id = $2 WHERE id = $1`,
+		id, humeVoiceID,
+	)
+	return err
+}
+
+// DeleteVoicePreviews drops every cached generation for a key
+func (db *DB) DeleteVoicePreviews(ctx context.Context, cacheKey string) error {
+	_, err := db.Pool.Exec(ctx, `DELETE FROM voice_previews WHERE cache_key = $1`, cacheKey)
+	return err
+}
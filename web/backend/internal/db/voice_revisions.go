@@ -0,0 +1,136 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Voice revision actions
+const (
+	VoiceActionCreate   = "create"
+	VoiceActionUpdate   = "update"
+	VoiceActionSync     = "sync"
+	VoiceActionRollback = "rollback"
+)
+
+// VoiceChange describes who changed a voice and why, for its revision
+type VoiceChange struct {
+	Action   string
+	Note     string
+	EditedBy *uuid.UUID
+}
+
+// VoiceRevision is a numbered snapshot of a voice, starting at 1
+type VoiceRevision struct {
+	ID                    uuid.UUID  `json:"id"`
+	VoiceID               uuid.UUID  `json:"voice_id"`
+	Revision              int        `json:"revision"`
+	Action                string     `json:"action"`
+	Note                  string     `json:"note,omitempty"`
+	Name                  string     `json:"name"`
+	Description           string     `json:"description"`
+	Prompt                string     `json:"prompt"`
+	VoiceDescription      string     `json:"voice_description"`
+	EVIVersion            string     `json:"evi_version"`
+	LanguageModelProvider string     `json:"language_model_provider"`
+	LanguageModelResource string     `json:"language_model_resource"`
+	Temperature           float64    `json:"temperature"`
+	GreetingMessage       string     `json:"greeting_message"`
+	InactivityMessage     string     `json:"inactivity_message"`
+	MaxDurationMessage    string     `json:"max_duration_message"`
+	InactivityTimeoutSecs int        `json:"inactivity_timeout_secs"`
+	MaxDurationSecs       int        `json:"max_duration_secs"`
+	HumeVoiceID           string     `json:"hume_voice_id"`
+	HumeVoiceProvider     string     `json:"hume_voice_provider"`
+	HumeVoiceName         string     `json:"hume_voice_name"`
+	HumePromptID          string     `json:"hume_prompt_id"`
+	HumePromptVersion     *int       `json:"hume_prompt_version"`
+	HumeConfigID          string     `json:"hume_config_id"`
+	HumeConfigVersion     *int       `json:"hume_config_version"`
+	EditedBy              *uuid.UUID `json:"edited_by,omitempty"`
+	CreatedAt             time.Time  `json:"created_at"`
+}
+
+const voiceRevisionColumns = `id, voice_id, revision, action, COALESCE(note, ''), name, COALESCE(description, ''), prompt, COALESCE(voice_description, ''), COALESCE(evi_version, ''), COALESCE(language_model_provider, ''), COALESCE(language_model_resource, ''), temperature, greeting_message, inactivity_message, max_duration_message, inactivity_timeout_secs, max_duration_secs, COALESCE(hume_voice_id, ''), COALESCE(hume_voice_provider, ''), COALESCE(hume_voice_name, ''), COALESCE(hume_prompt_id, ''), hume_prompt_version, COALESCE(hume_config_id, ''), hume_config_version, edited_by, created_at`
+
+func scanVoiceRevision(row pgx.Row) (*VoiceRevision, error) {
+	var rev VoiceRevision
+	err := row.Scan(&rev.ID, &rev.VoiceID, &rev.Revision, &rev.Action, &rev.Note, &rev.Name, &rev.Description, &rev.Prompt, &rev.VoiceDescription, &rev.EVIVersion, &rev.LanguageModelProvider, &rev.LanguageModelResource, &rev.Temperature, &rev.GreetingMessage, &rev.InactivityMessage, &rev.MaxDurationMessage, &rev.InactivityTimeoutSecs, &rev.MaxDurationSecs, &rev.HumeVoiceID, &rev.HumeVoiceProvider, &rev.HumeVoiceName, &rev.HumePromptID, &rev.HumePromptVersion, &rev.HumeConfigID, &rev.HumeConfigVersion, &rev.EditedBy, &rev.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+// RecordVoiceRevision snapshots the voice as its next revision. The voice row
+// is locked while the number is picked, so concurrent edits (e.g. a bulk sync
+// and an admin edit) get consecutive revisions instead of colliding.
+func (db *DB) RecordVoiceRevision(ctx context.Context, voice *Voice, change VoiceChange) (*VoiceRevision, error) {
+	var rev *VoiceRevision
+	err := pgx.BeginFunc(ctx, db.Pool, func(tx pgx.Tx) error {
+		var id uuid.UUID
+		if err := tx.QueryRow(ctx, `SELECT id FROM voices WHERE id = $1 FOR UPDATE`, voice.ID).Scan(&id); err != nil {
+			return err
+		}
+
+		var err error
+		rev, err = scanVoiceRevision(tx.QueryRow(ctx,
+			`INSERT INTO voice_revisions (voice_id, revision, action, note, name, description, prompt, voice_description, evi_version, language_model_provider, language_model_resource, temperature, greeting_message, inactivity_message, max_duration_message, inactivity_timeout_secs, max_duration_secs, hume_voice_id, hume_voice_provider, hume_voice_name, hume_prompt_id, hume_prompt_version, hume_config_id, hume_config_version, edited_by)
+			 SELECT $1, COALESCE(MAX(revision), 0) + 1, $2, NULLIF($3, ''), $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24
+			 FROM voice_revisions WHERE voice_id = $1
+			 RETURNING `+voiceRevisionColumns,
+			voice.ID, change.Action, change.Note, voice.Name, voice.Description, voice.Prompt, voice.VoiceDescription, voice.EVIVersion, voice.LanguageModelProvider, voice.LanguageModelResource, voice.Temperature, voice.GreetingMessage, voice.InactivityMessage, voice.MaxDurationMessage, voice.InactivityTimeoutSecs, voice.MaxDurationSecs, voice.HumeVoiceID, voice.HumeVoiceProvider, voice.HumeVoiceName, voice.HumePromptID, voice.HumePromptVersion, voice.HumeConfigID, voice.HumeConfigVersion, change.EditedBy,
+		))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rev, nil
+}
+
+// ListVoiceRevisions returns a voice's revisions, newest first
+func (db *DB) ListVoiceRevisions(ctx context.Context, voiceID uuid.UUID) ([]VoiceRevision, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT `+voiceRevisionColumns+`
+		 FROM voice_revisions WHERE voice_id = $1
+		 ORDER BY revision DESC`,
+		voiceID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []VoiceRevision
+	for rows.Next() {
+		rev, err := scanVoiceRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, *rev)
+	}
+	return revisions, rows.Err()
+}
+
+// GetVoiceRevision fetches one revision of a voice
+func (db *DB) GetVoiceRevision(ctx context.Context, voiceID uuid.UUID, revision int) (*VoiceRevision, error) {
+	return scanVoiceRevision(db.Pool.QueryRow(ctx,
+		`SELECT `+voiceRevisionColumns+`
+		 FROM voice_revisions WHERE voice_id = $1 AND revision = $2`,
+		voiceID, revision,
+	))
+}
+
+// GetLatestVoiceRevision fetches a voice's newest revision
+func (db *DB) GetLatestVoiceRevision(ctx context.Context, voiceID uuid.UUID) (*VoiceRevision, error) {
+	return scanVoiceRevision(db.Pool.QueryRow(ctx,
+		`SELECT `+voiceRevisionColumns+`
+		 FROM voice_revisions WHERE voice_id = $1
+		 ORDER BY revision DESC LIMIT 1`,
+		voiceID,
+	))
+}
@@ -4,10 +4,15 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
 )
 
+// connectTimeout bounds the startup connectivity check so an unreachable
+// Memgraph doesn't hold up the server
+const connectTimeout = 10 * time.Second
+
 // Client wraps Memgraph connection using Neo4j driver (Bolt protocol)
 type Client struct {
 	driver neo4j.DriverWithContext
@@ -24,8 +29,10 @@ func NewClient(uri, username, password string) (*Client, error) {
 	}
 
 	// Verify connectivity
-	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	defer cancel()
 	if err := driver.VerifyConnectivity(ctx); err != nil {
+		driver.Close(context.Background())
 		return nil, fmt.Errorf("failed to verify connectivity: %w", err)
 	}
 
@@ -55,6 +62,29 @@ func (c *Client) ExecuteWrite(ctx context.Context, cypher string, params map[str
 	return err
 }
 
+// ExecuteWriteCount executes a write query and returns how many rows it
+// returned, e.g. to tell whether its MATCH clauses found anything
+func (c *Client) ExecuteWriteCount(ctx context.Context, cypher string, params map[string]interface{}) (int, error) {
+	session := c.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	count, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		result, err := tx.Run(ctx, cypher, params)
+		if err != nil {
+			return nil, err
+		}
+		records, err := result.Collect(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return len(records), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count.(int), nil
+}
+
 // ExecuteRead executes a read query and returns results
 func (c *Client) ExecuteRead(ctx context.Context, cypher string, params map[string]interface{}) ([]map[string]interface{}, error) {
 	session := c.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
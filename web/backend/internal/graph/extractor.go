@@ -0,0 +1,285 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/hume-evi/web/internal/llm"
+)
+
+// UserEntity is the name extractors use to refer to the speaking user
+const UserEntity = "user"
+
+// Extractor turns a conversation into entities and relationships
+type Extractor interface {
+	Extract(ctx context.Context, messages []ConversationMessage) (GraphData, error)
+}
+
+// topicKeywords are the topics the keyword extractor looks for
+var topicKeywords = []string{"work", "stress", "moving", "house", "family", "health", "project"}
+
+// KeywordExtractor is the simple fallback: it tags known topic keywords
+type KeywordExtractor struct{}
+
+func (KeywordExtractor) Extract(ctx context.Context, messages []ConversationMessage) (GraphData, error) {
+	found := make(map[string]bool)
+	for _, msg := range messages {
+		content := strings.ToLower(msg.Content)
+		for _, keyword := range topicKeywords {
+			if strings.Contains(content, keyword) {
+				found[keyword] = true
+			}
+		}
+	}
+
+	topics := make([]string, 0, len(found))
+	for topic := range found {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+
+	data := GraphData{
+		Entities:      []ExtractedEntity{},
+		Relationships: []ExtractedRelationship{},
+	}
+	for _, topic := range topics {
+		data.Entities = append(data.Entities, ExtractedEntity{
+			Type:       "Topic",
+			Name:       topic,
+			Properties: map[string]string{},
+		})
+		data.Relationships = append(data.Relationships, ExtractedRelationship{
+			From:       UserEntity,
+			To:         topic,
+			Type:       "DISCUSSES",
+			Properties: map[string]string{},
+		})
+	}
+	return data, nil
+}
+
+var extractionPrompt = fmt.Sprintf(`You build a personal knowledge graph from conversations between a user and an AI companion.
+Extract the people, topics, emotions, places, events and goals that matter to the user, and how they relate.
+
+Rules:
+- Refer to the speaking user as "user" in relationships; don't emit an entity for them.
+- Entity names are short, lowercase and singular (e.g. "daughter", "moving_house", "stress").
+- Entity types must be one of: %s.
+- Relationship types must be one of: %s.
+- Every relationship's "from" and "to" must be "user" or the name of an extracted entity.
+- Property values are strings.
+- Only extract what the conversation states; don't speculate.
+
+Respond with JSON matching the provided schema.`,
+	strings.Join(sortedKeys(EntityLabels), ", "),
+	strings.Join(sortedKeys(RelationshipTypes), ", "))
+
+// extractionSchema constrains the model output to GraphData
+var extractionSchema = json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "entities": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "type": {"type": "string"},
+          "name": {"type": "string"},
+          "properties": {"type": "object", "additionalProperties": {"type": "string"}}
+        },
+        "required": ["type", "name"]
+      }
+    },
+    "relationships": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "from": {"type": "string"},
+          "to": {"type": "string"},
+          "type": {"type": "string"},
+          "properties": {"type": "object", "additionalProperties": {"type": "string"}}
+        },
+        "required": ["from", "to", "type"]
+      }
+    }
+  },
+  "required": ["entities", "relationships"]
+}`)
+
+// errMalformedOutput marks model output that can be retried with feedback
+var errMalformedOutput = errors.New("malformed extraction output")
+
+// LLMExtractor asks an OpenAI-compatible chat model to extract the graph.
+// Malformed output is retried with the validation error fed back to the
+// model; if every attempt fails the fallback extractor is used instead.
+type LLMExtractor struct {
+	client      *llm.Client
+	maxAttempts int
+	fallback    Extractor
+}
+
+// NewLLMExtractor creates an LLM extractor. A nil fallback means errors are
+// returned to the caller.
+func NewLLMExtractor(client *llm.Client, maxAttempts int, fallback Extractor) *LLMExtractor {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return &LLMExtractor{client: client, maxAttempts: maxAttempts, fallback: fallback}
+}
+
+func (e *LLMExtractor) Extract(ctx context.Context, messages []ConversationMessage) (GraphData, error) {
+	data, err := e.extract(ctx, messages)
+	if err == nil {
+		return data, nil
+	}
+	if e.fallback == nil {
+		return GraphData{}, err
+	}
+
+	log.Printf("LLM graph extraction failed, falling back to keywords: %v", err)
+	return e.fallback.Extract(ctx, messages)
+}
+
+func (e *LLMExtractor) extract(ctx context.Context, messages []ConversationMessage) (GraphData, error) {
+	var transcript strings.Builder
+	for _, msg := range messages {
+		fmt.Fprintf(&transcript, "%s: %s\n", msg.Role, msg.Content)
+	}
+
+	chat := []llm.Message{
+		{Role: "system", Content: extractionPrompt},
+		{Role: "user", Content: "Conversation:\n" + transcript.String()},
+	}
+	format := &llm.ResponseFormat{
+		Type:       "json_schema",
+		JSONSchema: &llm.JSONSchema{Name: "graph_data", Schema: extractionSchema},
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= e.maxAttempts; attempt++ {
+		content, err := e.client.Complete(ctx, chat, format)
+		if err != nil {
+			// Transport and API errors won't be fixed by re-prompting
+			return GraphData{}, err
+		}
+
+		data, err := ParseGraphData(content)
+		if err == nil {
+			return data, nil
+		}
+
+		lastErr = err
+		log.Printf("Graph extraction attempt %d/%d returned malformed output: %v", attempt, e.maxAttempts, err)
+		chat = append(chat,
+			llm.Message{Role: "assistant", Content: content},
+			llm.Message{Role: "user", Content: fmt.Sprintf("That response was invalid: %v. Reply again with only JSON matching the schema.", err)},
+		)
+	}
+
+	return GraphData{}, fmt.Errorf("giving up after %d attempts: %w", e.maxAttempts, lastErr)
+}
+
+// rawGraphData tolerates non-string property values from the model
+type rawGraphData struct {
+	Entities []struct {
+		Type       string                 `json:"type"`
+		Name       string                 `json:"name"`
+		Properties map[string]interface{} `json:"properties"`
+	} `json:"entities"`
+	Relationships []struct {
+		From       string                 `json:"from"`
+		To         string                 `json:"to"`
+		Type       string                 `json:"type"`
+		Properties map[string]interface{} `json:"properties"`
+	} `json:"relationships"`
+}
+
+// ParseGraphData decodes and validates model output. Relationships that
+// point at unknown entities are dropped rather than failing the whole result.
+// Types are left as the model wrote them; IngestGraphData maps them onto the
+// ontology.
+func ParseGraphData(content string) (GraphData, error) {
+	content = strings.TrimSpace(content)
+	// Some models wrap JSON in a markdown fence despite instructions
+	content = strings.TrimPrefix(content, "```json")
+	content = strings.TrimPrefix(content, "```")
+	content = strings.TrimSuffix(content, "```")
+
+	var raw rawGraphData
+	if err := json.Unmarshal([]byte(content), &raw); err != nil {
+		return GraphData{}, fmt.Errorf("%w: %v", errMalformedOutput, err)
+	}
+
+	data := GraphData{
+		Entities:      []ExtractedEntity{},
+		Relationships: []ExtractedRelationship{},
+	}
+	names := map[string]bool{UserEntity: true}
+	for i, entity := range raw.Entities {
+		name := strings.TrimSpace(entity.Name)
+		entityType := strings.TrimSpace(entity.Type)
+		if name == "" || entityType == "" {
+			return GraphData{}, fmt.Errorf("%w: entity %d is missing a type or name", errMalformedOutput, i)
+		}
+		if names[name] {
+			continue
+		}
+		names[name] = true
+		data.Entities = append(data.Entities, ExtractedEntity{
+			Type:       entityType,
+			Name:       name,
+			Properties: stringProperties(entity.Properties),
+		})
+	}
+
+	for i, rel := range raw.Relationships {
+		if rel.From == "" || rel.To == "" || rel.Type == "" {
+			return GraphData{}, fmt.Errorf("%w: relationship %d is missing from, to or type", errMalformedOutput, i)
+		}
+		if !names[rel.From] || !names[rel.To] {
+			log.Printf("Dropping relationship %s-[%s]->%s: unknown entity", rel.From, rel.Type, rel.To)
+			continue
+		}
+		data.Relationships = append(data.Relationships, ExtractedRelationship{
+			From:       rel.From,
+			To:         rel.To,
+			Type:       rel.Type,
+			Properties: stringProperties(rel.Properties),
+		})
+	}
+
+	return data, nil
+}
+
+func stringProperties(props map[string]interface{}) map[string]string {
+	result := make(map[string]string, len(props))
+	for key, value := range props {
+		if value == nil {
+			continue
+		}
+		if s, ok := value.(string); ok {
+			result[key] = s
+		} else {
+			result[key] = fmt.Sprint(value)
+		}
+	}
+	return result
+}
+
+// graphExtractionAttempts bounds re-prompting on malformed output
+const graphExtractionAttempts = 3
+
+// NewExtractor returns the LLM extractor when an endpoint is configured,
+// otherwise the keyword extractor
+func NewExtractor(baseURL, apiKey, model string) Extractor {
+	if baseURL == "" {
+		return KeywordExtractor{}
+	}
+	return NewLLMExtractor(llm.NewClient(baseURL, apiKey, model), graphExtractionAttempts, KeywordExtractor{})
+}
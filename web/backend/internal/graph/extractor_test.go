@@ -0,0 +1,192 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hume-evi/web/internal/llm"
+)
+
+// stubLLM is a chat completions endpoint that replies with scripted contents
+// in order and records the messages of every request
+type stubLLM struct {
+	t       *testing.T
+	replies []string
+	status  int
+
+	mu       sync.Mutex
+	requests [][]llm.Message
+}
+
+func (s *stubLLM) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/chat/completions" {
+		http.NotFound(w, r)
+		return
+	}
+	var req struct {
+		Messages []llm.Message `json:"messages"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.t.Errorf("decoding chat request: %v", err)
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req.Messages)
+	n := len(s.requests)
+	s.mu.Unlock()
+
+	if s.status != 0 {
+		http.Error(w, "upstream unavailable", s.status)
+		return
+	}
+	reply := s.replies[len(s.replies)-1]
+	if n <= len(s.replies) {
+		reply = s.replies[n-1]
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"choices": []map[string]interface{}{
+			{"message": llm.Message{Role: "assistant", Content: reply}},
+		},
+	})
+}
+
+func newStubExtractor(t *testing.T, stub *stubLLM) *LLMExtractor {
+	t.Helper()
+	stub.t = t
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+	return NewLLMExtractor(llm.NewClient(server.URL, "test-key", "test-model"), 3, KeywordExtractor{})
+}
+
+var testConversation = []ConversationMessage{
+	{Role: "user", Content: "Work has been a lot lately and my daughter is moving out."},
+	{Role: "assistant", Content: "That sounds like a big change."},
+}
+
+const validGraph = `{
+  "entities": [
+    {"type": "person", "name": "daughter", "properties": {"age": 19}},
+    {"type": "Topic", "name": "work"}
+  ],
+  "relationships": [
+    {"from": "user", "to": "daughter", "type": "HAS_CHILD"},
+    {"from": "user", "to": "work", "type": "WORRIES_ABOUT"}
+  ]
+}`
+
+func TestLLMExtractorRetriesMalformedOutput(t *testing.T) {
+	stub := &stubLLM{replies: []string{`{"entities": [`, "```json\n" + validGraph + "\n```"}}
+	extractor := newStubExtractor(t, stub)
+
+	data, err := extractor.Extract(context.Background(), testConversation)
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+
+	if len(stub.requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(stub.requests))
+	}
+	// The retry carries the bad reply and what was wrong with it
+	retry := stub.requests[1]
+	if len(retry) != 4 || retry[2].Content != `{"entities": [` || !strings.Contains(retry[3].Content, "invalid") {
+		t.Fatalf("retry didn't feed back the malformed output: %+v", retry)
+	}
+
+	if len(data.Entities) != 2 || len(data.Relationships) != 2 {
+		t.Fatalf("got %+v, want 2 entities and 2 relationships", data)
+	}
+	daughter := data.Entities[0]
+	if daughter.Type != "person" || daughter.Properties["age"] != "19" {
+		t.Fatalf("got %+v, want a person with age 19", daughter)
+	}
+}
+
+func TestLLMExtractorFallsBackToKeywords(t *testing.T) {
+	tests := []struct {
+		name     string
+		stub     *stubLLM
+		requests int
+	}{
+		// Malformed output is retried up to the attempt limit
+		{"malformed output", &stubLLM{replies: []string{"I can't do that."}}, 3},
+		// API errors aren't worth re-prompting
+		{"API error", &stubLLM{status: http.StatusBadGateway}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			extractor := newStubExtractor(t, tt.stub)
+
+			data, err := extractor.Extract(context.Background(), testConversation)
+			if err != nil {
+				t.Fatalf("Extract: %v", err)
+			}
+			if len(tt.stub.requests) != tt.requests {
+				t.Fatalf("got %d requests, want %d", len(tt.stub.requests), tt.requests)
+			}
+
+			want, _ := KeywordExtractor{}.Extract(context.Background(), testConversation)
+			if len(want.Entities) == 0 {
+				t.Fatal("test conversation has no keywords")
+			}
+			gotJSON, _ := json.Marshal(data)
+			wantJSON, _ := json.Marshal(want)
+			if string(gotJSON) != string(wantJSON) {
+				t.Fatalf("got %s, want keyword result %s", gotJSON, wantJSON)
+			}
+		})
+	}
+}
+
+func TestLLMExtractorKeepsEntitiesOutsideOntology(t *testing.T) {
+	stub := &stubLLM{replies: []string{`{
+  "entities": [
+    {"type": "Person", "name": "daughter"},
+    {"type": "Spaceship", "name": "enterprise"}
+  ],
+  "relationships": [
+    {"from": "user", "to": "daughter", "type": "HAS_CHILD"},
+    {"from": "user", "to": "enterprise", "type": "ENJOYS"}
+  ]
+}`}}
+	extractor := newStubExtractor(t, stub)
+
+	data, err := extractor.Extract(context.Background(), testConversation)
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if len(stub.requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(stub.requests))
+	}
+	if len(data.Entities) != 2 || data.Entities[1].Name != "enterprise" || data.Entities[1].Type != "Spaceship" {
+		t.Fatalf("got entities %+v, want daughter and enterprise with their types as extracted", data.Entities)
+	}
+	if len(data.Relationships) != 2 {
+		t.Fatalf("got relationships %+v, want both", data.Relationships)
+	}
+
+	// Ingestion stores the unknown type under the generic label
+	writer := &recordingWriter{}
+	report, err := ingestGraphData(context.Background(), writer, "user-1", "conv-1", data)
+	if err != nil {
+		t.Fatalf("ingestGraphData: %v", err)
+	}
+	if report.Entities != 2 || report.Relationships != 2 || len(report.Rejected) != 0 {
+		t.Fatalf("got report %+v, want both entities and relationships ingested", report)
+	}
+	if len(report.Remapped) != 1 || report.Remapped[0].Name != "enterprise" || report.Remapped[0].Type != "Spaceship" {
+		t.Fatalf("got remapped %+v, want enterprise", report.Remapped)
+	}
+
+	enterprise := writer.queries[1]
+	if !strings.Contains(enterprise.cypher, "MERGE (e:"+GenericLabel+" ") || enterprise.params["kind"] != "Spaceship" {
+		t.Fatalf("enterprise written with %q and kind %v", enterprise.cypher, enterprise.params["kind"])
+	}
+	if daughter := writer.queries[0]; !strings.Contains(daughter.cypher, "MERGE (e:Person ") || daughter.params["kind"] != nil {
+		t.Fatalf("daughter written with %q and kind %v", daughter.cypher, daughter.params["kind"])
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strings"
 )
 
 // ConversationMessage represents a message in the conversation
@@ -57,65 +58,196 @@ func (c *Client) SyncConversation(ctx context.Context, userID, conversationID st
 	return nil
 }
 
+// IngestIssue describes an entity or relationship that was remapped or
+// rejected during ingestion
+type IngestIssue struct {
+	Kind   string `json:"kind"` // "entity" or "relationship"
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+// IngestReport summarises what IngestGraphData wrote
+type IngestReport struct {
+	Entities      int           `json:"entities"`
+	Relationships int           `json:"relationships"`
+	Remapped      []IngestIssue `json:"remapped,omitempty"`
+	Rejected      []IngestIssue `json:"rejected,omitempty"`
+}
+
 // IngestGraphData ingests extracted entities and relationships into Memgraph
-// This is called after an LLM extracts the graph structure from conversation text
-func (c *Client) IngestGraphData(ctx context.Context, userID, conversationID string, data GraphData) error {
+// This is called after an LLM extracts the graph structure from conversation text.
+// Entities are linked to their conversation (MENTIONS, or EXPRESSES for emotions),
+// and relationships from or to UserEntity attach to the User node.
+// SyncConversation should be called first so the User and Conversation nodes exist.
+//
+// Types are checked against the ontology before being interpolated into Cypher;
+// unknown types are stored under GenericLabel / GenericRelationship with a
+// "kind" property and listed in the report.
+func (c *Client) IngestGraphData(ctx context.Context, userID, conversationID string, data GraphData) (*IngestReport, error) {
+	return ingestGraphData(ctx, c, userID, conversationID, data)
+}
+
+// graphWriter runs write queries and counts the rows they return. *Client
+// runs them against Memgraph; tests record them instead.
+type graphWriter interface {
+	ExecuteWriteCount(ctx context.Context, cypher string, params map[string]interface{}) (int, error)
+}
+
+func ingestGraphData(ctx context.Context, w graphWriter, userID, conversationID string, data GraphData) (*IngestReport, error) {
+	report := &IngestReport{}
+	entities := make(map[string]bool)
+
 	// Create entities
 	for _, entity := range data.Entities {
+		name := strings.TrimSpace(entity.Name)
+		if name == "" {
+			report.Rejected = append(report.Rejected, IngestIssue{Kind: "entity", Type: entity.Type, Reason: "missing name"})
+			continue
+		}
+		if name == UserEntity {
+			report.Rejected = append(report.Rejected, IngestIssue{Kind: "entity", Name: name, Type: entity.Type, Reason: "name is reserved for the user"})
+			continue
+		}
+
+		label, ok := NormalizeLabel(entity.Type)
+		kind := ""
+		if !ok {
+			kind = strings.TrimSpace(entity.Type)
+			report.Remapped = append(report.Remapped, IngestIssue{
+				Kind:   "entity",
+				Name:   name,
+				Type:   entity.Type,
+				Reason: fmt.Sprintf("unknown type, stored as %s", GenericLabel),
+			})
+			label = GenericLabel
+		}
+
+		link := "MENTIONS"
+		if label == "Emotion" {
+			link = "EXPRESSES"
+		}
+
+		// label and link come from the ontology allow-list, never from input
 		cypher := fmt.Sprintf(`
+			MATCH (c:Conversation {id: $conversationId})
 			MERGE (e:%s {name: $name, conversationId: $conversationId})
 			SET e += $properties
+			SET e.kind = $kind
 			SET e.updated_at = datetime()
-		`, entity.Type)
+			MERGE (c)-[:%s]->(e)
+			RETURN e.name AS name
+		`, label, link)
 
+		var kindParam interface{}
+		if kind != "" {
+			kindParam = kind
+		}
 		params := map[string]interface{}{
-			"name":           entity.Name,
+			"name":           name,
 			"conversationId": conversationID,
-			"properties":     entity.Properties,
+			"properties":     sanitizeProperties(entity.Properties),
+			"kind":           kindParam,
 		}
 
-		if err := c.ExecuteWrite(ctx, cypher, params); err != nil {
-			log.Printf("Warning: Failed to create entity %s: %v", entity.Name, err)
+		rows, err := w.ExecuteWriteCount(ctx, cypher, params)
+		if err != nil {
+			log.Printf("Warning: Failed to create entity %s: %v", name, err)
+			report.Rejected = append(report.Rejected, IngestIssue{Kind: "entity", Name: name, Type: entity.Type, Reason: err.Error()})
+			continue
 		}
+		if rows == 0 {
+			// The MATCH found no Conversation node, so nothing was written
+			report.Rejected = append(report.Rejected, IngestIssue{Kind: "entity", Name: name, Type: entity.Type, Reason: "conversation is not in the graph"})
+			continue
+		}
+		entities[name] = true
+		report.Entities++
 	}
 
 	// Create relationships
 	for _, rel := range data.Relationships {
+		name := fmt.Sprintf("%s->%s", rel.From, rel.To)
+		if rel.From == "" || rel.To == "" {
+			report.Rejected = append(report.Rejected, IngestIssue{Kind: "relationship", Name: name, Type: rel.Type, Reason: "missing endpoint"})
+			continue
+		}
+		if (rel.From != UserEntity && !entities[rel.From]) || (rel.To != UserEntity && !entities[rel.To]) {
+			report.Rejected = append(report.Rejected, IngestIssue{Kind: "relationship", Name: name, Type: rel.Type, Reason: "endpoint is not an ingested entity"})
+			continue
+		}
+
+		relType, ok := NormalizeRelationshipType(rel.Type)
+		var kindParam interface{}
+		if !ok {
+			kindParam = strings.TrimSpace(rel.Type)
+			report.Remapped = append(report.Remapped, IngestIssue{
+				Kind:   "relationship",
+				Name:   name,
+				Type:   rel.Type,
+				Reason: fmt.Sprintf("unknown type, stored as %s", GenericRelationship),
+			})
+			relType = GenericRelationship
+		}
+
+		// relType comes from the ontology allow-list, never from input
 		cypher := fmt.Sprintf(`
-			MATCH (from {name: $from, conversationId: $conversationId})
-			MATCH (to {name: $to, conversationId: $conversationId})
+			%s
+			%s
 			MERGE (from)-[r:%s]->(to)
 			SET r += $properties
+			SET r.kind = $kind
 			SET r.timestamp = datetime()
-		`, rel.Type)
+			RETURN type(r) AS type
+		`, matchEndpoint("from", rel.From), matchEndpoint("to", rel.To), relType)
 
 		params := map[string]interface{}{
 			"from":           rel.From,
 			"to":             rel.To,
+			"userId":         userID,
 			"conversationId": conversationID,
-			"properties":     rel.Properties,
+			"properties":     sanitizeProperties(rel.Properties),
+			"kind":           kindParam,
 		}
 
-		if err := c.ExecuteWrite(ctx, cypher, params); err != nil {
+		rows, err := w.ExecuteWriteCount(ctx, cypher, params)
+		if err != nil {
 			log.Printf("Warning: Failed to create relationship %s->%s: %v", rel.From, rel.To, err)
+			report.Rejected = append(report.Rejected, IngestIssue{Kind: "relationship", Name: name, Type: rel.Type, Reason: err.Error()})
+			continue
+		}
+		if rows == 0 {
+			// An endpoint's MATCH found nothing, e.g. no User node yet
+			report.Rejected = append(report.Rejected, IngestIssue{Kind: "relationship", Name: name, Type: rel.Type, Reason: "endpoint is not in the graph"})
+			continue
 		}
+		report.Relationships++
 	}
 
-	log.Printf("✅ Ingested %d entities and %d relationships for conversation %s",
-		len(data.Entities), len(data.Relationships), conversationID)
-	return nil
+	log.Printf("✅ Ingested %d entities and %d relationships for conversation %s (%d remapped, %d rejected)",
+		report.Entities, report.Relationships, conversationID, len(report.Remapped), len(report.Rejected))
+	return report, nil
+}
+
+// matchEndpoint builds the MATCH clause for one end of a relationship.
+// The variable name doubles as the parameter holding the entity name.
+func matchEndpoint(variable, name string) string {
+	if name == UserEntity {
+		return fmt.Sprintf("MATCH (%s:User {id: $userId})", variable)
+	}
+	return fmt.Sprintf("MATCH (%s {name: $%s, conversationId: $conversationId})", variable, variable)
 }
 
 // GetUserContext retrieves relevant context from the knowledge graph for a user
 // This is used to guide context injection
 func (c *Client) GetUserContext(ctx context.Context, userID string) (map[string]interface{}, error) {
-	// Find recurring topics and patterns
+	// Find topics that come up in more than one conversation
 	cypher := `
 		MATCH (u:User {id: $userId})-[:HAS_CONVERSATION]->(c:Conversation)
 		MATCH (c)-[:MENTIONS]->(topic:Topic)
-		WITH topic, count(*) as mentions
+		WITH topic.name as topic, count(DISTINCT c) as mentions
 		WHERE mentions > 1
-		RETURN topic.name as topic, mentions
+		RETURN topic, mentions
 		ORDER BY mentions DESC
 		LIMIT 5
 	`
@@ -130,7 +262,9 @@ func (c *Client) GetUserContext(ctx context.Context, userID string) (map[string]
 	}
 
 	context := map[string]interface{}{
-		"recurring_topics": results,
+		"recurring_topics":     nonNil(results),
+		"emotional_patterns":   []map[string]interface{}{},
+		"relationship_context": []map[string]interface{}{},
 	}
 
 	// Find emotional patterns
@@ -144,10 +278,29 @@ func (c *Client) GetUserContext(ctx context.Context, userID string) (map[string]
 
 	emotions, err := c.ExecuteRead(ctx, emotionCypher, params)
 	if err == nil {
-		context["emotional_patterns"] = emotions
+		context["emotional_patterns"] = nonNil(emotions)
+	}
+
+	// Find the people in the user's life
+	relationshipCypher := `
+		MATCH (u:User {id: $userId})-[r]->(p:Person)
+		RETURN p.name as person, type(r) as relationship, count(*) as mentions
+		ORDER BY mentions DESC
+		LIMIT 10
+	`
+
+	relationships, err := c.ExecuteRead(ctx, relationshipCypher, params)
+	if err == nil {
+		context["relationship_context"] = nonNil(relationships)
 	}
 
 	return context, nil
 }
 
-
+// nonNil keeps empty query results encoding as [] rather than null
+func nonNil(results []map[string]interface{}) []map[string]interface{} {
+	if results == nil {
+		return []map[string]interface{}{}
+	}
+	return results
+}
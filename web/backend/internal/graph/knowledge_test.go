@@ -0,0 +1,151 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// recordingWriter is a graphWriter that records queries instead of running
+// them. Each query returns one row unless result says otherwise.
+type recordingWriter struct {
+	result  func(q recordedQuery) (int, error)
+	queries []recordedQuery
+}
+
+type recordedQuery struct {
+	cypher string
+	params map[string]interface{}
+}
+
+func (w *recordingWriter) ExecuteWriteCount(ctx context.Context, cypher string, params map[string]interface{}) (int, error) {
+	q := recordedQuery{cypher: cypher, params: params}
+	w.queries = append(w.queries, q)
+	if w.result != nil {
+		return w.result(q)
+	}
+	return 1, nil
+}
+
+// issueNames lists the names of report issues, in order
+func issueNames(issues []IngestIssue) []string {
+	var names []string
+	for _, issue := range issues {
+		names = append(names, issue.Kind+":"+issue.Name)
+	}
+	return names
+}
+
+func TestIngestGraphDataReport(t *testing.T) {
+	data := GraphData{
+		Entities: []ExtractedEntity{
+			{Type: "person", Name: "daughter", Properties: map[string]string{"age": "19", "conversationId": "other"}},
+			{Type: "Emotion", Name: "worry"},
+			{Type: "Spaceship", Name: "enterprise"},
+			{Type: "Topic", Name: "  "},
+			{Type: "Person", Name: UserEntity},
+			{Type: "Topic", Name: "broken"},
+		},
+		Relationships: []ExtractedRelationship{
+			{From: UserEntity, To: "daughter", Type: "has child"},
+			{From: UserEntity, To: "worry", Type: "adores"},
+			{From: "", To: "daughter", Type: "KNOWS"},
+			{From: UserEntity, To: "ghost", Type: "KNOWS"},
+			{From: "daughter", To: "broken", Type: "DISCUSSES"},
+		},
+	}
+	writer := &recordingWriter{result: func(q recordedQuery) (int, error) {
+		if q.params["name"] == "broken" {
+			return 0, errors.New("constraint violated")
+		}
+		return 1, nil
+	}}
+
+	report, err := ingestGraphData(context.Background(), writer, "user-1", "conv-1", data)
+	if err != nil {
+		t.Fatalf("ingestGraphData: %v", err)
+	}
+	if report.Entities != 3 || report.Relationships != 2 {
+		t.Fatalf("ingested %d entities and %d relationships, want 3 and 2", report.Entities, report.Relationships)
+	}
+
+	wantRemapped := []string{"entity:enterprise", "relationship:user->worry"}
+	if got := issueNames(report.Remapped); strings.Join(got, ",") != strings.Join(wantRemapped, ",") {
+		t.Fatalf("remapped %v, want %v", got, wantRemapped)
+	}
+	if report.Remapped[1].Reason != "unknown type, stored as "+GenericRelationship {
+		t.Fatalf("relationship remapped because %q", report.Remapped[1].Reason)
+	}
+
+	wantRejected := []struct{ name, reason string }{
+		{"entity:", "missing name"},
+		{"entity:" + UserEntity, "name is reserved for the user"},
+		{"entity:broken", "constraint violated"},
+		{"relationship:->daughter", "missing endpoint"},
+		{"relationship:user->ghost", "endpoint is not an ingested entity"},
+		{"relationship:daughter->broken", "endpoint is not an ingested entity"},
+	}
+	if len(report.Rejected) != len(wantRejected) {
+		t.Fatalf("rejected %v, want %d issues", report.Rejected, len(wantRejected))
+	}
+	for i, want := range wantRejected {
+		got := report.Rejected[i]
+		if got.Kind+":"+got.Name != want.name || got.Reason != want.reason {
+			t.Errorf("rejected[%d] = %+v, want %s (%s)", i, got, want.name, want.reason)
+		}
+	}
+
+	// Emotions hang off the conversation with EXPRESSES, and reserved
+	// properties never reach the query
+	worry := writer.queries[1]
+	if !strings.Contains(worry.cypher, "MERGE (e:Emotion ") || !strings.Contains(worry.cypher, "[:EXPRESSES]") {
+		t.Fatalf("worry written with %q", worry.cypher)
+	}
+	daughter := writer.queries[0].params["properties"].(map[string]interface{})
+	if daughter["age"] != "19" || daughter["conversationId"] != nil {
+		t.Fatalf("daughter written with properties %v", daughter)
+	}
+}
+
+func TestIngestGraphDataCountsOnlyWrittenRows(t *testing.T) {
+	data := GraphData{
+		Entities: []ExtractedEntity{
+			{Type: "Person", Name: "daughter"},
+		},
+		Relationships: []ExtractedRelationship{
+			{From: UserEntity, To: "daughter", Type: "HAS_CHILD"},
+		},
+	}
+
+	// Without a Conversation node the entity MATCH returns no rows
+	writer := &recordingWriter{result: func(recordedQuery) (int, error) { return 0, nil }}
+	report, err := ingestGraphData(context.Background(), writer, "user-1", "conv-1", data)
+	if err != nil {
+		t.Fatalf("ingestGraphData: %v", err)
+	}
+	if report.Entities != 0 || report.Relationships != 0 {
+		t.Fatalf("counted %d entities and %d relationships for a missing conversation, want none", report.Entities, report.Relationships)
+	}
+	if len(report.Rejected) != 2 || report.Rejected[0].Reason != "conversation is not in the graph" || report.Rejected[1].Reason != "endpoint is not an ingested entity" {
+		t.Fatalf("rejected %+v", report.Rejected)
+	}
+
+	// Without a User node the relationship MATCH returns no rows
+	writer = &recordingWriter{result: func(q recordedQuery) (int, error) {
+		if strings.Contains(q.cypher, ":User ") {
+			return 0, nil
+		}
+		return 1, nil
+	}}
+	report, err = ingestGraphData(context.Background(), writer, "user-1", "conv-1", data)
+	if err != nil {
+		t.Fatalf("ingestGraphData: %v", err)
+	}
+	if report.Entities != 1 || report.Relationships != 0 {
+		t.Fatalf("counted %d entities and %d relationships for a missing user, want 1 and 0", report.Entities, report.Relationships)
+	}
+	if len(report.Rejected) != 1 || report.Rejected[0].Reason != "endpoint is not in the graph" {
+		t.Fatalf("rejected %+v", report.Rejected)
+	}
+}
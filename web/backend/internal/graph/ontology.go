@@ -0,0 +1,145 @@
+package graph
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Labels and relationship types can't be passed as Cypher parameters, so
+// anything interpolated into a query must come from these allow-lists.
+// Extractor output (from an LLM or a request body) is normalised and mapped
+// onto them; unknown types fall back to GenericLabel / GenericRelationship
+// with the original type kept in a "kind" property.
+
+const (
+	// GenericLabel is used for entities whose type isn't in the ontology
+	GenericLabel = "Entity"
+	// GenericRelationship is used for relationships whose type isn't in the ontology
+	GenericRelationship = "RELATED_TO"
+)
+
+// EntityLabels are the node labels extractors may produce
+var EntityLabels = map[string]bool{
+	"Person":       true,
+	"Topic":        true,
+	"Emotion":      true,
+	"Location":     true,
+	"Event":        true,
+	"Goal":         true,
+	"Activity":     true,
+	"Organization": true,
+	"Health":       true,
+	GenericLabel:   true,
+}
+
+// RelationshipTypes are the relationship types extractors may produce
+var RelationshipTypes = map[string]bool{
+	"DISCUSSES":         true,
+	"FEELS":             true,
+	"HAS_CHILD":         true,
+	"HAS_PARENT":        true,
+	"HAS_PARTNER":       true,
+	"HAS_SIBLING":       true,
+	"HAS_FRIEND":        true,
+	"KNOWS":             true,
+	"LIVES_IN":          true,
+	"LIVES_WITH":        true,
+	"WORKS_AT":          true,
+	"WORKS_WITH":        true,
+	"WORRIES_ABOUT":     true,
+	"CARES_ABOUT":       true,
+	"ENJOYS":            true,
+	"PLANS":             true,
+	"EXPERIENCED":       true,
+	"CAUSES":            true,
+	"SUPPORTS":          true,
+	GenericRelationship: true,
+}
+
+// reservedProperties are managed by ingestion and can't be set by extractors
+var reservedProperties = map[string]bool{
+	"name":           true,
+	"conversationId": true,
+	"kind":           true,
+	"updated_at":     true,
+	"timestamp":      true,
+	"id":             true,
+}
+
+// sanitizeIdentifier keeps ASCII letters and digits, treating any other
+// character (underscores and non-ASCII letters included) as a word break.
+// The result is split into words for re-casing.
+func sanitizeIdentifier(raw string) []string {
+	var words []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			words = append(words, current.String())
+			current.Reset()
+		}
+	}
+
+	runes := []rune(strings.TrimSpace(raw))
+	for i, r := range runes {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			// Split camelCase / PascalCase boundaries
+			if unicode.IsUpper(r) && i > 0 && unicode.IsLower(runes[i-1]) {
+				flush()
+			}
+			current.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return words
+}
+
+// NormalizeLabel converts an extractor type ("person", "life event") to
+// PascalCase and reports whether it's in the ontology
+func NormalizeLabel(raw string) (string, bool) {
+	words := sanitizeIdentifier(raw)
+	var b strings.Builder
+	for _, word := range words {
+		lower := strings.ToLower(word)
+		b.WriteString(strings.ToUpper(lower[:1]) + lower[1:])
+	}
+	label := b.String()
+	return label, EntityLabels[label]
+}
+
+// NormalizeRelationshipType converts an extractor type ("has child",
+// "hasChild") to UPPER_SNAKE_CASE and reports whether it's in the ontology
+func NormalizeRelationshipType(raw string) (string, bool) {
+	words := sanitizeIdentifier(raw)
+	for i, word := range words {
+		words[i] = strings.ToUpper(word)
+	}
+	relType := strings.Join(words, "_")
+	return relType, RelationshipTypes[relType]
+}
+
+// sanitizeProperties drops reserved keys so extractors can't overwrite the
+// properties ingestion relies on for matching
+func sanitizeProperties(props map[string]string) map[string]interface{} {
+	params := make(map[string]interface{}, len(props))
+	for key, value := range props {
+		if key == "" || reservedProperties[key] {
+			continue
+		}
+		params[key] = value
+	}
+	return params
+}
+
+// sortedKeys lists an allow-list for prompts and error messages
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
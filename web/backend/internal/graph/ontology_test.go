@@ -0,0 +1,86 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSanitizeIdentifier(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want []string
+	}{
+		{"person", []string{"person"}},
+		{"  life event  ", []string{"life", "event"}},
+		{"lifeEvent", []string{"life", "Event"}},
+		{"LifeEvent", []string{"Life", "Event"}},
+		{"has_child", []string{"has", "child"}},
+		{"WORKS-AT", []string{"WORKS", "AT"}},
+		{"`Person`", []string{"Person"}},
+		{"{Topic}", []string{"Topic"}},
+		{"Person) DETACH DELETE n //", []string{"Person", "DETACH", "DELETE", "n"}},
+		{"Café", []string{"Caf"}},
+		{"naïve", []string{"na", "ve"}},
+		{"人物", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := sanitizeIdentifier(tt.raw); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("sanitizeIdentifier(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeLabel(t *testing.T) {
+	tests := []struct {
+		raw   string
+		want  string
+		known bool
+	}{
+		{"Person", "Person", true},
+		{"person", "Person", true},
+		{"ORGANIZATION", "Organization", true},
+		{"`Person`", "Person", true},
+		{"{Topic}", "Topic", true},
+		{"Person) DETACH DELETE n //", "PersonDetachDeleteN", false},
+		{"life event", "LifeEvent", false},
+		{"lifeEvent", "LifeEvent", false},
+		{"health_issue", "HealthIssue", false},
+		{"Café", "Caf", false},
+		{"人物", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, known := NormalizeLabel(tt.raw)
+		if got != tt.want || known != tt.known {
+			t.Errorf("NormalizeLabel(%q) = %q, %v; want %q, %v", tt.raw, got, known, tt.want, tt.known)
+		}
+	}
+}
+
+func TestNormalizeRelationshipType(t *testing.T) {
+	tests := []struct {
+		raw   string
+		want  string
+		known bool
+	}{
+		{"HAS_CHILD", "HAS_CHILD", true},
+		{"has child", "HAS_CHILD", true},
+		{"hasChild", "HAS_CHILD", true},
+		{"has_child", "HAS_CHILD", true},
+		{"works-at", "WORKS_AT", true},
+		{"`KNOWS`", "KNOWS", true},
+		{"{LIVES_IN}", "LIVES_IN", true},
+		{"KNOWS]->(x) DETACH DELETE x //", "KNOWS_X_DETACH_DELETE_X", false},
+		{"adores", "ADORES", false},
+		{"aimé", "AIM", false},
+		{"愛する", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, known := NormalizeRelationshipType(tt.raw)
+		if got != tt.want || known != tt.known {
+			t.Errorf("NormalizeRelationshipType(%q) = %q, %v; want %q, %v", tt.raw, got, known, tt.want, tt.known)
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package hume
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the production Hume API
+const DefaultBaseURL = "https://api.hume.ai"
+
+// API is the subset of the Hume REST API used by the server. *Client
+// implements it; handlers depend on the interface so it can be faked.
+type API interface {
+	CreatePrompt(ctx context.Context, name, text string) (*Prompt, error)
+	CreatePromptVersion(ctx context.Context, promptID, text, versionDescription string) (*Prompt, error)
+	GetPrompt(ctx context.Context, id string) (*Prompt, error)
+	GetPromptVersion(ctx context.Context, id string, version int) (*Prompt, error)
+	ListPrompts(ctx context.Context) ([]Prompt, error)
+	GetConfig(ctx context.Context, id string) (*Config, error)
+	ListConfigs(ctx context.Context, pageNumber, pageSize int) (*ConfigPage, error)
+	CreateConfig(ctx context.Context, req ConfigRequest) (*Config, error)
+	CreateConfigVersion(ctx context.Context, configID string, req ConfigRequest) (*Config, error)
+	DeleteConfig(ctx context.Context, configID string) error
+	SynthesizeSpeech(ctx context.Context, req TTSRequest) (*TTSResponse, error)
+	SaveVoice(ctx context.Context, generationID, name string) (*Voice, error)
+	ListVoices(ctx context.Context, provider string, pageNumber, pageSize int) (*VoicePage, error)
+}
+
+var _ API = (*Client)(nil)
+
+// Client talks to the Hume REST API. It is safe for concurrent use and
+// reuses one HTTP client (and so one connection pool) for every call.
+type Client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewClient creates a client rooted at baseURL (DefaultBaseURL when empty)
+func NewClient(baseURL, apiKey string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// BaseURL returns the API root the client was created with
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// ChatURL returns the EVI chat websocket URL for a config, derived from the
+// REST base URL (https becomes wss, http becomes ws)
+func ChatURL(baseURL, configID string) string {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	base := strings.TrimRight(baseURL, "/")
+	switch {
+	case strings.HasPrefix(base, "https://"):
+		base = "wss://" + strings.TrimPrefix(base, "https://")
+	case strings.HasPrefix(base, "http://"):
+		base = "ws://" + strings.TrimPrefix(base, "http://")
+	}
+	return base + "/v0/evi/chat?config_id=" + url.QueryEscape(configID)
+}
+
+// CreatePrompt creates a new prompt. A prompt with the same name already
+// existing yields a StatusError for which IsConflict is true.
+func (c *Client) CreatePrompt(ctx context.Context, name, text string) (*Prompt, error) {
+	var prompt Prompt
+	if err := c.do(ctx, "POST", "/v0/evi/prompts", promptRequest{Name: name, Text: text}, &prompt); err != nil {
+		return nil, err
+	}
+	if prompt.ID == "" {
+		return nil, fmt.Errorf("prompt ID not found in response")
+	}
+	return &prompt, nil
+}
+
+// CreatePromptVersion publishes new text as the next version of a prompt
+func (c *Client) CreatePromptVersion(ctx context.Context, promptID, text, versionDescription string) (*Prompt, error) {
+	req := promptVersionRequest{Text: text, VersionDescription: versionDescription}
+	var prompt Prompt
+	if err := c.do(ctx, "POST", "/v0/evi/prompts/"+url.PathEscape(promptID), req, &prompt); err != nil {
+		return nil, err
+	}
+	return &prompt, nil
+}
+
+// GetPrompt retrieves a prompt by ID at its latest version
+func (c *Client) GetPrompt(ctx context.Context, id string) (*Prompt, error) {
+	var raw json.RawMessage
+	if err := c.do(ctx, "GET", "/v0/evi/prompts/"+url.PathEscape(id)+"?page_size=100", nil, &raw); err != nil {
+		return nil, err
+	}
+
+	// The endpoint lists the prompt's versions; pick the newest
+	var page promptPage
+	if err := json.Unmarshal(raw, &page); err == nil && page.PromptsPage != nil {
+		var latest *Prompt
+		for i := range page.PromptsPage {
+			if latest == nil || page.PromptsPage[i].Version > latest.Version {
+				latest = &page.PromptsPage[i]
+			}
+		}
+		if latest == nil {
+			return nil, fmt.Errorf("prompt %s has no versions", id)
+		}
+		return latest, nil
+	}
+
+	var prompt Prompt
+	if err := json.Unmarshal(raw, &prompt); err != nil {
+		return nil, fmt.Errorf("failed to decode prompt response: %v, body: %s", err, string(raw))
+	}
+	return &prompt, nil
+}
+
+// GetPromptVersion retrieves one version of a prompt
+func (c *Client) GetPromptVersion(ctx context.Context, id string, version int) (*Prompt, error) {
+	var prompt Prompt
+	if err := c.do(ctx, "GET", "/v0/evi/prompts/"+url.PathEscape(id)+"/version/"+strconv.Itoa(version), nil, &prompt); err != nil {
+		return nil, err
+	}
+	return &prompt, nil
+}
+
+// ListPrompts returns the first page (up to 100) of prompts
+func (c *Client) ListPrompts(ctx context.Context) ([]Prompt, error) {
+	var raw json.RawMessage
+	if err := c.do(ctx, "GET", "/v0/evi/prompts?page_size=100", nil, &raw); err != nil {
+		return nil, err
+	}
+
+	// The list has been returned in several shapes over time
+	var page promptPage
+	if err := json.Unmarshal(raw, &page); err == nil && page.PromptsPage != nil {
+		return page.PromptsPage, nil
+	}
+
+	var results promptListResponse
+	if err := json.Unmarshal(raw, &results); err == nil && results.ResultsPage.Results != nil {
+		return results.ResultsPage.Results, nil
+	}
+
+	var direct struct {
+		Results []Prompt `json:"results"`
+	}
+	if err := json.Unmarshal(raw, &direct); err == nil && direct.Results != nil {
+		return direct.Results, nil
+	}
+
+	var prompts []Prompt
+	if err := json.Unmarshal(raw, &prompts); err != nil {
+		return nil, fmt.Errorf("failed to decode prompt list response: %v, body: %s", err, string(raw))
+	}
+	return prompts, nil
+}
+
+// GetConfig retrieves a config by ID at its latest version
+func (c *Client) GetConfig(ctx context.Context, id string) (*Config, error) {
+	var page ConfigPage
+	if err := c.do(ctx, "GET", "/v0/evi/configs/"+url.PathEscape(id)+"?page_size=100", nil, &page); err != nil {
+		return nil, err
+	}
+
+	// The endpoint lists the config's versions; pick the newest
+	var latest *Config
+	for i := range page.Configs {
+		if latest == nil || page.Configs[i].Version > latest.Version {
+			latest = &page.Configs[i]
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("config %s has no versions", id)
+	}
+	return latest, nil
+}
+
+// ListConfigs returns one page of configs, each at its latest version;
+// pages are numbered from 0
+func (c *Client) ListConfigs(ctx context.Context, pageNumber, pageSize int) (*ConfigPage, error) {
+	query := url.Values{}
+	query.Set("page_number", strconv.Itoa(pageNumber))
+	query.Set("page_size", strconv.Itoa(pageSize))
+	query.Set("restrict_to_most_recent", "true")
+
+	var page ConfigPage
+	if err := c.do(ctx, "GET", "/v0/evi/configs?"+query.Encode(), nil, &page); err != nil {
+		return nil, err
+	}
+	if page.Configs == nil {
+		page.Configs = []Config{}
+	}
+	return &page, nil
+}
+
+// CreateConfig creates a new EVI config
+func (c *Client) CreateConfig(ctx context.Context, req ConfigRequest) (*Config, error) {
+	var config Config
+	if err := c.do(ctx, "POST", "/v0/evi/configs", req, &config); err != nil {
+		return nil, err
+	}
+	if config.ID == "" {
+		return nil, fmt.Errorf("config ID is empty in response")
+	}
+	return &config, nil
+}
+
+// CreateConfigVersion publishes a new version of an existing EVI config
+func (c *Client) CreateConfigVersion(ctx context.Context, configID string, req ConfigRequest) (*Config, error) {
+	var config Config
+	if err := c.do(ctx, "POST", "/v0/evi/configs/"+url.PathEscape(configID), req, &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// DeleteConfig deletes a config and all of its versions
+func (c *Client) DeleteConfig(ctx context.Context, configID string) error {
+	return c.do(ctx, "DELETE", "/v0/evi/configs/"+url.PathEscape(configID), nil, nil)
+}
+
+// SynthesizeSpeech generates speech samples for a voice description
+func (c *Client) SynthesizeSpeech(ctx context.Context, req TTSRequest) (*TTSResponse, error) {
+	var resp TTSResponse
+	if err := c.do(ctx, "POST", "/v0/tts", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SaveVoice saves a generation to the voice library under the given name
+func (c *Client) SaveVoice(ctx context.Context, generationID, name string) (*Voice, error) {
+	var voice Voice
+	if err := c.do(ctx, "POST", "/v0/tts/voices", saveVoiceRequest{GenerationID: generationID, Name: name}, &voice); err != nil {
+		return nil, err
+	}
+	return &voice, nil
+}
+
+// ListVoices returns one page of voices from a provider (VoiceProviderCustom
+// or VoiceProviderHume); pages are numbered from 0
+func (c *Client) ListVoices(ctx context.Context, provider string, pageNumber, pageSize int) (*VoicePage, error) {
+	query := url.Values{}
+	query.Set("provider", provider)
+	query.Set("page_number", strconv.Itoa(pageNumber))
+	query.Set("page_size", strconv.Itoa(pageSize))
+
+	var page VoicePage
+	if err := c.do(ctx, "GET", "/v0/tts/voices?"+query.Encode(), nil, &page); err != nil {
+		return nil, err
+	}
+	if page.Voices == nil {
+		page.Voices = []Voice{}
+	}
+	return &page, nil
+}
+
+// do sends body (if any) as JSON and decodes a 2xx response into out
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reqBody)
+	if err != nil {
+		return err
+	}
+
+	httpReq.Header.Set("X-Hume-Api-Key", c.apiKey)
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return newStatusError(resp.StatusCode, bodyBytes)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(bodyBytes, out); err != nil {
+		return fmt.Errorf("failed to decode response: %v, body: %s", err, string(bodyBytes))
+	}
+	return nil
+}
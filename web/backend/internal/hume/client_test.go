@@ -0,0 +1,165 @@
+package hume_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/hume-evi/web/internal/hume"
+	"github.com/hume-evi/web/internal/hume/fakehume"
+)
+
+func newTestClient(t *testing.T) (*hume.Client, *fakehume.Server) {
+	t.Helper()
+	fake, server := fakehume.NewTestServer()
+	fake.APIKey = "test-key"
+	t.Cleanup(server.Close)
+	return hume.NewClient(server.URL, "test-key"), fake
+}
+
+func TestPromptVersions(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	prompt, err := client.CreatePrompt(ctx, "Sam", "Be kind.")
+	if err != nil {
+		t.Fatalf("CreatePrompt: %v", err)
+	}
+	if prompt.Version != 0 {
+		t.Fatalf("new prompt has version %d, want 0", prompt.Version)
+	}
+
+	next, err := client.CreatePromptVersion(ctx, prompt.ID, "Be kinder.", "edited")
+	if err != nil {
+		t.Fatalf("CreatePromptVersion: %v", err)
+	}
+	if next.ID != prompt.ID || next.Version != 1 {
+		t.Fatalf("got %s version %d, want %s version 1", next.ID, next.Version, prompt.ID)
+	}
+
+	latest, err := client.GetPrompt(ctx, prompt.ID)
+	if err != nil {
+		t.Fatalf("GetPrompt: %v", err)
+	}
+	if latest.Version != 1 || latest.Text != "Be kinder." {
+		t.Fatalf("GetPrompt = version %d %q, want version 1 %q", latest.Version, latest.Text, "Be kinder.")
+	}
+
+	first, err := client.GetPromptVersion(ctx, prompt.ID, 0)
+	if err != nil {
+		t.Fatalf("GetPromptVersion: %v", err)
+	}
+	if first.Text != "Be kind." {
+		t.Fatalf("version 0 text = %q, want %q", first.Text, "Be kind.")
+	}
+}
+
+func TestConfigVersions(t *testing.T) {
+	client, fake := newTestClient(t)
+	ctx := context.Background()
+
+	prompt, err := client.CreatePrompt(ctx, "Sam", "Be kind.")
+	if err != nil {
+		t.Fatalf("CreatePrompt: %v", err)
+	}
+	req := hume.ConfigRequest{
+		Name:       "Sam",
+		EVIVersion: "3",
+		Prompt:     &hume.PromptReference{ID: prompt.ID, Version: prompt.Version},
+		LanguageModel: &hume.LanguageModel{
+			ModelProvider: hume.DefaultModelProvider,
+			ModelResource: hume.DefaultModelResource,
+			Temperature:   1,
+		},
+	}
+	config, err := client.CreateConfig(ctx, req)
+	if err != nil {
+		t.Fatalf("CreateConfig: %v", err)
+	}
+
+	req.Name = "Renamed"
+	req.LanguageModel.Temperature = 0.5
+	next, err := client.CreateConfigVersion(ctx, config.ID, req)
+	if err != nil {
+		t.Fatalf("CreateConfigVersion: %v", err)
+	}
+	if next.ID != config.ID || next.Version != 1 {
+		t.Fatalf("got %s version %d, want %s version 1", next.ID, next.Version, config.ID)
+	}
+
+	latest, err := client.GetConfig(ctx, config.ID)
+	if err != nil {
+		t.Fatalf("GetConfig: %v", err)
+	}
+	if latest.Version != 1 || latest.Name != "Sam" || latest.LanguageModel == nil || latest.LanguageModel.Temperature != 0.5 {
+		t.Fatalf("GetConfig = %+v, want version 1 named Sam at temperature 0.5", latest)
+	}
+	if n := len(fake.ConfigVersions(config.ID)); n != 2 {
+		t.Fatalf("fake holds %d versions, want 2", n)
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	if _, err := client.GetPrompt(ctx, "missing"); !hume.IsNotFound(err) {
+		t.Errorf("GetPrompt: got %v, want a 404", err)
+	}
+	if _, err := client.CreatePromptVersion(ctx, "missing", "text", ""); !hume.IsNotFound(err) {
+		t.Errorf("CreatePromptVersion: got %v, want a 404", err)
+	}
+	if _, err := client.GetConfig(ctx, "missing"); !hume.IsNotFound(err) {
+		t.Errorf("GetConfig: got %v, want a 404", err)
+	}
+	if _, err := client.CreateConfigVersion(ctx, "missing", hume.ConfigRequest{EVIVersion: "3"}); !hume.IsNotFound(err) {
+		t.Errorf("CreateConfigVersion: got %v, want a 404", err)
+	}
+}
+
+func TestConflict(t *testing.T) {
+	client, fake := newTestClient(t)
+	ctx := context.Background()
+
+	prompt, err := client.CreatePrompt(ctx, "Sam", "Be kind.")
+	if err != nil {
+		t.Fatalf("CreatePrompt: %v", err)
+	}
+
+	// A duplicate name names the existing prompt
+	_, err = client.CreatePrompt(ctx, "Sam", "Something else.")
+	var statusErr *hume.StatusError
+	if !hume.IsConflict(err) || !errors.As(err, &statusErr) {
+		t.Fatalf("got %v, want a 409", err)
+	}
+	if id := statusErr.DuplicateID(); id != prompt.ID {
+		t.Fatalf("DuplicateID() = %q, want %q", id, prompt.ID)
+	}
+
+	// Some conflicts don't say which resource they clash with
+	fake.InjectConflict("/v0/evi/prompts")
+	_, err = client.CreatePrompt(ctx, "Alex", "Be brief.")
+	if !hume.IsConflict(err) || !errors.As(err, &statusErr) {
+		t.Fatalf("got %v, want a 409", err)
+	}
+	if id := statusErr.DuplicateID(); id != "" {
+		t.Fatalf("DuplicateID() = %q, want none", id)
+	}
+
+	// The fault only applies once
+	if _, err := client.CreatePrompt(ctx, "Alex", "Be brief."); err != nil {
+		t.Fatalf("CreatePrompt after the injected conflict: %v", err)
+	}
+}
+
+func TestUnauthorized(t *testing.T) {
+	fake, server := fakehume.NewTestServer()
+	fake.APIKey = "test-key"
+	defer server.Close()
+
+	client := hume.NewClient(server.URL, "wrong-key")
+	if _, err := client.ListPrompts(context.Background()); !hume.IsStatus(err, http.StatusUnauthorized) {
+		t.Fatalf("got %v, want a 401", err)
+	}
+}
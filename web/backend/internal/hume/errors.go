@@ -0,0 +1,77 @@
+package hume
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// StatusError is returned when the Hume API responds with a non-2xx status.
+// Slug and Message are filled in when the body is a Hume error document.
+type StatusError struct {
+	StatusCode int
+	Slug       string
+	Message    string
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	if e.Slug != "" {
+		return fmt.Sprintf("Hume API error (status %d, %s): %s", e.StatusCode, e.Slug, e.Body)
+	}
+	return fmt.Sprintf("Hume API error (status %d): %s", e.StatusCode, e.Body)
+}
+
+// DuplicateID extracts the ID of the existing resource from a 409 response,
+// e.g. "Prevented duplicate key for prompt (ID: <uuid>, NAME: <name>)".
+// It returns "" when the message doesn't name one.
+func (e *StatusError) DuplicateID() string {
+	if e.StatusCode != http.StatusConflict {
+		return ""
+	}
+
+	start := strings.Index(e.Message, "ID: ")
+	if start == -1 {
+		return ""
+	}
+	start += len("ID: ")
+
+	end := strings.IndexAny(e.Message[start:], ",)")
+	if end == -1 {
+		return ""
+	}
+	return strings.TrimSpace(e.Message[start : start+end])
+}
+
+func newStatusError(statusCode int, body []byte) *StatusError {
+	statusErr := &StatusError{StatusCode: statusCode, Body: string(body)}
+
+	var doc struct {
+		Slug    string `json:"slug"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &doc); err == nil {
+		statusErr.Slug = doc.Slug
+		statusErr.Message = doc.Message
+	}
+	return statusErr
+}
+
+// IsStatus reports whether err is a StatusError with the given status code
+func IsStatus(err error, statusCode int) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == statusCode
+}
+
+// IsConflict reports whether the API rejected a duplicate name
+func IsConflict(err error) bool {
+	return IsStatus(err, http.StatusConflict)
+}
+
+// IsNotFound reports whether the requested resource doesn't exist (or isn't
+// visible to this API key)
+func IsNotFound(err error) bool {
+	return IsStatus(err, http.StatusNotFound)
+}
@@ -0,0 +1,41 @@
+package hume
+
+import "fmt"
+
+// Timeout limits accepted by EVI, in seconds
+const (
+	MinInactivityTimeout  = 30
+	MaxInactivityTimeout  = 1800
+	MinMaxDurationTimeout = 30
+	MaxMaxDurationTimeout = 7200
+)
+
+// NewEventMessages builds a config's event messages, enabling each one that
+// has text
+func NewEventMessages(greeting, inactivity, maxDuration string) *EventMessages {
+	return &EventMessages{
+		OnNewChat:            EventMessage{Enabled: greeting != "", Text: greeting},
+		OnInactivityTimeout:  EventMessage{Enabled: inactivity != "", Text: inactivity},
+		OnMaxDurationTimeout: EventMessage{Enabled: maxDuration != "", Text: maxDuration},
+	}
+}
+
+// NewTimeouts builds a config's timeouts; 0 leaves a timeout at Hume's default
+func NewTimeouts(inactivitySecs, maxDurationSecs int) *Timeouts {
+	return &Timeouts{
+		Inactivity:  Timeout{Enabled: inactivitySecs > 0, DurationSecs: inactivitySecs},
+		MaxDuration: Timeout{Enabled: maxDurationSecs > 0, DurationSecs: maxDurationSecs},
+	}
+}
+
+// ValidateTimeouts checks timeout durations against EVI's limits. 0 means
+// unset and is always valid.
+func ValidateTimeouts(inactivitySecs, maxDurationSecs int) error {
+	if inactivitySecs != 0 && (inactivitySecs < MinInactivityTimeout || inactivitySecs > MaxInactivityTimeout) {
+		return fmt.Errorf("inactivity timeout must be between %d and %d seconds", MinInactivityTimeout, MaxInactivityTimeout)
+	}
+	if maxDurationSecs != 0 && (maxDurationSecs < MinMaxDurationTimeout || maxDurationSecs > MaxMaxDurationTimeout) {
+		return fmt.Errorf("max duration must be between %d and %d seconds", MinMaxDurationTimeout, MaxMaxDurationTimeout)
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package fakehume
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// audioFramesPerTurn is how many audio_input frames count as one utterance
+const audioFramesPerTurn = 20
+
+// Turn is one scripted exchange on /v0/evi/chat
+type Turn struct {
+	// User is the transcript reported when the turn is triggered by audio;
+	// a user_input frame uses its own text instead
+	User      string `json:"user"`
+	Assistant string `json:"assistant"`
+}
+
+// silence is 100ms of base64-encoded 16-bit mono WAV, used for all audio
+var silence = silentWAV(44100, 4410)
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// Script sets the turns replayed, in order, by every chat. Once they run out
+// the assistant echoes the user.
+func (s *Server) Script(turns ...Turn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.turns = turns
+	s.nextTurn = 0
+}
+
+// Received returns every frame clients have sent over /v0/evi/chat
+func (s *Server) Received() []json.RawMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]json.RawMessage(nil), s.received...)
+}
+
+func (s *Server) takeTurn(userText string) Turn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	turn := Turn{User: userText}
+	if s.nextTurn < len(s.turns) {
+		scripted := s.turns[s.nextTurn]
+		s.nextTurn++
+		if turn.User == "" {
+			turn.User = scripted.User
+		}
+		turn.Assistant = scripted.Assistant
+	}
+	if turn.User == "" {
+		turn.User = "Hello?"
+	}
+	if turn.Assistant == "" {
+		turn.Assistant = "You said: " + turn.User
+	}
+	return turn
+}
+
+func (s *Server) chat(w http.ResponseWriter, r *http.Request) {
+	configID := r.URL.Query().Get("config_id")
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	if configID != "" && len(s.ConfigVersions(configID)) == 0 {
+		conn.WriteJSON(map[string]interface{}{
+			"type":    "error",
+			"code":    "E0101",
+			"slug":    "config_not_found",
+			"message": fmt.Sprintf("Config %s not found", configID),
+		})
+		return
+	}
+
+	chatID := uuid.NewString()
+	conn.WriteJSON(map[string]interface{}{
+		"type":          "chat_metadata",
+		"chat_id":       chatID,
+		"chat_group_id": uuid.NewString(),
+	})
+
+	audioFrames := 0
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.received = append(s.received, json.RawMessage(data))
+		s.mu.Unlock()
+
+		var msg struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		}
+		if err := json.Unmarshal(data, &msg); err != nil {
+			conn.WriteJSON(map[string]interface{}{"type": "error", "code": "E0102", "slug": "invalid_message", "message": err.Error()})
+			continue
+		}
+
+		switch msg.Type {
+		case "user_input":
+			err = respond(conn, s.takeTurn(msg.Text))
+		case "audio_input":
+			audioFrames++
+			if audioFrames%audioFramesPerTurn == 0 {
+				err = respond(conn, s.takeTurn(""))
+			}
+		case "assistant_input":
+			err = respondAssistant(conn, msg.Text)
+		}
+		if err != nil {
+			log.Printf("fakehume: chat %s write failed: %v", chatID, err)
+			return
+		}
+	}
+}
+
+// respond plays one exchange: the user's transcript, then the assistant's
+// reply with audio
+func respond(conn *websocket.Conn, turn Turn) error {
+	if err := conn.WriteJSON(map[string]interface{}{
+		"type":    "user_message",
+		"message": map[string]string{"role": "user", "content": turn.User},
+		"models":  prosody(),
+	}); err != nil {
+		return err
+	}
+	return respondAssistant(conn, turn.Assistant)
+}
+
+func respondAssistant(conn *websocket.Conn, text string) error {
+	id := uuid.NewString()
+	frames := []map[string]interface{}{
+		{
+			"type":    "assistant_message",
+			"id":      id,
+			"message": map[string]string{"role": "assistant", "content": text},
+			"models":  prosody(),
+		},
+		{"type": "audio_output", "id": id, "index": 0, "data": silence},
+		{"type": "assistant_end"},
+	}
+	for _, frame := range frames {
+		if err := conn.WriteJSON(frame); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prosody returns fixed expression scores in the shape EVI uses
+func prosody() map[string]interface{} {
+	return map[string]interface{}{
+		"prosody": map[string]interface{}{
+			"scores": map[string]float64{
+				"Calmness":      0.42,
+				"Interest":      0.31,
+				"Determination": 0.12,
+			},
+		},
+	}
+}
+
+func silentWAV(sampleRate, samples int) string {
+	dataSize := samples * 2
+
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36+dataSize))
+	buf.WriteString("WAVEfmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(16))
+	binary.Write(&buf, binary.LittleEndian, uint16(1)) // PCM
+	binary.Write(&buf, binary.LittleEndian, uint16(1)) // mono
+	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate))
+	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate*2))
+	binary.Write(&buf, binary.LittleEndian, uint16(2))
+	binary.Write(&buf, binary.LittleEndian, uint16(16))
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(dataSize))
+	buf.Write(make([]byte, dataSize))
+
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
@@ -0,0 +1,553 @@
+// Package fakehume is an in-memory stand-in for the Hume REST and EVI chat
+// APIs, for integration tests (NewTestServer) and offline development
+// (cmd/fakehume). It models the endpoints the server uses: prompts, configs
+// and their versions, TTS generations, saved TTS voices and /v0/evi/chat.
+package fakehume
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"github.com/hume-evi/web/internal/hume"
+)
+
+// Fault makes matching requests fail instead of being served
+type Fault struct {
+	// Method matches any method when empty
+	Method string
+	// PathPrefix matches request paths starting with it, e.g. "/v0/evi/prompts"
+	PathPrefix string
+	Status     int
+	// Slug and Message are returned in a Hume-style error body
+	Slug    string
+	Message string
+	// Times is how many requests fail before the fault clears; 0 never clears
+	Times int
+}
+
+// Config is one stored version of an EVI config
+type Config struct {
+	hume.ConfigRequest
+	ID        string    `json:"id"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_on"`
+}
+
+// Server is the fake. The zero value is not usable; call New.
+type Server struct {
+	// APIKey, when set, must match the X-Hume-Api-Key header
+	APIKey string
+
+	mu          sync.Mutex
+	router      *mux.Router
+	faults      []*Fault
+	prompts     map[string][]hume.Prompt // ID -> versions, oldest first
+	configs     map[string][]Config      // ID -> versions, oldest first
+	generations map[string]bool
+	voices      []hume.Voice
+	turns       []Turn
+	nextTurn    int
+	received    []json.RawMessage
+}
+
+// New creates an empty fake
+func New() *Server {
+	s := &Server{
+		router:      mux.NewRouter(),
+		prompts:     make(map[string][]hume.Prompt),
+		configs:     make(map[string][]Config),
+		generations: make(map[string]bool),
+	}
+
+	r := s.router
+	r.HandleFunc("/v0/evi/prompts", s.createPrompt).Methods("POST")
+	r.HandleFunc("/v0/evi/prompts", s.listPrompts).Methods("GET")
+	r.HandleFunc("/v0/evi/prompts/{id}", s.getPromptVersions).Methods("GET")
+	r.HandleFunc("/v0/evi/prompts/{id}", s.createPromptVersion).Methods("POST")
+	r.HandleFunc("/v0/evi/prompts/{id}/version/{version}", s.getPromptVersion).Methods("GET")
+	r.HandleFunc("/v0/evi/configs", s.createConfig).Methods("POST")
+	r.HandleFunc("/v0/evi/configs", s.listConfigs).Methods("GET")
+	r.HandleFunc("/v0/evi/configs/{id}", s.getConfigVersions).Methods("GET")
+	r.HandleFunc("/v0/evi/configs/{id}", s.deleteConfig).Methods("DELETE")
+	r.HandleFunc("/v0/evi/configs/{id}", s.createConfigVersion).Methods("POST")
+	r.HandleFunc("/v0/tts", s.synthesize).Methods("POST")
+	r.HandleFunc("/v0/tts/voices", s.saveVoice).Methods("POST")
+	r.HandleFunc("/v0/tts/voices", s.listVoices).Methods("GET")
+	r.HandleFunc("/v0/evi/chat", s.chat).Methods("GET")
+	return s
+}
+
+// NewTestServer starts a fake on a loopback address. Point the client at
+// ts.URL (REST) or hume.ChatURL(ts.URL, configID) (EVI), and Close ts when done.
+func NewTestServer() (*Server, *httptest.Server) {
+	s := New()
+	return s, httptest.NewServer(s)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("X-Hume-Api-Key")
+	if key == "" {
+		key = r.URL.Query().Get("api_key")
+	}
+	if key == "" || (s.APIKey != "" && key != s.APIKey) {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "Invalid or missing API key")
+		return
+	}
+
+	if fault := s.takeFault(r); fault != nil {
+		message := fault.Message
+		if message == "" {
+			message = http.StatusText(fault.Status)
+		}
+		writeError(w, fault.Status, fault.Slug, message)
+		return
+	}
+
+	s.router.ServeHTTP(w, r)
+}
+
+// Inject adds a fault; faults are matched in the order they were added
+func (s *Server) Inject(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// InjectConflict makes the next create under pathPrefix fail with a 409
+// whose message names no existing resource, as Hume does for some duplicates
+func (s *Server) InjectConflict(pathPrefix string) {
+	s.Inject(Fault{
+		Method:     "POST",
+		PathPrefix: pathPrefix,
+		Status:     http.StatusConflict,
+		Slug:       "duplicate_key",
+		Message:    "Prevented duplicate key",
+		Times:      1,
+	})
+}
+
+// ClearFaults removes all injected faults
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+func (s *Server) takeFault(r *http.Request) *Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, f := range s.faults {
+		if f.Method != "" && f.Method != r.Method {
+			continue
+		}
+		if !strings.HasPrefix(r.URL.Path, f.PathPrefix) {
+			continue
+		}
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+// SeedConfig stores a config under a fixed ID, e.g. the HUME_CONFIG_ID the
+// server falls back to
+func (s *Server) SeedConfig(id, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.configs[id] = []Config{{
+		ConfigRequest: hume.ConfigRequest{Name: name, EVIVersion: "3"},
+		ID:            id,
+		CreatedAt:     time.Now(),
+	}}
+}
+
+// SeedConfigVersion stores req as the next version of config id without the
+// checks the API makes, e.g. for a config built in Hume's UI with a model the
+// catalogue no longer lists
+func (s *Server) SeedConfigVersion(id string, req hume.ConfigRequest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	versions := s.configs[id]
+	if len(versions) > 0 {
+		req.Name = versions[0].Name
+	}
+	s.configs[id] = append(versions, Config{ConfigRequest: req, ID: id, Version: len(versions), CreatedAt: time.Now()})
+}
+
+// Prompts returns the latest version of every prompt
+func (s *Server) Prompts() []hume.Prompt {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prompts := make([]hume.Prompt, 0, len(s.prompts))
+	for _, versions := range s.prompts {
+		prompts = append(prompts, versions[len(versions)-1])
+	}
+	sort.Slice(prompts, func(i, j int) bool { return prompts[i].Name < prompts[j].Name })
+	return prompts
+}
+
+// ConfigVersions returns every stored version of a config, oldest first
+func (s *Server) ConfigVersions(id string) []Config {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Config(nil), s.configs[id]...)
+}
+
+// Voices returns the saved TTS voices
+func (s *Server) Voices() []hume.Voice {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]hume.Voice(nil), s.voices...)
+}
+
+// ExpireGenerations forgets every TTS generation, as Hume does after a
+// while; saving one as a voice then fails with 404
+func (s *Server) ExpireGenerations() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.generations)
+}
+
+func (s *Server) createPrompt(w http.ResponseWriter, r *http.Request) {
+	var req hume.Prompt
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" || req.Text == "" {
+		writeError(w, http.StatusBadRequest, "invalid_request", "name and text are required")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, versions := range s.prompts {
+		if versions[0].Name == req.Name {
+			writeError(w, http.StatusConflict, "duplicate_key",
+				fmt.Sprintf("Prevented duplicate key for prompt (ID: %s, NAME: %s)", id, req.Name))
+			return
+		}
+	}
+
+	prompt := hume.Prompt{ID: uuid.NewString(), Name: req.Name, Text: req.Text, Version: 0}
+	s.prompts[prompt.ID] = []hume.Prompt{prompt}
+	writeJSON(w, http.StatusCreated, prompt)
+}
+
+func (s *Server) listPrompts(w http.ResponseWriter, r *http.Request) {
+	writePage(w, r, "prompts_page", s.Prompts())
+}
+
+func (s *Server) getPromptVersions(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	versions := append([]hume.Prompt(nil), s.prompts[mux.Vars(r)["id"]]...)
+	s.mu.Unlock()
+
+	if len(versions) == 0 {
+		writeError(w, http.StatusNotFound, "not_found", "Prompt not found")
+		return
+	}
+	writePage(w, r, "prompts_page", versions)
+}
+
+func (s *Server) getPromptVersion(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	version, err := strconv.Atoi(vars["version"])
+
+	s.mu.Lock()
+	versions := s.prompts[vars["id"]]
+	s.mu.Unlock()
+
+	if err != nil || version < 0 || version >= len(versions) {
+		writeError(w, http.StatusNotFound, "not_found", "Prompt version not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, versions[version])
+}
+
+func (s *Server) createPromptVersion(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Text == "" {
+		writeError(w, http.StatusBadRequest, "invalid_request", "text is required")
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	versions, ok := s.prompts[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "Prompt not found")
+		return
+	}
+
+	prompt := hume.Prompt{ID: id, Name: versions[0].Name, Text: req.Text, Version: len(versions)}
+	s.prompts[id] = append(versions, prompt)
+	writeJSON(w, http.StatusCreated, prompt)
+}
+
+func (s *Server) createConfig(w http.ResponseWriter, r *http.Request) {
+	var req hume.ConfigRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
+		writeError(w, http.StatusBadRequest, "invalid_request", "name is required")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if msg := s.validateConfigLocked(req); msg != "" {
+		writeError(w, http.StatusBadRequest, "invalid_request", msg)
+		return
+	}
+
+	for id, versions := range s.configs {
+		if versions[0].Name == req.Name {
+			writeError(w, http.StatusConflict, "duplicate_key",
+				fmt.Sprintf("Prevented duplicate key for config (ID: %s, NAME: %s)", id, req.Name))
+			return
+		}
+	}
+
+	config := Config{ConfigRequest: req, ID: uuid.NewString(), Version: 0, CreatedAt: time.Now()}
+	s.configs[config.ID] = []Config{config}
+	writeJSON(w, http.StatusCreated, config)
+}
+
+func (s *Server) createConfigVersion(w http.ResponseWriter, r *http.Request) {
+	var req hume.ConfigRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "invalid config")
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	versions, ok := s.configs[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "Config not found")
+		return
+	}
+	if msg := s.validateConfigLocked(req); msg != "" {
+		writeError(w, http.StatusBadRequest, "invalid_request", msg)
+		return
+	}
+
+	// Versions keep the config's original name
+	req.Name = versions[0].Name
+	config := Config{ConfigRequest: req, ID: id, Version: len(versions), CreatedAt: time.Now()}
+	s.configs[id] = append(versions, config)
+	writeJSON(w, http.StatusCreated, config)
+}
+
+// validateConfigLocked checks the language model, timeouts, and that
+// referenced voices and prompts exist
+func (s *Server) validateConfigLocked(req hume.ConfigRequest) string {
+	if req.LanguageModel != nil {
+		if err := hume.ValidateLanguageModel(req.LanguageModel.ModelProvider, req.LanguageModel.ModelResource); err != nil {
+			return err.Error()
+		}
+	}
+	if t := req.Timeouts; t != nil {
+		if err := hume.ValidateTimeouts(t.Inactivity.DurationSecs, t.MaxDuration.DurationSecs); err != nil {
+			return err.Error()
+		}
+	}
+	if req.Voice != nil && req.Voice.Provider == hume.VoiceProviderCustom {
+		if req.Voice.ID == nil || !s.hasVoiceLocked(*req.Voice.ID) {
+			return "custom voice does not exist"
+		}
+	}
+	if req.Prompt == nil {
+		return ""
+	}
+	versions, ok := s.prompts[req.Prompt.ID]
+	if !ok || req.Prompt.Version < 0 || req.Prompt.Version >= len(versions) {
+		return fmt.Sprintf("prompt %s version %d does not exist", req.Prompt.ID, req.Prompt.Version)
+	}
+	return ""
+}
+
+func (s *Server) hasVoiceLocked(id string) bool {
+	for _, v := range s.voices {
+		if v.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) listConfigs(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	configs := make([]Config, 0, len(s.configs))
+	for _, versions := range s.configs {
+		configs = append(configs, versions[len(versions)-1])
+	}
+	s.mu.Unlock()
+
+	sort.Slice(configs, func(i, j int) bool { return configs[i].Name < configs[j].Name })
+	writePage(w, r, "configs_page", configs)
+}
+
+func (s *Server) getConfigVersions(w http.ResponseWriter, r *http.Request) {
+	versions := s.ConfigVersions(mux.Vars(r)["id"])
+	if len(versions) == 0 {
+		writeError(w, http.StatusNotFound, "not_found", "Config not found")
+		return
+	}
+	writePage(w, r, "configs_page", versions)
+}
+
+func (s *Server) deleteConfig(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.configs[id]; !ok {
+		writeError(w, http.StatusNotFound, "not_found", "Config not found")
+		return
+	}
+	delete(s.configs, id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) synthesize(w http.ResponseWriter, r *http.Request) {
+	var req hume.TTSRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Utterances) == 0 {
+		writeError(w, http.StatusBadRequest, "invalid_request", "at least one utterance is required")
+		return
+	}
+
+	n := req.NumGenerations
+	if n <= 0 {
+		n = 1
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := hume.TTSResponse{Generations: make([]hume.Generation, 0, n)}
+	for i := 0; i < n; i++ {
+		id := uuid.NewString()
+		s.generations[id] = true
+		resp.Generations = append(resp.Generations, hume.Generation{
+			GenerationID: id,
+			Audio:        silence,
+			Encoding:     &hume.Encoding{Format: "wav", SampleRate: 44100},
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) saveVoice(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		GenerationID string `json:"generation_id"`
+		Name         string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.GenerationID == "" || req.Name == "" {
+		writeError(w, http.StatusBadRequest, "invalid_request", "generation_id and name are required")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.generations[req.GenerationID] {
+		writeError(w, http.StatusNotFound, "not_found", "Generation not found")
+		return
+	}
+	for _, v := range s.voices {
+		if v.Name == req.Name {
+			writeError(w, http.StatusConflict, "duplicate_key",
+				fmt.Sprintf("Prevented duplicate key for voice (ID: %s, NAME: %s)", v.ID, v.Name))
+			return
+		}
+	}
+
+	voice := hume.Voice{ID: uuid.NewString(), Name: req.Name, Provider: hume.VoiceProviderCustom}
+	s.voices = append(s.voices, voice)
+	writeJSON(w, http.StatusOK, voice)
+}
+
+// libraryVoices stands in for Hume's shared voice library
+var libraryVoices = []hume.Voice{
+	{ID: "5add9038-28df-40a6-900c-2f736d008ab3", Name: "Ito", Provider: hume.VoiceProviderHume},
+	{ID: "9e068547-5ba4-4c8e-8e03-69282a008f04", Name: "Kora", Provider: hume.VoiceProviderHume},
+	{ID: "d2e3b4a1-8f5c-4a0e-9b7d-3c1f6e2a9b88", Name: "Dacher", Provider: hume.VoiceProviderHume},
+}
+
+func (s *Server) listVoices(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Query().Get("provider") {
+	case hume.VoiceProviderHume:
+		writePage(w, r, "voices_page", libraryVoices)
+	case hume.VoiceProviderCustom:
+		writePage(w, r, "voices_page", s.Voices())
+	default:
+		writeError(w, http.StatusBadRequest, "invalid_request", "provider must be CUSTOM_VOICE or HUME_AI")
+	}
+}
+
+// writePage returns items in Hume's paged list shape, honouring page_number
+// and page_size
+func writePage[T any](w http.ResponseWriter, r *http.Request, key string, items []T) {
+	pageSize, err := strconv.Atoi(r.URL.Query().Get("page_size"))
+	if err != nil || pageSize <= 0 {
+		pageSize = 10
+	}
+	pageNumber, err := strconv.Atoi(r.URL.Query().Get("page_number"))
+	if err != nil || pageNumber < 0 {
+		pageNumber = 0
+	}
+
+	start := pageNumber * pageSize
+	if start > len(items) {
+		start = len(items)
+	}
+	end := start + pageSize
+	if end > len(items) {
+		end = len(items)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"page_number": pageNumber,
+		"page_size":   pageSize,
+		"total_pages": (len(items) + pageSize - 1) / pageSize,
+		key:           append([]T{}, items[start:end]...),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, slug, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"status_code": status,
+		"slug":        slug,
+		"message":     message,
+	})
+}
@@ -0,0 +1,102 @@
+package hume
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+)
+
+// CustomLanguageModel is the provider for a self-hosted LLM endpoint; its
+// model resource is the endpoint URL rather than a model name
+const CustomLanguageModel = "CUSTOM_LANGUAGE_MODEL"
+
+// Default language model for new voices
+const (
+	DefaultModelProvider = "ANTHROPIC"
+	DefaultModelResource = "claude-3-7-sonnet-latest"
+)
+
+// languageModels is the catalogue of provider/model pairs EVI configs may
+// use. Add entries here as Hume supports new models.
+var languageModels = map[string][]string{
+	"ANTHROPIC": {
+		"claude-3-7-sonnet-latest",
+		"claude-3-5-sonnet-latest",
+		"claude-3-5-haiku-latest",
+		"claude-3-haiku-20240307",
+		"claude-sonnet-4-20250514",
+	},
+	"OPEN_AI": {
+		"gpt-4o",
+		"gpt-4o-mini",
+		"gpt-4.1",
+		"gpt-4.1-mini",
+	},
+	"GOOGLE": {
+		"gemini-2.0-flash",
+		"gemini-1.5-pro",
+		"gemini-1.5-flash",
+	},
+	"GROQ": {
+		"llama-3.3-70b-versatile",
+		"llama-3.1-8b-instant",
+	},
+	"FIREWORKS": {
+		"accounts/fireworks/models/llama-v3p1-405b-instruct",
+		"accounts/fireworks/models/llama-v3p1-70b-instruct",
+	},
+}
+
+// LanguageModelOption lists the models available from one provider
+type LanguageModelOption struct {
+	Provider string   `json:"provider"`
+	Models   []string `json:"models"`
+	// CustomURL is set for CUSTOM_LANGUAGE_MODEL, whose model is a URL
+	CustomURL bool `json:"custom_url,omitempty"`
+}
+
+// LanguageModels returns the catalogue, sorted by provider
+func LanguageModels() []LanguageModelOption {
+	providers := make([]string, 0, len(languageModels))
+	for provider := range languageModels {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+
+	options := make([]LanguageModelOption, 0, len(providers)+1)
+	for _, provider := range providers {
+		options = append(options, LanguageModelOption{
+			Provider: provider,
+			Models:   append([]string(nil), languageModels[provider]...),
+		})
+	}
+	return append(options, LanguageModelOption{Provider: CustomLanguageModel, Models: []string{}, CustomURL: true})
+}
+
+// ValidateLanguageModel checks a provider/model pair against the catalogue.
+// For CUSTOM_LANGUAGE_MODEL the resource must be an absolute http(s) or
+// ws(s) URL.
+func ValidateLanguageModel(provider, resource string) error {
+	if provider == CustomLanguageModel {
+		u, err := url.Parse(resource)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("custom language model must be an absolute URL, got %q", resource)
+		}
+		switch u.Scheme {
+		case "https", "wss", "http", "ws":
+			return nil
+		}
+		return fmt.Errorf("custom language model URL must use http(s) or ws(s), got %q", u.Scheme)
+	}
+
+	models, ok := languageModels[provider]
+	if !ok {
+		return fmt.Errorf("unsupported language model provider %q", provider)
+	}
+	for _, model := range models {
+		if model == resource {
+			return nil
+		}
+	}
+	return fmt.Errorf("model %q is not supported for provider %s", resource, provider)
+}
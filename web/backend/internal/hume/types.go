@@ -0,0 +1,170 @@
+package hume
+
+// Prompt is an EVI system prompt. Each update creates a new version.
+type Prompt struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Text    string `json:"text"`
+	Version int    `json:"version"`
+}
+
+type promptRequest struct {
+	Name string `json:"name"`
+	Text string `json:"text"`
+}
+
+type promptVersionRequest struct {
+	Text               string `json:"text"`
+	VersionDescription string `json:"version_description,omitempty"`
+}
+
+// promptPage is a page of prompts (or of one prompt's versions)
+type promptPage struct {
+	PageNumber  int      `json:"page_number"`
+	PageSize    int      `json:"page_size"`
+	TotalPages  int      `json:"total_pages"`
+	PromptsPage []Prompt `json:"prompts_page"`
+}
+
+type promptListResponse struct {
+	ResultsPage struct {
+		Results []Prompt `json:"results"`
+	} `json:"results_page"`
+}
+
+// ConfigRequest is the body for creating an EVI config or a new version of one
+type ConfigRequest struct {
+	Name               string           `json:"name"`
+	EVIVersion         string           `json:"eviVersion"`
+	VersionDescription string           `json:"versionDescription,omitempty"`
+	Prompt             *PromptReference `json:"prompt,omitempty"`
+	Voice              *VoiceReference  `json:"voice"`
+	LanguageModel      *LanguageModel   `json:"languageModel"`
+	EventMessages      *EventMessages   `json:"eventMessages,omitempty"`
+	Timeouts           *Timeouts        `json:"timeouts,omitempty"`
+}
+
+type PromptReference struct {
+	ID      string `json:"id"`
+	Version int    `json:"version"`
+}
+
+type VoiceReference struct {
+	Provider string  `json:"provider"`
+	Name     *string `json:"name,omitempty"`
+	ID       *string `json:"id,omitempty"`
+}
+
+type LanguageModel struct {
+	ModelProvider string  `json:"modelProvider"`
+	ModelResource string  `json:"modelResource"`
+	Temperature   float64 `json:"temperature"`
+}
+
+type EventMessage struct {
+	Enabled bool   `json:"enabled"`
+	Text    string `json:"text"`
+}
+
+type EventMessages struct {
+	OnNewChat            EventMessage `json:"onNewChat"`
+	OnInactivityTimeout  EventMessage `json:"onInactivityTimeout"`
+	OnMaxDurationTimeout EventMessage `json:"onMaxDurationTimeout"`
+}
+
+// Timeout ends a chat after DurationSecs; a disabled timeout falls back to
+// Hume's default
+type Timeout struct {
+	Enabled      bool `json:"enabled"`
+	DurationSecs int  `json:"durationSecs,omitempty"`
+}
+
+type Timeouts struct {
+	Inactivity  Timeout `json:"inactivity"`
+	MaxDuration Timeout `json:"maxDuration"`
+}
+
+// Config is an EVI configuration as returned by the API. The settings are
+// only populated by reads (GetConfig, ListConfigs).
+type Config struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Version    int    `json:"version"`
+	EVIVersion string `json:"eviVersion,omitempty"`
+	// Prompt carries its text only when the API inlines it
+	Prompt        *Prompt         `json:"prompt,omitempty"`
+	Voice         *VoiceReference `json:"voice,omitempty"`
+	LanguageModel *LanguageModel  `json:"languageModel,omitempty"`
+	EventMessages *EventMessages  `json:"eventMessages,omitempty"`
+	Timeouts      *Timeouts       `json:"timeouts,omitempty"`
+}
+
+// ConfigPage is one page of configs (or of one config's versions)
+type ConfigPage struct {
+	PageNumber int      `json:"page_number"`
+	PageSize   int      `json:"page_size"`
+	TotalPages int      `json:"total_pages"`
+	Configs    []Config `json:"configs_page"`
+}
+
+// TTSRequest asks the TTS API to design a voice from a description
+type TTSRequest struct {
+	Utterances     []Utterance  `json:"utterances"`
+	NumGenerations int          `json:"num_generations"`
+	Version        string       `json:"version"`
+	Format         *AudioFormat `json:"format,omitempty"`
+}
+
+// AudioFormat selects the encoding of generated audio ("mp3", "wav" or "pcm")
+type AudioFormat struct {
+	Type string `json:"type"`
+}
+
+type Utterance struct {
+	Text        string `json:"text"`
+	Description string `json:"description"`
+}
+
+type TTSResponse struct {
+	Generations []Generation `json:"generations"`
+}
+
+// Generation is one synthesized sample; its ID can be saved as a voice
+type Generation struct {
+	GenerationID string `json:"generation_id"`
+	// Audio is base64-encoded in the format given by Encoding
+	Audio    string    `json:"audio"`
+	Encoding *Encoding `json:"encoding,omitempty"`
+}
+
+type Encoding struct {
+	Format     string `json:"format"`
+	SampleRate int    `json:"sample_rate"`
+}
+
+// Voice providers: voices designed and saved by this account, or Hume's
+// shared voice library
+const (
+	VoiceProviderCustom = "CUSTOM_VOICE"
+	VoiceProviderHume   = "HUME_AI"
+)
+
+// Voice is a TTS voice that EVI configs can speak with
+type Voice struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Provider string `json:"provider,omitempty"`
+}
+
+// VoicePage is one page of voices
+type VoicePage struct {
+	PageNumber int     `json:"page_number"`
+	PageSize   int     `json:"page_size"`
+	TotalPages int     `json:"total_pages"`
+	Voices     []Voice `json:"voices_page"`
+}
+
+type saveVoiceRequest struct {
+	GenerationID string `json:"generation_id"`
+	Name         string `json:"name"`
+}
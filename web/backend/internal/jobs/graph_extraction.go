@@ -0,0 +1,174 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/hume-evi/web/internal/db"
+	"github.com/hume-evi/web/internal/graph"
+)
+
+const (
+	// pollInterval is how often the queue is checked for due retries
+	pollInterval = 10 * time.Second
+	// jobTimeout bounds a single extraction (LLM calls can be slow)
+	jobTimeout = 3 * time.Minute
+	// maxAttempts before a job is marked as failed
+	maxAttempts = 5
+	// retryBaseDelay doubles with each attempt, up to retryMaxDelay
+	retryBaseDelay = 30 * time.Second
+	retryMaxDelay  = 30 * time.Minute
+)
+
+// GraphExtractionQueue extracts a knowledge graph from each conversation once
+// it is paused or ended. Jobs live in the store, so with Postgres they survive
+// restarts, and their status is visible to admins.
+type GraphExtractionQueue struct {
+	db        db.Store
+	graph     *graph.Client
+	extractor graph.Extractor
+	wake      chan struct{}
+}
+
+// NewGraphExtractionQueue creates a queue; call Run to start processing
+func NewGraphExtractionQueue(database db.Store, graphClient *graph.Client, extractor graph.Extractor) *GraphExtractionQueue {
+	return &GraphExtractionQueue{
+		db:        database,
+		graph:     graphClient,
+		extractor: extractor,
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+// Enqueue schedules extraction for a conversation. A nil queue (no Memgraph)
+// ignores the request.
+func (q *GraphExtractionQueue) Enqueue(ctx context.Context, conversationID, userID uuid.UUID) error {
+	if q == nil {
+		return nil
+	}
+
+	if err := q.db.EnqueueGraphExtraction(ctx, conversationID, userID); err != nil {
+		return fmt.Errorf("failed to enqueue graph extraction: %w", err)
+	}
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run processes jobs until ctx is cancelled
+func (q *GraphExtractionQueue) Run(ctx context.Context) {
+	if requeued, err := q.db.RequeueRunningGraphExtractionJobs(ctx); err != nil {
+		log.Printf("Failed to requeue interrupted graph extraction jobs: %v", err)
+	} else if requeued > 0 {
+		log.Printf("Requeued %d interrupted graph extraction jobs", requeued)
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		q.drain(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-q.wake:
+		}
+	}
+}
+
+// drain processes due jobs until none are left
+func (q *GraphExtractionQueue) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		job, err := q.db.ClaimGraphExtractionJob(ctx)
+		if err != nil {
+			log.Printf("Failed to claim graph extraction job: %v", err)
+			return
+		}
+		if job == nil {
+			return
+		}
+
+		q.process(ctx, job)
+	}
+}
+
+func (q *GraphExtractionQueue) process(ctx context.Context, job *db.GraphExtractionJob) {
+	jobCtx, cancel := context.WithTimeout(ctx, jobTimeout)
+	defer cancel()
+
+	entities, relationships, err := q.extract(jobCtx, job)
+	if err == nil {
+		log.Printf("Graph extraction for conversation %s succeeded (%d entities, %d relationships)", job.ConversationID, entities, relationships)
+		if err := q.db.CompleteGraphExtractionJob(ctx, job.ConversationID, entities, relationships); err != nil {
+			log.Printf("Failed to record graph extraction result: %v", err)
+		}
+		return
+	}
+
+	var retryAt *time.Time
+	if job.Attempts < maxAttempts {
+		next := time.Now().Add(retryDelay(job.Attempts))
+		retryAt = &next
+		log.Printf("Graph extraction for conversation %s failed (attempt %d/%d), retrying at %s: %v",
+			job.ConversationID, job.Attempts, maxAttempts, next.Format(time.RFC3339), err)
+	} else {
+		log.Printf("Graph extraction for conversation %s failed permanently after %d attempts: %v", job.ConversationID, job.Attempts, err)
+	}
+
+	if err := q.db.FailGraphExtractionJob(ctx, job.ConversationID, err.Error(), retryAt); err != nil {
+		log.Printf("Failed to record graph extraction failure: %v", err)
+	}
+}
+
+func (q *GraphExtractionQueue) extract(ctx context.Context, job *db.GraphExtractionJob) (int, int, error) {
+	messages, err := q.db.GetMessages(ctx, job.ConversationID, job.UserID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to load messages: %w", err)
+	}
+	if len(messages) == 0 {
+		return 0, 0, nil
+	}
+
+	graphMessages := make([]graph.ConversationMessage, 0, len(messages))
+	for _, msg := range messages {
+		graphMessages = append(graphMessages, graph.ConversationMessage{Role: msg.Role, Content: msg.Content})
+	}
+
+	data, err := q.extractor.Extract(ctx, graphMessages)
+	if err != nil {
+		return 0, 0, fmt.Errorf("extraction failed: %w", err)
+	}
+
+	userID := job.UserID.String()
+	conversationID := job.ConversationID.String()
+	if err := q.graph.SyncConversation(ctx, userID, conversationID, graphMessages); err != nil {
+		return 0, 0, err
+	}
+
+	report, err := q.graph.IngestGraphData(ctx, userID, conversationID, data)
+	if err != nil {
+		return 0, 0, err
+	}
+	return report.Entities, report.Relationships, nil
+}
+
+// retryDelay is the exponential backoff after the given attempt
+func retryDelay(attempt int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= retryMaxDelay {
+			return retryMaxDelay
+		}
+	}
+	return delay
+}
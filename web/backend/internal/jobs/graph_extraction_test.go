@@ -0,0 +1,104 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hume-evi/web/internal/db"
+	"github.com/hume-evi/web/internal/db/memstore"
+	"github.com/hume-evi/web/internal/graph"
+)
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{4, 4 * time.Minute},
+		{6, 16 * time.Minute},
+		{7, retryMaxDelay},
+		{50, retryMaxDelay},
+	}
+	for _, tt := range tests {
+		if got := retryDelay(tt.attempt); got != tt.want {
+			t.Errorf("retryDelay(%d) = %s, want %s", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+type failingExtractor struct{ calls int }
+
+func (e *failingExtractor) Extract(ctx context.Context, messages []graph.ConversationMessage) (graph.GraphData, error) {
+	e.calls++
+	return graph.GraphData{}, errors.New("extractor down")
+}
+
+func TestGraphExtractionRetryLimit(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	user, err := store.CreateUser(ctx, "ada", "hash", nil, false)
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	conv, err := store.CreateConversation(ctx, user.ID, "Chat")
+	if err != nil {
+		t.Fatalf("CreateConversation: %v", err)
+	}
+	if _, err := store.AddMessage(ctx, conv.ID, "user", "I moved to Lisbon", nil); err != nil {
+		t.Fatalf("AddMessage: %v", err)
+	}
+
+	extractor := &failingExtractor{}
+	queue := NewGraphExtractionQueue(store, nil, extractor)
+	if err := queue.Enqueue(ctx, conv.ID, user.ID); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		job, err := store.ClaimGraphExtractionJob(ctx)
+		if err != nil || job == nil {
+			t.Fatalf("attempt %d: claimed %+v, %v; want the job", attempt, job, err)
+		}
+		queue.process(ctx, job)
+
+		jobs, err := store.ListGraphExtractionJobs(ctx, "", 1)
+		if err != nil || len(jobs) != 1 {
+			t.Fatalf("ListGraphExtractionJobs = %+v, %v", jobs, err)
+		}
+		job = &jobs[0]
+		if job.Attempts != attempt || job.LastError == "" {
+			t.Fatalf("after attempt %d the job is %+v", attempt, job)
+		}
+		if attempt == maxAttempts {
+			if job.Status != db.JobFailed {
+				t.Fatalf("after the last attempt the job is %s, want failed", job.Status)
+			}
+			break
+		}
+
+		// Backing off, so not due again straight away
+		if job.Status != db.JobRetrying {
+			t.Fatalf("after attempt %d the job is %s, want retrying", attempt, job.Status)
+		}
+		if due, err := store.ClaimGraphExtractionJob(ctx); err != nil || due != nil {
+			t.Fatalf("after attempt %d claimed %+v, %v; want nothing before the backoff ends", attempt, due, err)
+		}
+		// Cut the backoff short
+		retryAt := time.Now().Add(-time.Second)
+		if err := store.FailGraphExtractionJob(ctx, conv.ID, job.LastError, &retryAt); err != nil {
+			t.Fatalf("FailGraphExtractionJob: %v", err)
+		}
+	}
+
+	if extractor.calls != maxAttempts {
+		t.Fatalf("extractor ran %d times, want %d", extractor.calls, maxAttempts)
+	}
+	if job, err := store.ClaimGraphExtractionJob(ctx); err != nil || job != nil {
+		t.Fatalf("claimed %+v, %v after the job failed for good", job, err)
+	}
+}
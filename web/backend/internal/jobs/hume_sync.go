@@ -0,0 +1,357 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/hume-evi/web/internal/db"
+	"github.com/hume-evi/web/internal/hume"
+)
+
+// Per-voice result statuses
+const (
+	SyncStatusSynced  = "synced"
+	SyncStatusSkipped = "skipped"
+	SyncStatusError   = "error"
+	SyncStatusOK      = "ok"
+	SyncStatusDrift   = "drift"
+	SyncStatusHealed  = "healed"
+)
+
+// Kinds of drift a reconciliation reports
+const (
+	DriftNoConfig       = "no_config"
+	DriftConfigDeleted  = "config_deleted"
+	DriftConfigVersion  = "config_version_changed"
+	DriftNoPrompt       = "no_prompt"
+	DriftPromptDeleted  = "prompt_deleted"
+	DriftPromptMismatch = "prompt_mismatch"
+	DriftOrphanedConfig = "orphaned_config"
+)
+
+// configPageSize is how many Hume configs are fetched per page when looking
+// for orphans
+const configPageSize = 100
+
+// VoiceSyncFunc publishes a voice to Hume and records the change
+type VoiceSyncFunc func(ctx context.Context, voice *db.Voice, change db.VoiceChange) error
+
+// HumeSync runs bulk syncs and drift reconciliations between local voices
+// and their Hume prompts and configs. Runs are recorded in the store and
+// report progress as they go; at most one of each kind runs at a time.
+type HumeSync struct {
+	db       db.Store
+	hume     hume.API
+	sync     VoiceSyncFunc
+	interval time.Duration
+	heal     bool
+}
+
+// NewHumeSync creates a runner. Run reconciles every interval (never when
+// interval is 0), healing drift automatically when heal is set.
+func NewHumeSync(database db.Store, api hume.API, sync VoiceSyncFunc, interval time.Duration, heal bool) *HumeSync {
+	return &HumeSync{
+		db:       database,
+		hume:     api,
+		sync:     sync,
+		interval: interval,
+		heal:     heal,
+	}
+}
+
+// Run fails runs interrupted by a restart, then reconciles periodically
+// until ctx is cancelled
+func (h *HumeSync) Run(ctx context.Context) {
+	if failed, err := h.db.FailInterruptedHumeSyncRuns(ctx); err != nil {
+		log.Printf("Failed to clean up interrupted Hume sync runs: %v", err)
+	} else if failed > 0 {
+		log.Printf("Marked %d interrupted Hume sync runs as failed", failed)
+	}
+
+	if h.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if _, err := h.StartReconcile(ctx, h.heal, nil); err != nil && err != db.ErrHumeSyncRunning {
+			log.Printf("Failed to start scheduled Hume reconciliation: %v", err)
+		}
+	}
+}
+
+// StartSync syncs every voice to Hume in the background and returns the run
+// to poll. It fails with db.ErrHumeSyncRunning if a sync is in progress.
+func (h *HumeSync) StartSync(ctx context.Context, startedBy *uuid.UUID) (*db.HumeSyncRun, error) {
+	voices, err := h.db.ListVoices(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list voices: %w", err)
+	}
+
+	run, err := h.db.StartHumeSyncRun(ctx, db.HumeSyncKindSync, false, len(voices), startedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	// The run outlives the request that started it
+	go h.runSync(context.WithoutCancel(ctx), run.ID, voices, startedBy)
+	return run, nil
+}
+
+func (h *HumeSync) runSync(ctx context.Context, runID uuid.UUID, voices []db.Voice, startedBy *uuid.UUID) {
+	change := db.VoiceChange{Action: db.VoiceActionSync, Note: "bulk sync", EditedBy: startedBy}
+
+	var failed int
+	for i := range voices {
+		voice := &voices[i]
+		result := db.HumeSyncResult{VoiceID: &voice.ID, Name: voice.Name, HumeConfigID: voice.HumeConfigID}
+
+		switch {
+		case voice.Prompt == "" || voice.HumeConfigID == "":
+			result.Status = SyncStatusSkipped
+			result.Detail = "missing prompt or config ID"
+		default:
+			if err := h.sync(ctx, voice, change); err != nil {
+				failed++
+				result.Status = SyncStatusError
+				result.Detail = err.Error()
+			} else {
+				result.Status = SyncStatusSynced
+				result.HumeConfigID = voice.HumeConfigID
+			}
+		}
+
+		h.appendResult(ctx, runID, result)
+	}
+
+	log.Printf("Hume bulk sync %s finished: %d voices, %d failed", runID, len(voices), failed)
+	h.finish(ctx, runID, db.JobSucceeded, "")
+}
+
+// StartReconcile compares every voice with its Hume config and prompt in the
+// background and returns the run to poll. With heal set, drifted voices are
+// re-synced and orphaned configs are queued for cleanup.
+func (h *HumeSync) StartReconcile(ctx context.Context, heal bool, startedBy *uuid.UUID) (*db.HumeSyncRun, error) {
+	voices, err := h.db.ListVoices(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list voices: %w", err)
+	}
+
+	run, err := h.db.StartHumeSyncRun(ctx, db.HumeSyncKindReconcile, heal, len(voices), startedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	go h.runReconcile(context.WithoutCancel(ctx), run.ID, voices, heal, startedBy)
+	return run, nil
+}
+
+func (h *HumeSync) runReconcile(ctx context.Context, runID uuid.UUID, voices []db.Voice, heal bool, startedBy *uuid.UUID) {
+	var drifted int
+	for i := range voices {
+		result := h.reconcileVoice(ctx, &voices[i], heal, startedBy)
+		if result.Status != SyncStatusOK {
+			drifted++
+		}
+		h.appendResult(ctx, runID, result)
+	}
+
+	// Healing may have replaced configs, so orphans are judged against the
+	// voices as they are now
+	voices, err := h.db.ListVoices(ctx)
+	if err == nil {
+		var orphans []db.HumeSyncResult
+		orphans, err = h.findOrphanedConfigs(ctx, voices)
+		if err == nil {
+			if err := h.db.SetHumeSyncRunTotal(ctx, runID, len(voices)+len(orphans)); err != nil {
+				log.Printf("Failed to update Hume reconciliation %s total: %v", runID, err)
+			}
+			for _, orphan := range orphans {
+				if heal {
+					h.queueOrphanForCleanup(ctx, &orphan)
+				}
+				h.appendResult(ctx, runID, orphan)
+			}
+			drifted += len(orphans)
+		}
+	}
+	if err != nil {
+		log.Printf("Hume reconciliation %s failed: %v", runID, err)
+		h.finish(ctx, runID, db.JobFailed, err.Error())
+		return
+	}
+
+	log.Printf("Hume reconciliation %s finished: %d voices, %d out of step", runID, len(voices), drifted)
+	h.finish(ctx, runID, db.JobSucceeded, "")
+}
+
+// reconcileVoice reports how a voice differs from its Hume config and
+// prompt, healing it by re-syncing when asked
+func (h *HumeSync) reconcileVoice(ctx context.Context, voice *db.Voice, heal bool, startedBy *uuid.UUID) db.HumeSyncResult {
+	result := db.HumeSyncResult{VoiceID: &voice.ID, Name: voice.Name, HumeConfigID: voice.HumeConfigID}
+
+	if voice.HumeConfigID == "" {
+		result.Drift = append(result.Drift, DriftNoConfig)
+	} else {
+		config, err := h.hume.GetConfig(ctx, voice.HumeConfigID)
+		switch {
+		case hume.IsNotFound(err):
+			result.Drift = append(result.Drift, DriftConfigDeleted)
+		case err != nil:
+			result.Status = SyncStatusError
+			result.Detail = fmt.Sprintf("failed to fetch config: %v", err)
+			return result
+		case voice.HumeConfigVersion != nil && config.Version != *voice.HumeConfigVersion:
+			result.Drift = append(result.Drift, DriftConfigVersion)
+			result.Detail = fmt.Sprintf("Hume has config version %d, voice was synced at %d", config.Version, *voice.HumeConfigVersion)
+		}
+	}
+
+	if voice.HumePromptID == "" {
+		result.Drift = append(result.Drift, DriftNoPrompt)
+	} else {
+		prompt, err := h.hume.GetPrompt(ctx, voice.HumePromptID)
+		switch {
+		case hume.IsNotFound(err):
+			result.Drift = append(result.Drift, DriftPromptDeleted)
+		case err != nil:
+			result.Status = SyncStatusError
+			result.Detail = fmt.Sprintf("failed to fetch prompt: %v", err)
+			return result
+		case prompt.Text != voice.Prompt:
+			result.Drift = append(result.Drift, DriftPromptMismatch)
+		}
+	}
+
+	if len(result.Drift) == 0 {
+		result.Status = SyncStatusOK
+		return result
+	}
+	result.Status = SyncStatusDrift
+	if !heal {
+		return result
+	}
+	if voice.Prompt == "" {
+		result.Detail = "voice has no prompt text to sync"
+		return result
+	}
+
+	change := db.VoiceChange{
+		Action:   db.VoiceActionSync,
+		Note:     "auto-heal: " + strings.Join(result.Drift, ", "),
+		EditedBy: startedBy,
+	}
+	if err := h.sync(ctx, voice, change); err != nil {
+		result.Status = SyncStatusError
+		result.Detail = fmt.Sprintf("heal failed: %v", err)
+		return result
+	}
+	result.Status = SyncStatusHealed
+	result.HumeConfigID = voice.HumeConfigID
+	return result
+}
+
+// findOrphanedConfigs lists Hume configs that the app created for one of our
+// voices on a name conflict ("Name (timestamp)") that no voice uses and that
+// aren't already awaiting cleanup. Other configs, such as ones built by hand
+// in the Hume dashboard, are never reported.
+func (h *HumeSync) findOrphanedConfigs(ctx context.Context, voices []db.Voice) ([]db.HumeSyncResult, error) {
+	known := make(map[string]bool)
+	for _, voice := range voices {
+		if voice.HumeConfigID != "" {
+			known[voice.HumeConfigID] = true
+		}
+	}
+	replaced, err := h.db.ListReplacedHumeConfigs(ctx, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list replaced configs: %w", err)
+	}
+	for _, config := range replaced {
+		known[config.HumeConfigID] = true
+	}
+
+	var orphans []db.HumeSyncResult
+	for pageNumber := 0; ; pageNumber++ {
+		page, err := h.hume.ListConfigs(ctx, pageNumber, configPageSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list Hume configs: %w", err)
+		}
+
+		for _, config := range page.Configs {
+			if known[config.ID] {
+				continue
+			}
+			voice := voiceNamedLike(voices, config.Name)
+			if voice == nil {
+				continue
+			}
+			orphans = append(orphans, db.HumeSyncResult{
+				VoiceID:      &voice.ID,
+				Name:         config.Name,
+				HumeConfigID: config.ID,
+				Status:       SyncStatusDrift,
+				Drift:        []string{DriftOrphanedConfig},
+			})
+		}
+
+		if len(page.Configs) == 0 || pageNumber+1 >= page.TotalPages {
+			return orphans, nil
+		}
+	}
+}
+
+// conflictRetryName matches the "Name (unix-timestamp)" config names
+// createHumeConfig falls back to when a name is taken
+var conflictRetryName = regexp.MustCompile(`^(.+) \((\d+)\)$`)
+
+// voiceNamedLike returns the voice whose conflict-retry name configName is
+func voiceNamedLike(voices []db.Voice, configName string) *db.Voice {
+	match := conflictRetryName.FindStringSubmatch(configName)
+	if match == nil {
+		return nil
+	}
+	for i := range voices {
+		if voices[i].Name == match[1] {
+			return &voices[i]
+		}
+	}
+	return nil
+}
+
+// queueOrphanForCleanup records an orphaned config as replaced, so it shows
+// up for deletion alongside configs the app replaced itself
+func (h *HumeSync) queueOrphanForCleanup(ctx context.Context, orphan *db.HumeSyncResult) {
+	err := h.db.RecordReplacedHumeConfig(ctx, orphan.VoiceID, orphan.HumeConfigID, "", "orphaned config found by reconciliation")
+	if err != nil {
+		orphan.Detail = fmt.Sprintf("failed to queue for cleanup: %v", err)
+		return
+	}
+	orphan.Status = SyncStatusHealed
+	orphan.Detail = "queued for cleanup"
+}
+
+func (h *HumeSync) appendResult(ctx context.Context, runID uuid.UUID, result db.HumeSyncResult) {
+	if err := h.db.AppendHumeSyncResult(ctx, runID, result); err != nil {
+		log.Printf("Failed to record Hume sync result for %s: %v", result.Name, err)
+	}
+}
+
+func (h *HumeSync) finish(ctx context.Context, runID uuid.UUID, status, runError string) {
+	if err := h.db.FinishHumeSyncRun(ctx, runID, status, runError); err != nil {
+		log.Printf("Failed to finish Hume sync run %s: %v", runID, err)
+	}
+}
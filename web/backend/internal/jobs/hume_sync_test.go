@@ -0,0 +1,33 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/hume-evi/web/internal/db"
+)
+
+func TestVoiceNamedLike(t *testing.T) {
+	voices := []db.Voice{{Name: "Sam"}, {Name: "Sam (work)"}}
+
+	tests := []struct {
+		configName string
+		want       string
+	}{
+		{"Sam (1718000000)", "Sam"},
+		{"Sam (work) (1718000000)", "Sam (work)"},
+		// Hand-built configs that only share a voice's name aren't ours
+		{"Sam", ""},
+		{"Sam (work)", ""},
+		{"Sam (copy)", ""},
+		{"Samantha (1718000000)", ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if voice := voiceNamedLike(voices, tt.configName); voice != nil {
+			got = voice.Name
+		}
+		if got != tt.want {
+			t.Errorf("voiceNamedLike(%q) = %q, want %q", tt.configName, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+// Package textdiff computes line-based diffs of short texts such as prompts
+package textdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Op says whether a line is kept, added or removed
+type Op byte
+
+const (
+	Equal  Op = ' '
+	Insert Op = '+'
+	Delete Op = '-'
+)
+
+// Line is one line of a diff
+type Line struct {
+	Op   Op
+	Text string
+	// NoNewline marks the last line of a text that doesn't end in a newline
+	NoNewline bool
+}
+
+// maxLCSCells bounds the table Lines builds after trimming the common prefix
+// and suffix. Larger changes are shown as replacing every line between them.
+const maxLCSCells = 1 << 20
+
+// line is a line of input; the last one may lack its newline, which makes it
+// differ from the same text with one
+type line struct {
+	text string
+	eol  bool
+}
+
+// Lines diffs a against b line by line using a longest common subsequence.
+// It is quadratic in the number of changed lines, which is fine for prompts;
+// past maxLCSCells the changed region is replaced wholesale.
+func Lines(a, b string) []Line {
+	x := splitLines(a)
+	y := splitLines(b)
+
+	var lines []Line
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		lines = append(lines, diffLine(Equal, x[prefix]))
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(x)-prefix && suffix < len(y)-prefix && x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+
+	lines = append(lines, lcsLines(x[prefix:len(x)-suffix], y[prefix:len(y)-suffix])...)
+	for _, l := range x[len(x)-suffix:] {
+		lines = append(lines, diffLine(Equal, l))
+	}
+	return lines
+}
+
+// lcsLines diffs texts with no common first or last line
+func lcsLines(x, y []line) []Line {
+	var lines []Line
+	if (len(x)+1)*(len(y)+1) > maxLCSCells {
+		for _, l := range x {
+			lines = append(lines, diffLine(Delete, l))
+		}
+		for _, l := range y {
+			lines = append(lines, diffLine(Insert, l))
+		}
+		return lines
+	}
+
+	// lcs[i][j] is the LCS length of x[i:] and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			lines = append(lines, diffLine(Equal, x[i]))
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, diffLine(Delete, x[i]))
+			i++
+		default:
+			lines = append(lines, diffLine(Insert, y[j]))
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		lines = append(lines, diffLine(Delete, x[i]))
+	}
+	for ; j < len(y); j++ {
+		lines = append(lines, diffLine(Insert, y[j]))
+	}
+	return lines
+}
+
+func diffLine(op Op, l line) Line {
+	return Line{Op: op, Text: l.text, NoNewline: !l.eol}
+}
+
+// Unified renders a diff in unified format with the given lines of context
+// around each change. It returns "" when the texts are equal.
+func Unified(fromName, toName string, lines []Line, context int) string {
+	var hunks []string
+	for start := 0; start < len(lines); {
+		// Find the next change
+		first := start
+		for first < len(lines) && lines[first].Op == Equal {
+			first++
+		}
+		if first == len(lines) {
+			break
+		}
+
+		// Extend the hunk while changes are within 2*context lines of each other
+		last := first
+		for k := first; k < len(lines); k++ {
+			if lines[k].Op != Equal {
+				last = k
+			} else if k-last > 2*context {
+				break
+			}
+		}
+
+		from := max(first-context, start)
+		to := min(last+context+1, len(lines))
+		hunks = append(hunks, hunk(lines, from, to))
+		start = to
+	}
+
+	if len(hunks) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("--- %s\n+++ %s\n%s", fromName, toName, strings.Join(hunks, ""))
+}
+
+func hunk(lines []Line, from, to int) string {
+	// Line numbers (1-based) in the old and new text where the hunk starts
+	oldStart, newStart := 1, 1
+	for _, l := range lines[:from] {
+		if l.Op != Insert {
+			oldStart++
+		}
+		if l.Op != Delete {
+			newStart++
+		}
+	}
+
+	var b strings.Builder
+	oldCount, newCount := 0, 0
+	for _, l := range lines[from:to] {
+		if l.Op != Insert {
+			oldCount++
+		}
+		if l.Op != Delete {
+			newCount++
+		}
+		b.WriteByte(byte(l.Op))
+		b.WriteString(l.Text)
+		b.WriteByte('\n')
+		if l.NoNewline {
+			b.WriteString("\\ No newline at end of file\n")
+		}
+	}
+
+	// An empty side is numbered from the line before it, as diff(1) does
+	if oldCount == 0 {
+		oldStart--
+	}
+	if newCount == 0 {
+		newStart--
+	}
+
+	return fmt.Sprintf("@@ -%s +%s @@\n%s", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount), b.String())
+}
+
+// hunkRange formats one side of a hunk header, leaving out a count of 1
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+func splitLines(s string) []line {
+	if s == "" {
+		return nil
+	}
+	eol := strings.HasSuffix(s, "\n")
+	texts := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	lines := make([]line, len(texts))
+	for i, text := range texts {
+		lines[i] = line{text: text, eol: true}
+	}
+	lines[len(lines)-1].eol = eol
+	return lines
+}
@@ -0,0 +1,243 @@
+package textdiff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// The expected output of each case is what GNU diff -U<context> --label old
+// --label new prints for the same two files
+func TestUnifiedMatchesDiff(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		context int
+		want    string
+	}{
+		{
+			name: "equal",
+			a:    "one\ntwo\n",
+			b:    "one\ntwo\n",
+			want: "",
+		},
+		{
+			name:    "insert only",
+			a:       "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\n",
+			b:       "one\ntwo\nthree\nfour\nnew\nfive\nsix\nseven\neight\n",
+			context: 3,
+			want: `--- old
++++ new
+@@ -2,6 +2,7 @@
+ two
+ three
+ four
++new
+ five
+ six
+ seven
+`,
+		},
+		{
+			name:    "delete only",
+			a:       "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\n",
+			b:       "one\ntwo\nthree\nfive\nsix\nseven\neight\n",
+			context: 3,
+			want: `--- old
++++ new
+@@ -1,7 +1,6 @@
+ one
+ two
+ three
+-four
+ five
+ six
+ seven
+`,
+		},
+		{
+			name:    "changes 2*context apart share a hunk",
+			a:       "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			b:       "1\nX\n3\n4\n5\n6\n7\n8\nY\n10\n",
+			context: 3,
+			want: `--- old
++++ new
+@@ -1,10 +1,10 @@
+ 1
+-2
++X
+ 3
+ 4
+ 5
+ 6
+ 7
+ 8
+-9
++Y
+ 10
+`,
+		},
+		{
+			name:    "changes further apart get separate hunks",
+			a:       "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n",
+			b:       "1\nX\n3\n4\n5\n6\n7\n8\n9\nY\n11\n",
+			context: 3,
+			want: `--- old
++++ new
+@@ -1,5 +1,5 @@
+ 1
+-2
++X
+ 3
+ 4
+ 5
+@@ -7,5 +7,5 @@
+ 7
+ 8
+ 9
+-10
++Y
+ 11
+`,
+		},
+		{
+			name:    "no context",
+			a:       "a\nb\nc\nd\n",
+			b:       "a\nc\nd\ne\n",
+			context: 0,
+			want: `--- old
++++ new
+@@ -2 +1,0 @@
+-b
+@@ -4,0 +4 @@
++e
+`,
+		},
+		{
+			name:    "from empty",
+			a:       "",
+			b:       "a\nb\n",
+			context: 3,
+			want: `--- old
++++ new
+@@ -0,0 +1,2 @@
++a
++b
+`,
+		},
+		{
+			name:    "to empty",
+			a:       "a\nb\n",
+			b:       "",
+			context: 3,
+			want: `--- old
++++ new
+@@ -1,2 +0,0 @@
+-a
+-b
+`,
+		},
+		{
+			name:    "trailing newline added",
+			a:       "a\nb",
+			b:       "a\nb\n",
+			context: 3,
+			want: `--- old
++++ new
+@@ -1,2 +1,2 @@
+ a
+-b
+\ No newline at end of file
++b
+`,
+		},
+		{
+			name:    "trailing newline removed",
+			a:       "a\nb\n",
+			b:       "a\nb",
+			context: 3,
+			want: `--- old
++++ new
+@@ -1,2 +1,2 @@
+ a
+-b
++b
+\ No newline at end of file
+`,
+		},
+		{
+			name:    "last line changed without newlines",
+			a:       "a\nb",
+			b:       "a\nc",
+			context: 3,
+			want: `--- old
++++ new
+@@ -1,2 +1,2 @@
+ a
+-b
+\ No newline at end of file
++c
+\ No newline at end of file
+`,
+		},
+		{
+			name:    "line appended after one without a newline",
+			a:       "a",
+			b:       "a\nb",
+			context: 3,
+			want: `--- old
++++ new
+@@ -1 +1,2 @@
+-a
+\ No newline at end of file
++a
++b
+\ No newline at end of file
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Unified("old", "new", Lines(tt.a, tt.b), tt.context)
+			if got != tt.want {
+				t.Fatalf("Unified =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinesReplacesLargeChangesWholesale(t *testing.T) {
+	var a, b strings.Builder
+	a.WriteString("same\n")
+	b.WriteString("same\n")
+	for i := 0; i < 1100; i++ {
+		fmt.Fprintf(&a, "old %d\n", i)
+		fmt.Fprintf(&b, "new %d\n", i)
+	}
+	// One common line in the middle that a full LCS would keep
+	a.WriteString("middle\n")
+	b.WriteString("middle\n")
+	for i := 0; i < 1100; i++ {
+		fmt.Fprintf(&a, "old tail %d\n", i)
+		fmt.Fprintf(&b, "new tail %d\n", i)
+	}
+	a.WriteString("end\n")
+	b.WriteString("end\n")
+
+	lines := Lines(a.String(), b.String())
+	if len(lines) != 2+2*2201 {
+		t.Fatalf("got %d lines, want the common first and last lines and every other line replaced", len(lines))
+	}
+	if lines[0] != (Line{Op: Equal, Text: "same"}) || lines[len(lines)-1] != (Line{Op: Equal, Text: "end"}) {
+		t.Fatalf("first and last lines are %+v and %+v, want them kept", lines[0], lines[len(lines)-1])
+	}
+	for i, l := range lines[1 : len(lines)-1] {
+		want := Delete
+		if i >= 2201 {
+			want = Insert
+		}
+		if l.Op != want {
+			t.Fatalf("line %d is %c %q, want all deletions then all insertions", i+1, l.Op, l.Text)
+		}
+	}
+}
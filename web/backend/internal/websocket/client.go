@@ -12,7 +12,9 @@ import (
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 
+	"github.com/hume-evi/web/internal/analysis"
 	"github.com/hume-evi/web/internal/db"
+	"github.com/hume-evi/web/internal/hume"
 )
 
 const (
@@ -22,35 +24,54 @@ const (
 	maxMessageSize = 512 * 1024 // 512KB
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		return true // Allow all origins in development
-	},
-}
-
 type Client struct {
 	hub              *Hub
 	conn             *websocket.Conn
 	send             chan []byte
+	sessionID        string
 	userID           string
+	connectedAt      time.Time
 	conversationID   *uuid.UUID
 	humeConn         *websocket.Conn
 	humeMutex        sync.Mutex
-	db               *db.DB
+	db               db.Store
 	humeAPIKey       string
 	humeConfigID     string
 	ctx              context.Context
 	cancel           context.CancelFunc
+
+	// sendMu guards sends on send against the hub closing it, since the Hume
+	// read loop and analysis goroutines can outlive the hub's registration
+	sendMu     sync.Mutex
+	sendClosed bool
+
+	// Rolling transcript and injected context state, guarded by historyMu
+	historyMu         sync.Mutex
+	history           []analysis.ConversationMessage
+	messageCount      int
+	persistentContext string
+	restorePersistent bool
 }
 
 type HumeMessage struct {
 	Type    string          `json:"type"`
 	Data    json.RawMessage `json:"data,omitempty"`
 	Message *MessageContent `json:"message,omitempty"`
+	Models  *MessageModels  `json:"models,omitempty"`
 	Code    string          `json:"code,omitempty"`
 	Slug    string          `json:"slug,omitempty"`
+	Interim bool            `json:"interim,omitempty"`
+}
+
+// MessageModels is the expression measurement EVI attaches to user and
+// assistant messages
+type MessageModels struct {
+	Prosody *ProsodyInference `json:"prosody,omitempty"`
+}
+
+// ProsodyInference scores each of Hume's expressions (e.g. "Anxiety") from 0 to 1
+type ProsodyInference struct {
+	Scores map[string]float64 `json:"scores"`
 }
 
 type MessageContent struct {
@@ -76,7 +97,7 @@ type AudioConfig struct {
 }
 
 func ServeWS(hub *Hub, w http.ResponseWriter, r *http.Request, userID string) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+	conn, err := hub.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("WebSocket upgrade error: %v", err)
 		return
@@ -88,7 +109,9 @@ func ServeWS(hub *Hub, w http.ResponseWriter, r *http.Request, userID string) {
 		hub:          hub,
 		conn:         conn,
 		send:         make(chan []byte, 256),
+		sessionID:    uuid.New().String(),
 		userID:       userID,
+		connectedAt:  time.Now(),
 		db:           hub.db,
 		humeAPIKey:   hub.config.HumeAPIKey,
 		humeConfigID: hub.config.HumeConfigID,
@@ -102,14 +125,46 @@ func ServeWS(hub *Hub, w http.ResponseWriter, r *http.Request, userID string) {
 	go client.readPump()
 }
 
+// queue hands a message to the write pump without blocking. It reports false
+// if the send buffer is full or the client has been unregistered.
+func (c *Client) queue(message []byte) bool {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	if c.sendClosed {
+		return false
+	}
+	select {
+	case c.send <- message:
+		return true
+	default:
+		return false
+	}
+}
+
+// closeSend closes the send channel, which stops the write pump. Later calls
+// to queue are dropped instead of panicking on the closed channel.
+func (c *Client) closeSend() {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	if !c.sendClosed {
+		c.sendClosed = true
+		close(c.send)
+	}
+}
+
 func (c *Client) readPump() {
 	defer func() {
+		// Stop in-flight analysis before the hub closes the send channel
+		c.cancel()
 		c.hub.unregister <- c
 		c.conn.Close()
+		c.humeMutex.Lock()
 		if c.humeConn != nil {
 			c.humeConn.Close()
 		}
-		c.cancel()
+		c.humeMutex.Unlock()
 	}()
 
 	c.conn.SetReadDeadline(time.Now().Add(pongWait))
@@ -207,9 +262,59 @@ func (c *Client) handleStartConversation(msg map[string]interface{}) {
 		}
 	}
 
+	userUUID, _ := uuid.Parse(c.userID)
+
+	// Verify conversation belongs to user
+	var resumed *db.Conversation
+	if convID != nil {
+		conv, err := c.db.GetConversation(c.ctx, *convID, userUUID)
+		if err != nil {
+			log.Printf("Conversation not found: %v", err)
+			c.sendError("Conversation not found")
+			return
+		}
+		resumed = conv
+	}
+
+	// Use the selected voice's EVI config, falling back to the voice the
+	// conversation was started with and then to the global default. The
+	// voice is checked before anything is created or attached to the client.
+	configID := c.humeConfigID
+	var voice *db.Voice
+	if voiceIDStr, ok := msg["voice_id"].(string); ok && voiceIDStr != "" {
+		voiceID, err := uuid.Parse(voiceIDStr)
+		if err != nil {
+			c.sendError("Invalid voice ID")
+			return
+		}
+		voice, err = c.db.GetVoice(c.ctx, voiceID)
+		if err != nil {
+			log.Printf("Voice not found: %v", err)
+			c.sendError("Voice not found")
+			return
+		}
+		if voice.HumeConfigID == "" {
+			c.sendError("Voice has no Hume config")
+			return
+		}
+	} else if resumed != nil && resumed.VoiceID != nil {
+		stored, err := c.db.GetVoice(c.ctx, *resumed.VoiceID)
+		switch {
+		case err != nil:
+			log.Printf("Voice %s for conversation %s unavailable, using default config: %v", *resumed.VoiceID, resumed.ID, err)
+		case stored.HumeConfigID == "":
+			log.Printf("Voice %s has no Hume config, using default config", stored.Name)
+		default:
+			voice = stored
+		}
+	}
+	if voice != nil {
+		configID = voice.HumeConfigID
+		log.Printf("Using voice %s (config %s)", voice.Name, configID)
+	}
+
 	// Create new conversation if needed
 	if convID == nil {
-		userUUID, _ := uuid.Parse(c.userID)
 		conv, err := c.db.CreateConversation(c.ctx, userUUID, "")
 		if err != nil {
 			log.Printf("Failed to create conversation: %v", err)
@@ -219,21 +324,20 @@ func (c *Client) handleStartConversation(msg map[string]interface{}) {
 		convID = &conv.ID
 		log.Printf("Created new conversation: %s", conv.ID)
 	} else {
-		// Verify conversation belongs to user
-		userUUID, _ := uuid.Parse(c.userID)
-		_, err := c.db.GetConversation(c.ctx, *convID, userUUID)
-		if err != nil {
-			log.Printf("Conversation not found: %v", err)
-			c.sendError("Conversation not found")
-			return
-		}
+		c.seedHistory(*convID)
 	}
 
 	c.conversationID = convID
 
+	if voice != nil && (resumed == nil || resumed.VoiceID == nil || *resumed.VoiceID != voice.ID) {
+		if err := c.db.SetConversationVoice(c.ctx, *convID, userUUID, voice.ID); err != nil {
+			log.Printf("Failed to record voice for conversation: %v", err)
+		}
+	}
+
 	// Connect to Hume EVI
 	log.Printf("Attempting to connect to Hume EVI...")
-	if err := c.connectToHume(); err != nil {
+	if err := c.connectToHume(configID); err != nil {
 		log.Printf("Failed to connect to Hume EVI: %v", err)
 		c.sendError("Failed to connect to Hume EVI: " + err.Error())
 		return
@@ -242,11 +346,21 @@ func (c *Client) handleStartConversation(msg map[string]interface{}) {
 	log.Printf("Hume EVI connected, starting read loop")
 	// Start reading from Hume
 	go c.readFromHume()
+
+	// Tell the frontend which conversation the transcript is being saved to
+	response := map[string]interface{}{
+		"type":            "conversation_started",
+		"conversation_id": convID.String(),
+		"session_id":      c.sessionID,
+	}
+
+	responseJSON, _ := json.Marshal(response)
+	c.queue(responseJSON)
 }
 
-func (c *Client) connectToHume() error {
+func (c *Client) connectToHume(configID string) error {
 	// Hume WebSocket URL with config_id as query parameter
-	url := fmt.Sprintf("wss://api.hume.ai/v0/evi/chat?config_id=%s", c.humeConfigID)
+	url := hume.ChatURL(c.hub.config.HumeAPIURL, configID)
 	
 	// Create WebSocket connection with auth header
 	dialer := websocket.Dialer{
@@ -296,10 +410,6 @@ func (c *Client) connectToHume() error {
 }
 
 func (c *Client) handleAudioInput(msg map[string]interface{}) {
-	if c.humeConn == nil {
-		return
-	}
-
 	data, ok := msg["data"].(string)
 	if !ok {
 		return
@@ -317,6 +427,10 @@ func (c *Client) handleAudioInput(msg map[string]interface{}) {
 	}
 
 	c.humeMutex.Lock()
+	if c.humeConn == nil {
+		c.humeMutex.Unlock()
+		return
+	}
 	err = c.humeConn.WriteMessage(websocket.TextMessage, audioJSON)
 	c.humeMutex.Unlock()
 
@@ -326,19 +440,22 @@ func (c *Client) handleAudioInput(msg map[string]interface{}) {
 }
 
 func (c *Client) readFromHume() {
+	// Read from the connection this loop was started for; closing it (on
+	// end_conversation or disconnect) ends the loop
+	c.humeMutex.Lock()
+	conn := c.humeConn
+	c.humeMutex.Unlock()
+	if conn == nil {
+		return
+	}
+
 	defer func() {
-		if c.humeConn != nil {
-			c.humeConn.Close()
-		}
+		conn.Close()
 		log.Printf("Hume read loop ended")
 	}()
 
 	for {
-		if c.humeConn == nil {
-			return
-		}
-
-		_, message, err := c.humeConn.ReadMessage()
+		_, message, err := conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("Hume connection error: %v", err)
@@ -360,6 +477,9 @@ func (c *Client) readFromHume() {
 			c.handleTextMessage(humeMsg)
 		case "audio_output":
 			c.handleAudioOutput(humeMsg)
+		case "assistant_end":
+			// A temporary context only lasts one assistant turn
+			c.restorePersistentContext()
 		case "user_interruption":
 			c.handleInterruption()
 		case "error":
@@ -382,9 +502,27 @@ func (c *Client) handleTextMessage(msg HumeMessage) {
 		role = "user"
 	}
 
-	_, err := c.db.AddMessage(c.ctx, *c.conversationID, role, msg.Message.Content)
-	if err != nil {
-		log.Printf("Failed to save message: %v", err)
+	var emotions map[string]float64
+	if msg.Models != nil && msg.Models.Prosody != nil && len(msg.Models.Prosody.Scores) > 0 {
+		emotions = msg.Models.Prosody.Scores
+	}
+
+	// Interim transcripts are superseded by a final user_message, so only
+	// completed messages are persisted and analyzed
+	if !msg.Interim {
+		saved, err := c.db.AddMessage(c.ctx, *c.conversationID, role, msg.Message.Content, emotions)
+		if err != nil {
+			log.Printf("Failed to save message: %v", err)
+		}
+
+		history, messageCount := c.recordHistory(role, msg.Message.Content, emotions)
+		if role == "user" {
+			var messageID *uuid.UUID
+			if err == nil {
+				messageID = &saved.ID
+			}
+			go c.analyzeAndInject(history, messageCount, messageID)
+		}
 	}
 
 	// Forward to frontend
@@ -392,13 +530,11 @@ func (c *Client) handleTextMessage(msg HumeMessage) {
 		"type":    msg.Type,
 		"role":    msg.Message.Role,
 		"content": msg.Message.Content,
+		"interim": msg.Interim,
 	}
 
 	responseJSON, _ := json.Marshal(response)
-	select {
-	case c.send <- responseJSON:
-	default:
-	}
+	c.queue(responseJSON)
 }
 
 func (c *Client) handleAudioOutput(msg HumeMessage) {
@@ -409,10 +545,7 @@ func (c *Client) handleAudioOutput(msg HumeMessage) {
 	}
 
 	responseJSON, _ := json.Marshal(response)
-	select {
-	case c.send <- responseJSON:
-	default:
-	}
+	c.queue(responseJSON)
 }
 
 func (c *Client) handleInterruption() {
@@ -422,22 +555,23 @@ func (c *Client) handleInterruption() {
 	}
 
 	responseJSON, _ := json.Marshal(response)
-	select {
-	case c.send <- responseJSON:
-	default:
-	}
+	c.queue(responseJSON)
 }
 
 func (c *Client) handleEndConversation() {
 	if c.conversationID != nil {
 		userUUID, _ := uuid.Parse(c.userID)
-		_ = c.db.UpdateConversationStatus(c.ctx, *c.conversationID, userUUID, "paused")
+		if err := c.db.UpdateConversationStatus(c.ctx, *c.conversationID, userUUID, "paused"); err == nil {
+			c.hub.conversationEnded(c.ctx, *c.conversationID, userUUID)
+		}
 	}
 
+	c.humeMutex.Lock()
 	if c.humeConn != nil {
 		c.humeConn.Close()
 		c.humeConn = nil
 	}
+	c.humeMutex.Unlock()
 }
 
 func (c *Client) sendError(message string) {
@@ -447,9 +581,6 @@ func (c *Client) sendError(message string) {
 	}
 
 	responseJSON, _ := json.Marshal(response)
-	select {
-	case c.send <- responseJSON:
-	default:
-	}
+	c.queue(responseJSON)
 }
 
@@ -1,31 +1,111 @@
 package websocket
 
 import (
+	"context"
 	"log"
+	"net/http"
+	"net/url"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+
+	"github.com/hume-evi/web/internal/analysis"
 	"github.com/hume-evi/web/internal/config"
 	"github.com/hume-evi/web/internal/db"
 )
 
 type Hub struct {
-	clients    map[string]*Client
-	register   chan *Client
-	unregister chan *Client
-	broadcast  chan []byte
-	db         *db.DB
-	config     *config.Config
-	mu         sync.RWMutex
+	// clients is keyed by session ID; a user may hold several sessions at once
+	// (e.g. a phone and a laptop), so userSessions indexes them by user ID.
+	clients      map[string]*Client
+	userSessions map[string]map[string]*Client
+	register     chan *Client
+	unregister   chan *Client
+	broadcast    chan []byte
+	db           db.Store
+	config       *config.Config
+	analyzer     analysis.Analyzer
+	upgrader     websocket.Upgrader
+	// onConversationEnded runs after a client ends (pauses) its conversation
+	onConversationEnded func(ctx context.Context, conversationID, userID uuid.UUID)
+	mu                  sync.RWMutex
+}
+
+// SessionInfo describes one live proxy connection
+type SessionInfo struct {
+	SessionID   string    `json:"session_id"`
+	UserID      string    `json:"user_id"`
+	ConnectedAt time.Time `json:"connected_at"`
 }
 
-func NewHub(database *db.DB, cfg *config.Config) *Hub {
-	return &Hub{
-		clients:    make(map[string]*Client),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
-		broadcast:  make(chan []byte),
-		db:         database,
-		config:     cfg,
+func NewHub(database db.Store, cfg *config.Config, analyzer analysis.Analyzer) *Hub {
+	h := &Hub{
+		clients:      make(map[string]*Client),
+		userSessions: make(map[string]map[string]*Client),
+		register:     make(chan *Client),
+		unregister:   make(chan *Client),
+		broadcast:    make(chan []byte),
+		db:           database,
+		config:       cfg,
+		analyzer:     analyzer,
+	}
+	h.upgrader = websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     h.checkOrigin,
+	}
+	return h
+}
+
+// checkOrigin lets browsers open the proxy only from CORS_ORIGIN or, when
+// that is "*", from the proxy's own host. The proxy authenticates with the
+// auth_token cookie, which the browser attaches for any site, so accepting
+// other origins would let them hijack the user's session. Requests without
+// an Origin header don't come from a browser page and are allowed.
+func (h *Hub) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	allowed := "*"
+	if h.config != nil && h.config.CORSOrigin != "" {
+		allowed = h.config.CORSOrigin
+	}
+	if allowed != "*" {
+		if strings.EqualFold(origin, strings.TrimSuffix(allowed, "/")) {
+			return true
+		}
+		log.Printf("Rejected WebSocket from origin %s, want %s", origin, allowed)
+		return false
+	}
+
+	u, err := url.Parse(origin)
+	if err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	log.Printf("Rejected WebSocket from origin %s for host %s", origin, r.Host)
+	return false
+}
+
+// OnConversationEnded registers a callback for conversations that a client
+// ends over the proxy, e.g. to schedule post-conversation processing
+func (h *Hub) OnConversationEnded(fn func(ctx context.Context, conversationID, userID uuid.UUID)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.onConversationEnded = fn
+}
+
+func (h *Hub) conversationEnded(ctx context.Context, conversationID, userID uuid.UUID) {
+	h.mu.RLock()
+	fn := h.onConversationEnded
+	h.mu.RUnlock()
+
+	if fn != nil {
+		fn(ctx, conversationID, userID)
 	}
 }
 
@@ -34,38 +114,99 @@ func (h *Hub) Run() {
 		select {
 		case client := <-h.register:
 			h.mu.Lock()
-			h.clients[client.userID] = client
+			h.clients[client.sessionID] = client
+			sessions, ok := h.userSessions[client.userID]
+			if !ok {
+				sessions = make(map[string]*Client)
+				h.userSessions[client.userID] = sessions
+			}
+			sessions[client.sessionID] = client
+			count := len(sessions)
 			h.mu.Unlock()
-			log.Printf("Client registered: %s", client.userID)
+			log.Printf("Client registered: user=%s session=%s (%d active)", client.userID, client.sessionID, count)
 
 		case client := <-h.unregister:
 			h.mu.Lock()
-			if _, ok := h.clients[client.userID]; ok {
-				delete(h.clients, client.userID)
-				close(client.send)
-			}
+			h.removeLocked(client)
 			h.mu.Unlock()
-			log.Printf("Client unregistered: %s", client.userID)
+			log.Printf("Client unregistered: user=%s session=%s", client.userID, client.sessionID)
 
 		case message := <-h.broadcast:
-			h.mu.RLock()
+			h.mu.Lock()
 			for _, client := range h.clients {
-				select {
-				case client.send <- message:
-				default:
-					close(client.send)
-					delete(h.clients, client.userID)
+				if !client.queue(message) {
+					h.removeLocked(client)
 				}
 			}
-			h.mu.RUnlock()
+			h.mu.Unlock()
 		}
 	}
 }
 
-func (h *Hub) GetClient(userID string) (*Client, bool) {
+// removeLocked drops a client from both indexes and closes its send channel.
+// The client's own goroutines may still be running; queue drops their sends.
+// It is a no-op if the session was already removed. Callers must hold h.mu.
+func (h *Hub) removeLocked(client *Client) {
+	if current, ok := h.clients[client.sessionID]; !ok || current != client {
+		return
+	}
+	delete(h.clients, client.sessionID)
+	if sessions, ok := h.userSessions[client.userID]; ok {
+		delete(sessions, client.sessionID)
+		if len(sessions) == 0 {
+			delete(h.userSessions, client.userID)
+		}
+	}
+	client.closeSend()
+}
+
+// UserSessions lists the live sessions for a user
+func (h *Hub) UserSessions(userID string) []SessionInfo {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
-	client, ok := h.clients[userID]
-	return client, ok
+
+	sessions := make([]SessionInfo, 0, len(h.userSessions[userID]))
+	for _, client := range h.userSessions[userID] {
+		sessions = append(sessions, SessionInfo{
+			SessionID:   client.sessionID,
+			UserID:      client.userID,
+			ConnectedAt: client.connectedAt,
+		})
+	}
+	return sessions
 }
 
+// SendToUser queues a message on every session the user has open and
+// returns how many sessions accepted it. Sessions with a full send buffer
+// are skipped rather than blocking the caller.
+func (h *Hub) SendToUser(userID string, message []byte) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	delivered := 0
+	for _, client := range h.userSessions[userID] {
+		if client.queue(message) {
+			delivered++
+		} else {
+			log.Printf("Send buffer full for session %s, dropping message", client.sessionID)
+		}
+	}
+	return delivered
+}
+
+// KickSession disconnects a single session. The client's read loop notices
+// the closed connection and unregisters itself. Returns false if the session
+// doesn't exist.
+func (h *Hub) KickSession(sessionID string) bool {
+	h.mu.RLock()
+	client, ok := h.clients[sessionID]
+	h.mu.RUnlock()
+	if !ok {
+		return false
+	}
+
+	log.Printf("Kicking session %s for user %s", sessionID, client.userID)
+	client.cancel()
+	client.conn.Close()
+	return true
+}
@@ -0,0 +1,74 @@
+package websocket
+
+import (
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/hume-evi/web/internal/config"
+)
+
+func TestQueueAfterUnregister(t *testing.T) {
+	hub := NewHub(nil, nil, nil)
+	client := &Client{hub: hub, send: make(chan []byte, 1), sessionID: "s1", userID: "u1"}
+	hub.clients[client.sessionID] = client
+	hub.userSessions[client.userID] = map[string]*Client{client.sessionID: client}
+
+	// Senders racing the hub's unregister must not panic on the closed channel
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				client.queue([]byte("x"))
+			}
+		}()
+	}
+
+	hub.mu.Lock()
+	hub.removeLocked(client)
+	hub.mu.Unlock()
+	wg.Wait()
+
+	if client.queue([]byte("late")) {
+		t.Fatal("queue accepted a message after unregister")
+	}
+	if n := hub.SendToUser("u1", []byte("x")); n != 0 {
+		t.Fatalf("SendToUser delivered to %d sessions after unregister, want 0", n)
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	tests := []struct {
+		name       string
+		corsOrigin string
+		host       string
+		origin     string
+		want       bool
+	}{
+		{"no origin header", "https://app.example.com", "api.example.com", "", true},
+		{"configured origin", "https://app.example.com", "api.example.com", "https://app.example.com", true},
+		{"configured origin with trailing slash", "https://app.example.com/", "api.example.com", "https://APP.example.com", true},
+		{"other origin", "https://app.example.com", "api.example.com", "https://evil.example", false},
+		{"configured origin over http", "https://app.example.com", "api.example.com", "http://app.example.com", false},
+		{"wildcard same host", "*", "localhost:3000", "http://localhost:3000", true},
+		{"wildcard other host", "*", "localhost:3000", "https://evil.example", false},
+		{"wildcard other port", "*", "localhost:3000", "http://localhost:4000", false},
+		{"unset same host", "", "chat.example.com", "https://chat.example.com", true},
+		{"unparseable origin", "*", "localhost:3000", "://localhost:3000", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := NewHub(nil, &config.Config{CORSOrigin: tt.corsOrigin}, nil)
+			r := httptest.NewRequest("GET", "/ws", nil)
+			r.Host = tt.host
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if got := hub.checkOrigin(r); got != tt.want {
+				t.Fatalf("checkOrigin(Origin %q, Host %q, CORS_ORIGIN %q) = %v, want %v", tt.origin, tt.host, tt.corsOrigin, got, tt.want)
+			}
+		})
+	}
+}
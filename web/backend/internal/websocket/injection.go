@@ -0,0 +1,186 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+
+	"github.com/hume-evi/web/internal/analysis"
+	"github.com/hume-evi/web/internal/db"
+)
+
+const (
+	// maxHistory bounds the rolling transcript kept in memory for analysis
+	maxHistory = 20
+	// analysisTimeout bounds a single analyzer run (LLM analyzers can be slow)
+	analysisTimeout = 20 * time.Second
+)
+
+// SessionContextSettings is a session_settings frame that only touches context.
+// A nil Context clears any context previously injected.
+type SessionContextSettings struct {
+	Type    string          `json:"type"`
+	Context *SessionContext `json:"context"`
+}
+
+type SessionContext struct {
+	Text string `json:"text"`
+	Type string `json:"type"`
+}
+
+// seedHistory loads the tail of a resumed conversation so analysis has context
+func (c *Client) seedHistory(conversationID uuid.UUID) {
+	userUUID, _ := uuid.Parse(c.userID)
+	messages, err := c.db.ListMessages(c.ctx, conversationID, userUUID, db.MessagePage{Limit: maxHistory})
+	if err != nil {
+		log.Printf("Failed to load history for analysis: %v", err)
+		return
+	}
+
+	// Rules can depend on the length of the whole conversation
+	messageCount := len(messages)
+	if messageCount == maxHistory {
+		if messageCount, err = c.db.CountMessages(c.ctx, conversationID); err != nil {
+			log.Printf("Failed to count messages for analysis: %v", err)
+			messageCount = len(messages)
+		}
+	}
+
+	c.historyMu.Lock()
+	defer c.historyMu.Unlock()
+
+	c.messageCount = messageCount
+	c.history = c.history[:0]
+	for _, msg := range messages {
+		c.history = append(c.history, analysis.ConversationMessage{Role: msg.Role, Content: msg.Content, Emotions: msg.Emotions})
+	}
+}
+
+// recordHistory appends a message and its prosody scores to the rolling
+// history and returns a snapshot along with the total message count for the
+// conversation
+func (c *Client) recordHistory(role, content string, emotions map[string]float64) ([]analysis.ConversationMessage, int) {
+	c.historyMu.Lock()
+	defer c.historyMu.Unlock()
+
+	c.messageCount++
+	c.history = append(c.history, analysis.ConversationMessage{Role: role, Content: content, Emotions: emotions})
+	if len(c.history) > maxHistory {
+		c.history = c.history[len(c.history)-maxHistory:]
+	}
+
+	snapshot := make([]analysis.ConversationMessage, len(c.history))
+	copy(snapshot, c.history)
+	return snapshot, c.messageCount
+}
+
+// analyzeAndInject runs the analyzer over the history and injects context into
+// the EVI session when it asks for an intervention
+func (c *Client) analyzeAndInject(history []analysis.ConversationMessage, messageCount int, messageID *uuid.UUID) {
+	ctx, cancel := context.WithTimeout(c.ctx, analysisTimeout)
+	defer cancel()
+
+	if c.conversationID != nil {
+		ctx = analysis.WithSession(ctx, analysis.Session{
+			ConversationID: c.conversationID.String(),
+			MessageCount:   messageCount,
+		})
+	}
+
+	result, err := c.hub.analyzer.Analyze(ctx, history)
+	if err != nil {
+		log.Printf("Context analysis failed: %v", err)
+		return
+	}
+	if !result.ShouldIntervene || result.ContextText == "" {
+		return
+	}
+
+	contextType := result.ContextType
+	if contextType != analysis.ContextPersistent {
+		contextType = analysis.ContextTemporary
+	}
+
+	c.historyMu.Lock()
+	if contextType == analysis.ContextPersistent {
+		if c.persistentContext == result.ContextText {
+			// Already in effect, nothing to do
+			c.historyMu.Unlock()
+			return
+		}
+		c.persistentContext = result.ContextText
+		c.restorePersistent = false
+	} else {
+		// A temporary context replaces the persistent one for a single turn,
+		// so put the persistent context back once the assistant has replied
+		c.restorePersistent = c.persistentContext != ""
+	}
+	c.historyMu.Unlock()
+
+	if err := c.sendContext(&SessionContext{Text: result.ContextText, Type: contextType}); err != nil {
+		log.Printf("Failed to inject context: %v", err)
+		return
+	}
+
+	log.Printf("Injected %s context: %s", contextType, result.Reasoning)
+	analysis.RecordIntervention(ctx, c.hub.analyzer, result)
+
+	if c.conversationID != nil {
+		_, err := c.db.LogContextInjection(c.ctx, *c.conversationID, messageID, result.ContextText, contextType, result.Reasoning)
+		if err != nil {
+			log.Printf("Failed to log context injection: %v", err)
+		}
+	}
+
+	// Let the frontend show that context was injected
+	response := map[string]interface{}{
+		"type":         "context_injected",
+		"context_text": result.ContextText,
+		"context_type": contextType,
+		"reasoning":    result.Reasoning,
+	}
+
+	responseJSON, _ := json.Marshal(response)
+	c.queue(responseJSON)
+}
+
+// restorePersistentContext re-applies the persistent context after a
+// temporary injection has been consumed by an assistant turn
+func (c *Client) restorePersistentContext() {
+	c.historyMu.Lock()
+	if !c.restorePersistent || c.persistentContext == "" {
+		c.historyMu.Unlock()
+		return
+	}
+	c.restorePersistent = false
+	text := c.persistentContext
+	c.historyMu.Unlock()
+
+	if err := c.sendContext(&SessionContext{Text: text, Type: analysis.ContextPersistent}); err != nil {
+		log.Printf("Failed to restore persistent context: %v", err)
+	}
+}
+
+// sendContext writes a context-only session_settings frame to Hume
+func (c *Client) sendContext(sessionContext *SessionContext) error {
+	settingsJSON, err := json.Marshal(SessionContextSettings{
+		Type:    "session_settings",
+		Context: sessionContext,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal context settings: %w", err)
+	}
+
+	c.humeMutex.Lock()
+	defer c.humeMutex.Unlock()
+
+	if c.humeConn == nil {
+		return fmt.Errorf("not connected to Hume")
+	}
+	return c.humeConn.WriteMessage(websocket.TextMessage, settingsJSON)
+}
@@ -0,0 +1,300 @@
+package websocket
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+
+	"github.com/hume-evi/web/internal/analysis"
+	"github.com/hume-evi/web/internal/config"
+	"github.com/hume-evi/web/internal/db"
+	"github.com/hume-evi/web/internal/db/memstore"
+	"github.com/hume-evi/web/internal/hume/fakehume"
+)
+
+// testProxy is the EVI proxy wired to the fake Hume chat and an in-memory
+// store, with one frontend connection open as user
+type testProxy struct {
+	fake  *fakehume.Server
+	store *memstore.Store
+	user  *db.User
+	conn  *websocket.Conn
+}
+
+func newTestProxy(t *testing.T, analyzer analysis.Analyzer) *testProxy {
+	t.Helper()
+
+	fake, humeServer := fakehume.NewTestServer()
+	fake.APIKey = "test-key"
+	t.Cleanup(humeServer.Close)
+
+	store := memstore.New()
+	user, err := store.CreateUser(context.Background(), "ada", "hash", nil, false)
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	cfg := &config.Config{HumeAPIKey: "test-key", HumeAPIURL: humeServer.URL}
+	hub := NewHub(store, cfg, analyzer)
+	go hub.Run()
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ServeWS(hub, w, r, user.ID.String())
+	}))
+	t.Cleanup(proxy.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(proxy.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dialing the proxy: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return &testProxy{fake: fake, store: store, user: user, conn: conn}
+}
+
+func (p *testProxy) send(t *testing.T, msg map[string]interface{}) {
+	t.Helper()
+	if err := p.conn.WriteJSON(msg); err != nil {
+		t.Fatalf("writing %v: %v", msg["type"], err)
+	}
+}
+
+// next returns the next frame forwarded to the frontend. The write pump
+// batches queued messages into one websocket frame, newline separated.
+func (p *testProxy) next(t *testing.T, pending *[]map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	for len(*pending) == 0 {
+		p.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, data, err := p.conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("reading from the proxy: %v", err)
+		}
+		for _, line := range bytes.Split(data, []byte{'\n'}) {
+			var msg map[string]interface{}
+			if err := json.Unmarshal(line, &msg); err != nil {
+				t.Fatalf("proxy sent %q: %v", line, err)
+			}
+			*pending = append(*pending, msg)
+		}
+	}
+	msg := (*pending)[0]
+	*pending = (*pending)[1:]
+	return msg
+}
+
+// waitFor reads frames until one of the given type arrives
+func (p *testProxy) waitFor(t *testing.T, pending *[]map[string]interface{}, msgType string) map[string]interface{} {
+	t.Helper()
+	for {
+		msg := p.next(t, pending)
+		if msg["type"] == "error" {
+			t.Fatalf("proxy sent an error waiting for %s: %v", msgType, msg["message"])
+		}
+		if msg["type"] == msgType {
+			return msg
+		}
+	}
+}
+
+// start begins a new conversation and returns its ID
+func (p *testProxy) start(t *testing.T, pending *[]map[string]interface{}) uuid.UUID {
+	t.Helper()
+	p.send(t, map[string]interface{}{"type": "start_conversation"})
+	started := p.waitFor(t, pending, "conversation_started")
+	convID, err := uuid.Parse(started["conversation_id"].(string))
+	if err != nil {
+		t.Fatalf("conversation_started carried conversation_id %v", started["conversation_id"])
+	}
+	return convID
+}
+
+// speak sends enough audio for the fake to play its next scripted turn
+func (p *testProxy) speak(t *testing.T) {
+	t.Helper()
+	for i := 0; i < 20; i++ {
+		p.send(t, map[string]interface{}{"type": "audio_input", "data": "AAAA"})
+	}
+}
+
+func TestProxyRoundTrip(t *testing.T) {
+	p := newTestProxy(t, &analysis.FakeAnalyzer{})
+	p.fake.Script(fakehume.Turn{User: "I've been feeling stuck lately", Assistant: "What does stuck feel like?"})
+
+	var pending []map[string]interface{}
+	convID := p.start(t, &pending)
+	p.speak(t)
+
+	user := p.waitFor(t, &pending, "user_message")
+	if user["role"] != "user" || user["content"] != "I've been feeling stuck lately" || user["interim"] != false {
+		t.Fatalf("forwarded user_message = %v", user)
+	}
+	assistant := p.waitFor(t, &pending, "assistant_message")
+	if assistant["content"] != "What does stuck feel like?" {
+		t.Fatalf("forwarded assistant_message = %v", assistant)
+	}
+
+	// Messages are saved before they are forwarded
+	messages, err := p.store.GetMessages(context.Background(), convID, p.user.ID)
+	if err != nil {
+		t.Fatalf("GetMessages: %v", err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("persisted %d messages, want 2: %+v", len(messages), messages)
+	}
+	if messages[0].Role != "user" || messages[0].Content != "I've been feeling stuck lately" || messages[0].Emotions["Calmness"] != 0.42 {
+		t.Fatalf("persisted user message %+v", messages[0])
+	}
+	if messages[1].Role != "assistant" || messages[1].Content != "What does stuck feel like?" {
+		t.Fatalf("persisted assistant message %+v", messages[1])
+	}
+
+	// The proxy configures the session before forwarding the user's audio
+	received := p.fake.Received()
+	if len(received) != 21 {
+		t.Fatalf("fake received %d frames, want session_settings and 20 audio_input", len(received))
+	}
+	var settings SessionSettings
+	if err := json.Unmarshal(received[0], &settings); err != nil || settings.Type != "session_settings" || settings.Audio.Encoding != "linear16" {
+		t.Fatalf("first frame sent to Hume = %s", received[0])
+	}
+	var audio AudioInputMessage
+	if err := json.Unmarshal(received[1], &audio); err != nil || audio.Type != "audio_input" || audio.Data != "AAAA" {
+		t.Fatalf("audio forwarded to Hume as %s", received[1])
+	}
+}
+
+// injectedContexts returns the context-only session_settings frames the
+// proxy has sent to Hume, in order
+func (p *testProxy) injectedContexts(t *testing.T) []SessionContext {
+	t.Helper()
+	var contexts []SessionContext
+	for _, frame := range p.fake.Received() {
+		var settings SessionContextSettings
+		if err := json.Unmarshal(frame, &settings); err != nil {
+			t.Fatalf("proxy sent Hume %s: %v", frame, err)
+		}
+		if settings.Type == "session_settings" && settings.Context != nil {
+			contexts = append(contexts, *settings.Context)
+		}
+	}
+	return contexts
+}
+
+// waitForContexts polls until the proxy has sent Hume n contexts
+func (p *testProxy) waitForContexts(t *testing.T, n int) []SessionContext {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		contexts := p.injectedContexts(t)
+		if len(contexts) >= n || time.Now().After(deadline) {
+			return contexts
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestProxyInjectsContext(t *testing.T) {
+	intervene := func(text, contextType string) analysis.AnalyzeResponse {
+		return analysis.AnalyzeResponse{ShouldIntervene: true, ContextText: text, ContextType: contextType, Reasoning: "turn says " + text}
+	}
+	analyzer := &analysis.FakeAnalyzer{Responses: []analysis.AnalyzeResponse{
+		intervene("Breathe.", analysis.ContextTemporary),
+		intervene("Keep it short.", analysis.ContextPersistent),
+		intervene("Ask about sleep.", analysis.ContextTemporary),
+		intervene("Keep it short.", analysis.ContextPersistent),
+		intervene("Be playful.", "sticky"),
+	}}
+	p := newTestProxy(t, analyzer)
+
+	var pending []map[string]interface{}
+	convID := p.start(t, &pending)
+
+	// Each turn's analysis runs alongside the rest of the turn, so wait for
+	// its outcome before the next one
+	injected := func(text, contextType string) {
+		t.Helper()
+		msg := p.waitFor(t, &pending, "context_injected")
+		if msg["context_text"] != text || msg["context_type"] != contextType || msg["reasoning"] != "turn says "+text {
+			t.Fatalf("frontend told context_injected %v, want %s context %q", msg, contextType, text)
+		}
+	}
+
+	// A temporary context with no persistent one in effect has nothing to
+	// restore afterwards
+	p.speak(t)
+	injected("Breathe.", analysis.ContextTemporary)
+	p.speak(t)
+	injected("Keep it short.", analysis.ContextPersistent)
+
+	// A temporary context replaces the persistent one for one assistant
+	// turn, after which the persistent context is sent again
+	p.speak(t)
+	injected("Ask about sleep.", analysis.ContextTemporary)
+
+	// The persistent context already in effect isn't sent again
+	p.speak(t)
+	deadline := time.Now().Add(5 * time.Second)
+	for len(analyzer.Calls()) < 4 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Unknown context types are sent as temporary
+	p.speak(t)
+	injected("Be playful.", analysis.ContextTemporary)
+
+	want := []SessionContext{
+		{Text: "Breathe.", Type: analysis.ContextTemporary},
+		{Text: "Keep it short.", Type: analysis.ContextPersistent},
+		{Text: "Ask about sleep.", Type: analysis.ContextTemporary},
+		{Text: "Keep it short.", Type: analysis.ContextPersistent},
+		{Text: "Be playful.", Type: analysis.ContextTemporary},
+	}
+	got := p.waitForContexts(t, len(want))
+	if len(got) != len(want) {
+		t.Fatalf("proxy sent Hume contexts %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("proxy sent Hume contexts %+v, want %+v", got, want)
+		}
+	}
+
+	// Each turn's analysis saw the transcript so far
+	calls := analyzer.Calls()
+	if len(calls) != 5 || len(calls[0]) != 1 || len(calls[4]) != 9 || calls[4][8].Role != "user" {
+		t.Fatalf("analyzer saw histories of %d calls, want 5 growing by a turn each", len(calls))
+	}
+
+	// Only the analyzer's injections are logged, not restores or repeats,
+	// each against the user message that prompted it
+	injections, err := p.store.ListContextInjections(context.Background(), convID, p.user.ID)
+	if err != nil {
+		t.Fatalf("ListContextInjections: %v", err)
+	}
+	if len(injections) != 4 {
+		t.Fatalf("logged %d injections, want 4: %+v", len(injections), injections)
+	}
+	messages, err := p.store.GetMessages(context.Background(), convID, p.user.ID)
+	if err != nil {
+		t.Fatalf("GetMessages: %v", err)
+	}
+	userMessages := map[uuid.UUID]bool{}
+	for _, msg := range messages {
+		if msg.Role == "user" {
+			userMessages[msg.ID] = true
+		}
+	}
+	for _, injection := range injections {
+		if injection.MessageID == nil || !userMessages[*injection.MessageID] {
+			t.Fatalf("injection %+v isn't linked to a user message", injection)
+		}
+	}
+}
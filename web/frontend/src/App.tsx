@@ -4,6 +4,7 @@ import { Auth } from './components/Auth'
 import { ConversationList } from './components/ConversationList'
 import { VoiceChat } from './components/VoiceChat'
 import { Transcript } from './components/Transcript'
+import { Search } from './components/Search'
 import { VoiceAdmin } from './components/VoiceAdmin'
 import { UserAdmin } from './components/UserAdmin'
 import { auth, conversations, User } from './lib/api'
@@ -134,7 +135,8 @@ function AppContent() {
           element={
             <main className="container mx-auto px-4 py-8">
               <div className="grid grid-cols-1 lg:grid-cols-2 gap-6">
-                <div>
+                <div className="space-y-6">
+                  <Search onSelectConversation={setSelectedConversationId} />
                   <ConversationList
                     onSelectConversation={setSelectedConversationId}
                     onCreateConversation={handleCreateConversation}
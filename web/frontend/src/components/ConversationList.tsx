@@ -13,20 +13,41 @@ interface ConversationListProps {
 
 export function ConversationList({ onSelectConversation, onCreateConversation, selectedId }: ConversationListProps) {
   const [convs, setConvs] = useState<Conversation[]>([])
+  const [nextCursor, setNextCursor] = useState<string | undefined>()
   const [loading, setLoading] = useState(true)
+  const [loadingMore, setLoadingMore] = useState(false)
 
   const loadConversations = async () => {
     try {
       const data = await conversations.list()
-      setConvs(Array.isArray(data) ? data : [])
+      setConvs(data.conversations ?? [])
+      setNextCursor(data.next_cursor)
     } catch (error) {
       console.error('Failed to load conversations:', error)
       setConvs([])
+      setNextCursor(undefined)
     } finally {
       setLoading(false)
     }
   }
 
+  const loadMore = async () => {
+    if (!nextCursor) return
+    setLoadingMore(true)
+    try {
+      const data = await conversations.list(nextCursor)
+      setConvs((prev) => {
+        const seen = new Set(prev.map((c) => c.id))
+        return [...prev, ...(data.conversations ?? []).filter((c) => !seen.has(c.id))]
+      })
+      setNextCursor(data.next_cursor)
+    } catch (error) {
+      console.error('Failed to load more conversations:', error)
+    } finally {
+      setLoadingMore(false)
+    }
+  }
+
   useEffect(() => {
     loadConversations()
   }, [])
@@ -112,6 +133,13 @@ export function ConversationList({ onSelectConversation, onCreateConversation, s
                 ))}
               </TableBody>
             </Table>
+            {nextCursor && (
+              <div className="text-center pt-4">
+                <Button variant="outline" size="sm" onClick={loadMore} disabled={loadingMore}>
+                  {loadingMore ? 'Loading...' : 'Load more'}
+                </Button>
+              </div>
+            )}
           </div>
         )}
       </CardContent>
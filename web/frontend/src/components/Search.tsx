@@ -0,0 +1,85 @@
+import { useState } from 'react'
+import { Button } from './ui/button'
+import { Input } from './ui/input'
+import { Card, CardContent, CardDescription, CardHeader, CardTitle } from './ui/card'
+import { search, SearchResult } from '@/lib/api'
+
+interface SearchProps {
+  onSelectConversation: (id: string) => void
+}
+
+export function Search({ onSelectConversation }: SearchProps) {
+  const [query, setQuery] = useState('')
+  const [results, setResults] = useState<SearchResult[] | null>(null)
+  const [error, setError] = useState('')
+  const [loading, setLoading] = useState(false)
+
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault()
+    if (!query.trim()) return
+    setError('')
+    setLoading(true)
+    try {
+      const data = await search.transcripts(query.trim())
+      setResults(data.results ?? [])
+    } catch (err: any) {
+      const errorMsg = err.response?.data || err.message || 'Search failed'
+      setError(typeof errorMsg === 'string' ? errorMsg : 'Search failed')
+      setResults(null)
+    } finally {
+      setLoading(false)
+    }
+  }
+
+  return (
+    <Card>
+      <CardHeader>
+        <CardTitle>Search</CardTitle>
+        <CardDescription>Find what was said in your conversations</CardDescription>
+      </CardHeader>
+      <CardContent className="space-y-4">
+        <form onSubmit={handleSubmit} className="flex gap-2">
+          <Input
+            value={query}
+            onChange={(e) => setQuery(e.target.value)}
+            placeholder='e.g. "new job" or moving -work'
+            maxLength={200}
+          />
+          <Button type="submit" disabled={loading || !query.trim()}>
+            {loading ? 'Searching...' : 'Search'}
+          </Button>
+        </form>
+        {error && <div className="text-sm text-red-600">{error}</div>}
+        {results && results.length === 0 && (
+          <div className="text-center py-4 text-muted-foreground">No matches</div>
+        )}
+        {results && results.length > 0 && (
+          <div className="space-y-2 max-h-96 overflow-y-auto">
+            {results.map((result) => (
+              <button
+                key={result.message_id}
+                type="button"
+                onClick={() => onSelectConversation(result.conversation_id)}
+                className="w-full text-left p-3 rounded-lg border hover:bg-muted"
+              >
+                <div className="text-xs text-muted-foreground mb-1">
+                  {result.conversation_title || 'Untitled'} • {result.role === 'user' ? 'You' : 'EVI'} •{' '}
+                  {new Date(result.timestamp).toLocaleDateString('en-US', { month: 'short', day: 'numeric', year: 'numeric' })}
+                </div>
+                <div className="text-sm">
+                  {(result.snippet ?? []).map((part, i) =>
+                    part.match ? (
+                      <mark key={i} className="bg-yellow-200 rounded px-0.5">{part.text}</mark>
+                    ) : (
+                      <span key={i}>{part.text}</span>
+                    ),
+                  )}
+                </div>
+              </button>
+            ))}
+          </div>
+        )}
+      </CardContent>
+    </Card>
+  )
+}
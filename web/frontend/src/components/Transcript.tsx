@@ -1,16 +1,32 @@
 import { useEffect, useState } from 'react'
+import { Button } from './ui/button'
 import { Card, CardContent, CardDescription, CardHeader, CardTitle } from './ui/card'
 import { messages, Message } from '@/lib/api'
 
+// mergeMessages adds a page to what is already loaded, keeping one copy of
+// each message in transcript order
+function mergeMessages(existing: Message[], page: Message[]) {
+  const byId = new Map(existing.map((m) => [m.id, m]))
+  page.forEach((m) => byId.set(m.id, m))
+  return Array.from(byId.values()).sort((a, b) =>
+    a.timestamp === b.timestamp ? a.id.localeCompare(b.id) : a.timestamp.localeCompare(b.timestamp),
+  )
+}
+
 interface TranscriptProps {
   conversationId?: string
 }
 
 export function Transcript({ conversationId }: TranscriptProps) {
   const [msgs, setMsgs] = useState<Message[]>([])
+  // Cursor for the turns before the oldest loaded one; null once they're all loaded
+  const [olderCursor, setOlderCursor] = useState<string | null>(null)
   const [loading, setLoading] = useState(true)
+  const [loadingOlder, setLoadingOlder] = useState(false)
 
-  const loadMessages = async () => {
+  // loadMessages fetches the newest page; polling merges it into the
+  // transcript so older turns already loaded stay put
+  const loadMessages = async (initial = false) => {
     if (!conversationId) {
       setMsgs([])
       setLoading(false)
@@ -18,20 +34,41 @@ export function Transcript({ conversationId }: TranscriptProps) {
     }
     try {
       const data = await messages.list(conversationId)
-      setMsgs(Array.isArray(data) ? data : [])
+      const page = data.messages ?? []
+      setMsgs((prev) => (initial ? page : mergeMessages(prev, page)))
+      if (initial) {
+        setOlderCursor(data.next_cursor ?? null)
+      }
     } catch (error) {
       console.error('Failed to load messages:', error)
-      setMsgs([])
+      if (initial) {
+        setMsgs([])
+      }
     } finally {
       setLoading(false)
     }
   }
 
+  const loadOlder = async () => {
+    if (!conversationId || !olderCursor) return
+    setLoadingOlder(true)
+    try {
+      const data = await messages.list(conversationId, { before: olderCursor })
+      setMsgs((prev) => mergeMessages(prev, data.messages ?? []))
+      setOlderCursor(data.next_cursor ?? null)
+    } catch (error) {
+      console.error('Failed to load earlier messages:', error)
+    } finally {
+      setLoadingOlder(false)
+    }
+  }
+
   useEffect(() => {
     if (conversationId) {
-      loadMessages()
+      setLoading(true)
+      loadMessages(true)
       // Poll for new messages
-      const interval = setInterval(loadMessages, 2000)
+      const interval = setInterval(() => loadMessages(), 2000)
       return () => clearInterval(interval)
     }
   }, [conversationId])
@@ -53,6 +90,13 @@ export function Transcript({ conversationId }: TranscriptProps) {
           <div className="text-center py-8 text-muted-foreground">No messages yet</div>
         ) : (
           <div className="space-y-4 max-h-96 overflow-y-auto">
+            {olderCursor && (
+              <div className="text-center">
+                <Button variant="outline" size="sm" onClick={loadOlder} disabled={loadingOlder}>
+                  {loadingOlder ? 'Loading...' : 'Load earlier messages'}
+                </Button>
+              </div>
+            )}
             {msgs.map((msg) => (
               <div
                 key={msg.id}
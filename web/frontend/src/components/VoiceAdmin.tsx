@@ -116,6 +116,10 @@ export function VoiceAdmin() {
     setError(null)
     try {
       const result = await voices.syncAll()
+      if (result.status === 'failed') {
+        setError(`Sync failed: ${result.error}`)
+        return
+      }
       const synced = result.results.filter(r => r.status === 'synced').length
       const errors = result.results.filter(r => r.status === 'error').length
       const skipped = result.results.filter(r => r.status === 'skipped').length
@@ -123,7 +127,7 @@ export function VoiceAdmin() {
       if (errors > 0) {
         const errorDetails = result.results
           .filter(r => r.status === 'error')
-          .map(r => `${r.name}: ${r.error}`)
+          .map(r => `${r.name}: ${r.detail}`)
           .join('\n')
         setError(`Some voices failed to sync:\n${errorDetails}`)
       }
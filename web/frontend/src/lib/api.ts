@@ -31,9 +31,46 @@ export interface Message {
   conversation_id: string
   role: string
   content: string
+  // Hume prosody scores by expression name; only sent when asked for
+  emotions?: Record<string, number>
   timestamp: string
 }
 
+// Paged list responses; pass next_cursor back to get the following page
+export interface ConversationPage {
+  conversations: Conversation[]
+  next_cursor?: string
+}
+
+export interface MessagePage {
+  messages: Message[]
+  next_cursor?: string
+}
+
+export interface MessagePageParams {
+  limit?: number
+  before?: string
+  after?: string
+  emotions?: boolean
+}
+
+export interface SearchResult {
+  message_id: string
+  conversation_id: string
+  conversation_title: string
+  role: string
+  timestamp: string
+  rank: number
+  // Snippet text split into runs; match marks the words that matched
+  snippet: { text: string; match?: boolean }[] | null
+  messages_url: string
+}
+
+export interface SearchResponse {
+  query: string
+  results: SearchResult[]
+}
+
 export const auth = {
   login: async (username: string, password: string) => {
     const { data } = await api.post<{ user_id: string; username: string; is_admin: boolean; token: string }>('/auth/login', {
@@ -54,8 +91,8 @@ export const auth = {
 }
 
 export const conversations = {
-  list: async () => {
-    const { data } = await api.get<Conversation[]>('/conversations')
+  list: async (cursor?: string, limit?: number) => {
+    const { data } = await api.get<ConversationPage>('/conversations', { params: { cursor, limit } })
     return data
   },
 
@@ -82,15 +119,24 @@ export const conversations = {
     return data
   },
 
-  getMessages: async (id: string) => {
-    const { data } = await api.get<Message[]>(`/conversations/${id}/messages`)
+  getMessages: async (id: string, params?: MessagePageParams) => {
+    const { data } = await api.get<MessagePage>(`/conversations/${id}/messages`, { params })
+    return data
+  },
+}
+
+export const search = {
+  transcripts: async (q: string, limit?: number) => {
+    const { data } = await api.get<SearchResponse>('/search', { params: { q, limit } })
     return data
   },
 }
 
 export const messages = {
-  list: async (conversationId: string) => {
-    const { data } = await api.get<Message[]>(`/conversations/${conversationId}/messages`)
+  // Without before/after this is the newest page; next_cursor then loads
+  // older turns via before
+  list: async (conversationId: string, params?: MessagePageParams) => {
+    const { data } = await api.get<MessagePage>(`/conversations/${conversationId}/messages`, { params })
     return data
   },
   
@@ -158,12 +204,27 @@ export const voices = {
     return data
   },
 
+  // Starts a background sync and polls it until it finishes
   syncAll: async () => {
-    const { data } = await api.post<{ status: string; results: Array<{ voice_id: string; name: string; status: string; error?: string; reason?: string }> }>('/admin/voices/sync')
+    let { data } = await api.post<HumeSyncRun>('/admin/voices/sync')
+    while (data.status === 'running') {
+      await new Promise(resolve => setTimeout(resolve, 1000))
+      ;({ data } = await api.get<HumeSyncRun>(`/admin/hume/sync-runs/${data.id}`))
+    }
     return data
   },
 }
 
+export interface HumeSyncRun {
+  id: string
+  kind: 'sync' | 'reconcile'
+  status: 'running' | 'succeeded' | 'failed'
+  total: number
+  processed: number
+  results: Array<{ voice_id?: string; name: string; status: string; drift?: string[]; detail?: string }>
+  error?: string
+}
+
 export interface AdminUser {
   id: string
   username: string